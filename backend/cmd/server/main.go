@@ -3,13 +3,17 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"flashcard/internal/config"
 	"flashcard/internal/handlers"
+	"flashcard/internal/metrics"
 	"flashcard/internal/middleware"
+	"flashcard/internal/services"
 	"flashcard/pkg/database"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -25,6 +29,14 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	// 注册监控指标
+	if err := metrics.RegisterDBCallbacks(database.GetDB()); err != nil {
+		log.Fatalf("注册数据库监控回调失败: %v", err)
+	}
+	if err := metrics.RegisterCardGauges(database.GetDB()); err != nil {
+		log.Fatalf("注册卡片监控指标失败: %v", err)
+	}
+
 	// 创建处理器
 	deckHandler := handlers.NewDeckHandler()
 	tagHandler := handlers.NewTagHandler()
@@ -32,12 +44,22 @@ func main() {
 	importExportHandler := handlers.NewImportExportHandler()
 	studyHandler := handlers.NewStudyHandler()
 	systemHandler := handlers.NewSystemHandler()
+	analyticsHandler := handlers.NewAnalyticsHandler()
+	noteHandler := handlers.NewNoteHandler()
+	noteTypeHandler := handlers.NewNoteTypeHandler()
+	savedSearchHandler := handlers.NewSavedSearchHandler()
+	mediaHandler := handlers.NewMediaHandler()
+	trashHandler := handlers.NewTrashHandler()
+
+	// 定期彻底删除超过保留天数的回收站项目
+	services.NewTrashService().StartAutoPurge(time.Hour)
 
 	// 创建Gin引擎
 	r := gin.New()
 
 	// 使用中间件
 	r.Use(middleware.Logger())
+	r.Use(middleware.Metrics())
 	r.Use(middleware.Recovery())
 	r.Use(middleware.CORS())
 
@@ -49,6 +71,9 @@ func main() {
 		})
 	})
 
+	// Prometheus监控指标接口
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// API路由组
 	api := r.Group("/api/v1")
 	{
@@ -62,36 +87,100 @@ func main() {
 		// 卡包相关路由
 		decks := api.Group("/decks")
 		{
-			decks.GET("", deckHandler.GetDecks)                 // 获取所有卡包
-			decks.POST("", deckHandler.CreateDeck)              // 创建卡包
-			decks.GET("/:id", deckHandler.GetDeck)              // 获取单个卡包
-			decks.PATCH("/:id", deckHandler.UpdateDeck)         // 更新卡包
-			decks.DELETE("/:id", deckHandler.DeleteDeck)        // 删除卡包
-			decks.GET("/:id/stats", deckHandler.GetDeckStats)   // 获取卡包统计
-			decks.GET("/:id/cards", cardHandler.GetCardsByDeck) // 获取卡包下的所有卡片
+			decks.GET("", deckHandler.GetDecks)                            // 获取所有卡包
+			decks.POST("", deckHandler.CreateDeck)                         // 创建卡包
+			decks.GET("/tree", deckHandler.GetDeckTree)                    // 获取卡包树
+			decks.GET("/:id", deckHandler.GetDeck)                         // 获取单个卡包
+			decks.PATCH("/:id", deckHandler.UpdateDeck)                    // 更新卡包
+			decks.DELETE("/:id", deckHandler.DeleteDeck)                   // 删除卡包
+			decks.POST("/:id/move", deckHandler.MoveDeck)                  // 移动卡包
+			decks.POST("/:id/clone", deckHandler.CloneDeck)                // 复制卡包
+			decks.POST("/:id/merge", deckHandler.MergeDeck)                // 合并到其他卡包
+			decks.GET("/:id/stats", deckHandler.GetDeckStats)              // 获取卡包统计
+			decks.GET("/:id/duplicates", deckHandler.GetDeckDuplicates)    // 获取卡包中的重复卡片
+			decks.GET("/:id/cards", cardHandler.GetCardsByDeck)            // 获取卡包下的所有卡片
+			decks.POST("/:id/cards/reorder", cardHandler.ReorderCards)     // 调整卡包中卡片的顺序
+			decks.GET("/:id/analytics", analyticsHandler.GetDeckAnalytics) // 获取卡包记忆分析
 		}
 
 		// 标签相关路由
 		apiTags := api.Group("/tags")
 		{
-			apiTags.GET("", tagHandler.GetAllTags)               // 获取所有标签
-			apiTags.POST("", tagHandler.CreateTag)               // 创建标签
-			apiTags.GET("/deck/:deckId", tagHandler.GetTags)     // 获取卡包下的所有标签
-			apiTags.GET("/:id", tagHandler.GetTag)               // 获取单个标签
-			apiTags.PATCH("/:id", tagHandler.UpdateTag)          // 更新标签
-			apiTags.DELETE("/:id", tagHandler.DeleteTag)         // 删除标签
-			apiTags.GET("/:id/stats", tagHandler.GetTagStats)    // 获取标签统计
-			apiTags.GET("/:id/cards", cardHandler.GetCardsByTag) // 获取标签下的所有卡片
+			apiTags.GET("", tagHandler.GetAllTags)                          // 获取所有标签
+			apiTags.POST("", tagHandler.CreateTag)                          // 创建标签
+			apiTags.GET("/deck/:deckId", tagHandler.GetTags)                // 获取卡包下的所有标签
+			apiTags.GET("/:id", tagHandler.GetTag)                          // 获取单个标签
+			apiTags.PATCH("/:id", tagHandler.UpdateTag)                     // 更新标签
+			apiTags.POST("/:id/move", tagHandler.MoveTag)                   // 移动标签及其子标签
+			apiTags.POST("/:id/rename", tagHandler.RenameTagPath)           // 按完整路径重命名标签
+			apiTags.DELETE("/:id", tagHandler.DeleteTag)                    // 删除标签
+			apiTags.GET("/:id/stats", tagHandler.GetTagStats)               // 获取标签统计
+			apiTags.GET("/:id/cards", cardHandler.GetCardsByTag)            // 获取标签下的所有卡片
+			apiTags.GET("/:id/analytics", analyticsHandler.GetTagAnalytics) // 获取标签记忆分析
 		}
 
 		// 卡片相关路由
 		apiCards := api.Group("/cards")
 		{
-			apiCards.GET("", cardHandler.SearchCards)       // 搜索卡片
-			apiCards.POST("", cardHandler.CreateCard)       // 创建卡片
-			apiCards.GET("/:id", cardHandler.GetCard)       // 获取单个卡片
-			apiCards.PATCH("/:id", cardHandler.UpdateCard)  // 更新卡片
-			apiCards.DELETE("/:id", cardHandler.DeleteCard) // 删除卡片
+			apiCards.GET("", cardHandler.SearchCards)                                  // 搜索卡片
+			apiCards.POST("", cardHandler.CreateCard)                                  // 创建卡片
+			apiCards.POST("/bulk", cardHandler.BulkUpdateCards)                        // 批量操作卡片
+			apiCards.POST("/replace", cardHandler.ReplaceCards)                        // 查找替换卡片内容
+			apiCards.GET("/:id", cardHandler.GetCard)                                  // 获取单个卡片
+			apiCards.PATCH("/:id", cardHandler.UpdateCard)                             // 更新卡片
+			apiCards.DELETE("/:id", cardHandler.DeleteCard)                            // 删除卡片
+			apiCards.PUT("/:id/media", mediaHandler.SetCardMedia)                      // 设置卡片引用的媒体
+			apiCards.PUT("/:id/flag", cardHandler.SetCardFlag)                         // 设置卡片标记
+			apiCards.GET("/:id/revisions", cardHandler.GetCardRevisions)               // 获取卡片修订记录
+			apiCards.GET("/:id/revisions/diff", cardHandler.DiffCardRevisions)         // 比较两个修订
+			apiCards.POST("/:id/revisions/:revisionId/revert", cardHandler.RevertCard) // 恢复到指定修订
+		}
+
+		// 笔记相关路由（填空等由笔记生成多张卡片的类型）
+		apiNotes := api.Group("/notes")
+		{
+			apiNotes.POST("", noteHandler.CreateNote)       // 创建笔记
+			apiNotes.GET("/:id", noteHandler.GetNote)       // 获取单个笔记
+			apiNotes.PATCH("/:id", noteHandler.UpdateNote)  // 更新笔记
+			apiNotes.DELETE("/:id", noteHandler.DeleteNote) // 删除笔记
+		}
+
+		// 笔记类型相关路由（自定义字段和卡片模板）
+		apiNoteTypes := api.Group("/note-types")
+		{
+			apiNoteTypes.GET("", noteTypeHandler.GetNoteTypes)          // 获取所有笔记类型
+			apiNoteTypes.POST("", noteTypeHandler.CreateNoteType)       // 创建笔记类型
+			apiNoteTypes.GET("/:id", noteTypeHandler.GetNoteType)       // 获取单个笔记类型
+			apiNoteTypes.PATCH("/:id", noteTypeHandler.UpdateNoteType)  // 更新笔记类型并重新生成卡片
+			apiNoteTypes.DELETE("/:id", noteTypeHandler.DeleteNoteType) // 删除笔记类型
+		}
+
+		// 保存搜索相关路由
+		apiSavedSearches := api.Group("/saved-searches")
+		{
+			apiSavedSearches.GET("", savedSearchHandler.GetSavedSearches)              // 获取所有保存的搜索
+			apiSavedSearches.POST("", savedSearchHandler.CreateSavedSearch)            // 保存搜索
+			apiSavedSearches.GET("/:id", savedSearchHandler.GetSavedSearch)            // 获取单个保存的搜索
+			apiSavedSearches.PATCH("/:id", savedSearchHandler.UpdateSavedSearch)       // 更新保存的搜索
+			apiSavedSearches.DELETE("/:id", savedSearchHandler.DeleteSavedSearch)      // 删除保存的搜索
+			apiSavedSearches.GET("/:id/cards", savedSearchHandler.GetSavedSearchCards) // 执行保存的搜索
+		}
+
+		// 媒体相关路由
+		apiMedia := api.Group("/media")
+		{
+			apiMedia.POST("", mediaHandler.UploadMedia)       // 上传媒体文件
+			apiMedia.GET("/:id", mediaHandler.GetMedia)       // 获取媒体文件
+			apiMedia.POST("/gc", mediaHandler.CollectGarbage) // 清理未引用的媒体文件
+		}
+
+		// 回收站相关路由
+		apiTrash := api.Group("/trash")
+		{
+			apiTrash.GET("", trashHandler.ListTrash)                      // 获取回收站中的项目
+			apiTrash.DELETE("", trashHandler.EmptyTrash)                  // 清空回收站
+			apiTrash.POST("/:type/:id/restore", trashHandler.RestoreItem) // 恢复项目
+			apiTrash.DELETE("/:type/:id", trashHandler.PurgeItem)         // 彻底删除项目
 		}
 
 		// 导入导出相关路由
@@ -107,6 +196,7 @@ func main() {
 			apiStudy.POST("/deck/:deckId", studyHandler.StartDeckStudy) // 开始学习卡包
 			apiStudy.POST("/tag/:tagId", studyHandler.StartTagStudy)    // 开始学习标签
 			apiStudy.POST("/random", studyHandler.StartRandomStudy)     // 开始随机学习
+			apiStudy.POST("/filtered", studyHandler.StartFilteredStudy) // 按筛选条件开始学习
 			apiStudy.GET("/due", studyHandler.GetDueCards)              // 获取到期卡片
 			apiStudy.POST("/review/:cardId", studyHandler.SubmitReview) // 提交复习结果
 		}
@@ -114,10 +204,11 @@ func main() {
 		// 系统管理相关路由
 		apiSystem := api.Group("/system")
 		{
-			apiSystem.GET("/backup", systemHandler.BackupData)     // 备份数据
-			apiSystem.POST("/restore", systemHandler.RestoreData)  // 恢复数据
-			apiSystem.DELETE("/clear", systemHandler.ClearAllData) // 清空数据
-			apiSystem.GET("/stats", systemHandler.GetSystemStats)  // 获取系统统计
+			apiSystem.GET("/backup", systemHandler.BackupData)                   // 备份数据
+			apiSystem.POST("/restore", systemHandler.RestoreData)                // 恢复数据
+			apiSystem.DELETE("/clear", systemHandler.ClearAllData)               // 清空数据
+			apiSystem.GET("/stats", systemHandler.GetSystemStats)                // 获取系统统计
+			apiSystem.GET("/analytics", analyticsHandler.GetCollectionAnalytics) // 获取全部卡片记忆分析
 		}
 	}
 
@@ -20,6 +20,15 @@ type Config struct {
 	// 导入导出配置
 	ImportExportDir string
 
+	// 媒体文件存储目录
+	MediaDir string
+
+	// 每张卡片保留的修订记录数量，0表示不限制
+	CardRevisionLimit int
+
+	// 回收站中的项目保留天数，超过后自动彻底删除，0表示不自动清理
+	TrashRetentionDays int
+
 	// 日志配置
 	LogLevel string
 }
@@ -35,11 +44,14 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		DBPath:          getEnv("DB_PATH", "./flashcard.db"),
-		Port:            getEnv("PORT", "8080"),
-		GinMode:         getEnv("GIN_MODE", "debug"),
-		ImportExportDir: getEnv("IMPORT_EXPORT_DIR", "./data"),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		DBPath:             getEnv("DB_PATH", "./flashcard.db"),
+		Port:               getEnv("PORT", "8080"),
+		GinMode:            getEnv("GIN_MODE", "debug"),
+		ImportExportDir:    getEnv("IMPORT_EXPORT_DIR", "./data"),
+		MediaDir:           getEnv("MEDIA_DIR", "./media"),
+		CardRevisionLimit:  getEnvAsInt("CARD_REVISION_LIMIT", 50),
+		TrashRetentionDays: getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
 	}
 
 	AppConfig = config
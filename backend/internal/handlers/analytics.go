@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AnalyticsHandler 记忆分析处理器
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+// NewAnalyticsHandler 创建记忆分析处理器实例
+func NewAnalyticsHandler() *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: services.NewAnalyticsService(),
+	}
+}
+
+// parseAnalyticsParams 解析统计时间窗口和难记卡片数量参数
+func parseAnalyticsParams(c *gin.Context) (days, limit int) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	if days > 3650 {
+		days = 3650
+	}
+
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	return days, limit
+}
+
+// GetDeckAnalytics 获取卡包的记忆分析统计
+func (h *AnalyticsHandler) GetDeckAnalytics(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
+	}
+
+	days, limit := parseAnalyticsParams(c)
+	stats, err := h.analyticsService.GetDeckAnalytics(uint(id), days, limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡包不存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取卡包记忆分析失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(stats))
+}
+
+// GetTagAnalytics 获取标签的记忆分析统计
+func (h *AnalyticsHandler) GetTagAnalytics(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的标签ID"))
+		return
+	}
+
+	days, limit := parseAnalyticsParams(c)
+	stats, err := h.analyticsService.GetTagAnalytics(uint(id), days, limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "标签不存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取标签记忆分析失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(stats))
+}
+
+// GetCollectionAnalytics 获取全部卡片的记忆分析统计
+func (h *AnalyticsHandler) GetCollectionAnalytics(c *gin.Context) {
+	days, limit := parseAnalyticsParams(c)
+	stats, err := h.analyticsService.GetCollectionAnalytics(days, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取记忆分析失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(stats))
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestSubmitReviewRecordsLapse 测试复习阶段遗忘会记录遗忘次数和复习日志
+func TestSubmitReviewRecordsLapse(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	// 先记住，再忘记
+	for _, result := range []models.ReviewResult{models.Good, models.Again} {
+		jsonData, _ := json.Marshal(models.ReviewRequest{Result: result})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/study/review/%d", card.ID), bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var review models.Review
+	db.Where("card_id = ?", card.ID).First(&review)
+	assert.Equal(t, 1, review.Lapses)
+
+	var logs []models.ReviewLog
+	db.Where("card_id = ?", card.ID).Order("id").Find(&logs)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, 0, logs[0].LastInterval)
+	assert.Equal(t, 1, logs[1].LastInterval)
+}
+
+// TestGetDeckAnalytics 测试获取卡包记忆分析
+func TestGetDeckAnalytics(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	newCard := models.Card{DeckID: deck.ID, Question: "新卡片", Answer: "答案"}
+	youngCard := models.Card{DeckID: deck.ID, Question: "新手卡片", Answer: "答案"}
+	matureCard := models.Card{DeckID: deck.ID, Question: "成熟卡片", Answer: "答案"}
+	suspendedCard := models.Card{DeckID: deck.ID, Question: "暂停卡片", Answer: "答案", Suspended: true}
+	db.Create(&newCard)
+	db.Create(&youngCard)
+	db.Create(&matureCard)
+	db.Create(&suspendedCard)
+
+	db.Create(&models.Review{CardID: youngCard.ID, EFactor: 2.5, Interval: 6, Repetitions: 2, NextReview: time.Now()})
+	db.Create(&models.Review{CardID: matureCard.ID, EFactor: 1.4, Interval: 40, Repetitions: 5, Lapses: 3, NextReview: time.Now()})
+
+	// 新手卡片通过一次、遗忘一次；成熟卡片通过一次；学习中的复习不计入保持率
+	db.Create(&models.ReviewLog{CardID: youngCard.ID, Result: models.Good, LastInterval: 3, ReviewedAt: time.Now()})
+	db.Create(&models.ReviewLog{CardID: youngCard.ID, Result: models.Again, LastInterval: 6, ReviewedAt: time.Now()})
+	db.Create(&models.ReviewLog{CardID: matureCard.ID, Result: models.Hard, LastInterval: 30, ReviewedAt: time.Now()})
+	db.Create(&models.ReviewLog{CardID: newCard.ID, Result: models.Again, LastInterval: 0, ReviewedAt: time.Now()})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/decks/%d/analytics", deck.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Code string                `json:"code"`
+		Data models.AnalyticsStats `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "SUCCESS", response.Code)
+
+	stats := response.Data
+	assert.Equal(t, models.CardStateCounts{New: 1, Young: 1, Mature: 1, Suspended: 1}, stats.CardStates)
+
+	assert.Equal(t, 1, stats.Retention.Young.Passed)
+	assert.Equal(t, 1, stats.Retention.Young.Failed)
+	assert.Equal(t, 1, stats.Retention.Mature.Passed)
+	assert.InDelta(t, 2.0/3.0, stats.Retention.Total.Rate, 0.0001)
+
+	intervalTotal := 0
+	for _, bucket := range stats.IntervalHistogram {
+		intervalTotal += bucket.Count
+	}
+	assert.Equal(t, 2, intervalTotal)
+	assert.Equal(t, 1, stats.EaseHistogram[0].Count)
+
+	assert.Equal(t, 1, len(stats.HardestCards))
+	assert.Equal(t, matureCard.ID, stats.HardestCards[0].CardID)
+	assert.Equal(t, 3, stats.HardestCards[0].Lapses)
+}
+
+// TestAnalyticsNotFound 测试卡包或标签不存在时返回404
+func TestAnalyticsNotFound(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "已删除"}
+	db.Create(&deck)
+	db.Delete(&deck)
+
+	for _, url := range []string{
+		"/api/v1/decks/999/analytics",
+		fmt.Sprintf("/api/v1/decks/%d/analytics", deck.ID),
+		"/api/v1/tags/999/analytics",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, url)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// bulkUpdateCards 通过接口批量操作卡片，返回响应和解析出的操作结果
+func bulkUpdateCards(router http.Handler, body interface{}) (*httptest.ResponseRecorder, models.CardBulkResponse) {
+	var result models.CardBulkResponse
+	w := sendJSON(router, "POST", "/api/v1/cards/bulk", body, &result)
+	return w, result
+}
+
+// TestBulkMoveAndTagCards 测试批量移动卡片和设置标签，由笔记生成的卡片单独失败
+func TestBulkMoveAndTagCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	source := models.Deck{Name: "导入卡包"}
+	target := models.Deck{Name: "目标卡包"}
+	db.Create(&source)
+	db.Create(&target)
+	tag := models.Tag{DeckID: &target.ID, Name: "已整理", Path: "已整理"}
+	db.Create(&tag)
+
+	card1 := models.Card{DeckID: source.ID, Question: "问题1", Answer: "答案"}
+	card2 := models.Card{DeckID: source.ID, Question: "问题2", Answer: "答案"}
+	db.Create(&card1)
+	db.Create(&card2)
+	note := models.Note{DeckID: source.ID, Type: models.CardTypeCloze, Content: "{{c1::笔记}}"}
+	db.Create(&note)
+	noteCard := models.Card{DeckID: source.ID, NoteID: &note.ID, Question: "笔记问题", Answer: "答案"}
+	db.Create(&noteCard)
+
+	w, result := bulkUpdateCards(router, map[string]interface{}{
+		"action":   models.BulkActionMove,
+		"card_ids": []uint{card1.ID, noteCard.ID, 9999},
+		"filter":   map[string]interface{}{"keyword": "问题2"},
+		"deck_id":  target.ID,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+	if assert.Equal(t, 4, len(result.Results)) {
+		assert.True(t, result.Results[0].Success)
+		assert.False(t, result.Results[1].Success)
+		assert.NotEmpty(t, result.Results[1].Error)
+		assert.False(t, result.Results[2].Success)
+		assert.Equal(t, card2.ID, result.Results[3].CardID)
+	}
+
+	var count int64
+	db.Model(&models.Card{}).Where("deck_id = ?", target.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Model(&models.CardRevision{}).Where("card_id = ?", card1.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// 按卡包筛选后添加标签，再移除
+	w, result = bulkUpdateCards(router, map[string]interface{}{
+		"action": models.BulkActionAddTag,
+		"filter": map[string]interface{}{"deck_id": target.ID},
+		"tag_id": tag.ID,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Succeeded)
+	db.Table("card_tags").Where("tag_id = ?", tag.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	w, result = bulkUpdateCards(router, map[string]interface{}{
+		"action":   models.BulkActionRemoveTag,
+		"card_ids": []uint{card1.ID},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, result.Succeeded)
+	db.Table("card_tags").Where("tag_id = ?", tag.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// 参数错误时整个请求失败
+	w, _ = bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionMove, "card_ids": []uint{card1.ID}, "deck_id": 9999})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = bulkUpdateCards(router, map[string]interface{}{"action": "archive", "card_ids": []uint{card1.ID}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionSuspend})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestBulkSuspendResetDeleteCards 测试批量暂停、重置复习进度和删除卡片
+func TestBulkSuspendResetDeleteCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card1 := models.Card{DeckID: deck.ID, Question: "问题1", Answer: "答案"}
+	card2 := models.Card{DeckID: deck.ID, Question: "问题2", Answer: "答案"}
+	db.Create(&card1)
+	db.Create(&card2)
+	db.Create(&models.Review{CardID: card1.ID, EFactor: 2.5, Interval: 10, Repetitions: 3, NextReview: time.Now()})
+
+	ids := []uint{card1.ID, card2.ID}
+	w, result := bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionSuspend, "card_ids": ids})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Succeeded)
+	var count int64
+	db.Model(&models.Card{}).Where("suspended = ?", true).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionUnsuspend, "card_ids": []uint{card2.ID}})
+	db.Model(&models.Card{}).Where("suspended = ?", true).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w, _ = bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionReset, "card_ids": ids})
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Review{}).Where("card_id = ?", card1.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 删除的卡片进入回收站
+	w, result = bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionDelete, "card_ids": ids})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Succeeded)
+	db.Model(&models.Card{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&models.Card{}).Where("deleted_at IS NOT NULL").Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+// TestBulkRejectsEmptyFilter 测试空筛选条件不会匹配全部卡片
+func TestBulkRejectsEmptyFilter(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	w, _ := bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionDelete, "filter": map[string]interface{}{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = bulkUpdateCards(router, map[string]interface{}{
+		"action":   models.BulkActionDelete,
+		"card_ids": []uint{card.ID},
+		"filter":   map[string]interface{}{"keyword": "  "},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var count int64
+	db.Model(&models.Card{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
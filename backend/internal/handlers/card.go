@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CardHandler 卡片处理器
@@ -21,27 +23,133 @@ func NewCardHandler() *CardHandler {
 	}
 }
 
+// cardRequest 创建和更新卡片的请求参数
+type cardRequest struct {
+	DeckID   uint                `json:"deck_id" binding:"required"`
+	TagID    *uint               `json:"tag_id"`  // 兼容旧版本的单个标签
+	TagIDs   *[]uint             `json:"tag_ids"` // 更新时未提供tag_ids和tag_id则保留原有标签
+	Type     string              `json:"type"`
+	Question string              `json:"question" binding:"required"`
+	Answer   string              `json:"answer"` // 选择题为可选的解析内容
+	Format   string              `json:"format"` // plain或markdown，默认plain
+	Options  []models.CardOption `json:"options"`
+}
+
+// mergeTagIDs 合并标签ID列表和旧版本的单个标签ID
+func mergeTagIDs(tagID *uint, tagIDs []uint) []uint {
+	if tagID != nil {
+		return append([]uint{*tagID}, tagIDs...)
+	}
+	return tagIDs
+}
+
+// tagIDs 合并请求中的标签ID，未提供tag_ids和tag_id时返回nil
+func (r *cardRequest) tagIDs() *[]uint {
+	if r.TagID == nil && r.TagIDs == nil {
+		return nil
+	}
+	var ids []uint
+	if r.TagIDs != nil {
+		ids = *r.TagIDs
+	}
+	merged := mergeTagIDs(r.TagID, ids)
+	return &merged
+}
+
+// cardPageRequest 解析卡片列表的分页和排序参数，页码和每页数量无效时使用默认值
+func cardPageRequest(c *gin.Context) models.CardPageRequest {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	// 验证分页参数
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return models.CardPageRequest{
+		Page:     page,
+		PageSize: pageSize,
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+		Cursor:   c.Query("cursor"),
+	}
+}
+
+// isCardListParamError 判断是否为搜索语句、排序方式或分页游标不合法导致的错误
+func isCardListParamError(err error) bool {
+	return errors.Is(err, services.ErrInvalidSearchQuery) ||
+		errors.Is(err, services.ErrInvalidCardSort) ||
+		errors.Is(err, services.ErrInvalidCursor)
+}
+
+// valid 校验卡片类型和内容格式，普通卡片必须有答案
+func (r *cardRequest) valid() bool {
+	if r.Format != "" && r.Format != models.ContentFormatPlain && r.Format != models.ContentFormatMarkdown {
+		return false
+	}
+
+	switch r.Type {
+	case models.CardTypeChoice:
+		return true
+	case "", models.CardTypeBasic:
+		return r.Answer != ""
+	default:
+		return false
+	}
+}
+
 // CreateCard 创建卡片
 func (h *CardHandler) CreateCard(c *gin.Context) {
-	var req struct {
-		DeckID   uint   `json:"deck_id" binding:"required"`
-		TagID    *uint  `json:"tag_id"`
-		Question string `json:"question" binding:"required"`
-		Answer   string `json:"answer" binding:"required"`
+	var req cardRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !req.valid() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+	// 卡包中已有相同问题的卡片时按on_duplicate参数提示或拒绝创建
+	onDuplicate := c.DefaultQuery("on_duplicate", models.DuplicateWarn)
+	if onDuplicate != models.DuplicateWarn && onDuplicate != models.DuplicateReject {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "on_duplicate参数只能是warn或reject"))
 		return
 	}
 
-	card, err := h.cardService.CreateCard(req.DeckID, req.TagID, req.Question, req.Answer)
+	var tagIDs []uint
+	if ids := req.tagIDs(); ids != nil {
+		tagIDs = *ids
+	}
+
+	var card *models.Card
+	var duplicateIDs []uint
+	var err error
+	if req.Type == models.CardTypeChoice {
+		card, duplicateIDs, err = h.cardService.CreateChoiceCard(req.DeckID, tagIDs, req.Question, req.Answer, req.Format, onDuplicate, req.Options)
+	} else {
+		card, duplicateIDs, err = h.cardService.CreateCard(req.DeckID, tagIDs, req.Question, req.Answer, req.Format, onDuplicate)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrDuplicateCard) {
+			c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error(), gin.H{
+				"duplicate_card_ids": duplicateIDs,
+			}))
+			return
+		}
+		if errors.Is(err, services.ErrInvalidOptions) || errors.Is(err, services.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "创建卡片失败", err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.SuccessResponse(card))
+	response := models.SuccessResponse(card)
+	if len(duplicateIDs) > 0 {
+		response.Message = services.ErrDuplicateCard.Error()
+		response.Details = gin.H{"duplicate_card_ids": duplicateIDs}
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // GetCard 获取单个卡片
@@ -59,10 +167,10 @@ func (h *CardHandler) GetCard(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse(card))
+	c.JSON(http.StatusOK, models.SuccessResponse(services.NewCardResponse(*card)))
 }
 
-// GetCardsByDeck 获取卡包下的所有卡片
+// GetCardsByDeck 获取卡包下的所有卡片，q为搜索语句
 func (h *CardHandler) GetCardsByDeck(c *gin.Context) {
 	deckIDStr := c.Param("id")
 	deckID, err := strconv.ParseUint(deckIDStr, 10, 32)
@@ -71,28 +179,53 @@ func (h *CardHandler) GetCardsByDeck(c *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	cards, err := h.cardService.GetCardsByDeckID(uint(deckID), c.Query("q"), cardPageRequest(c))
+	if err != nil {
+		if isCardListParamError(err) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取卡片列表失败", err.Error()))
+		return
+	}
 
-	// 验证分页参数
-	if page < 1 {
-		page = 1
+	c.JSON(http.StatusOK, models.SuccessResponse(cards))
+}
+
+// ReorderCards 调整卡包中卡片的顺序，移动一张或多张卡片，或第start到第end张卡片
+func (h *CardHandler) ReorderCards(c *gin.Context) {
+	deckIDStr := c.Param("id")
+	deckID, err := strconv.ParseUint(deckIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+
+	var req models.CardReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
 	}
 
-	cards, err := h.cardService.GetCardsByDeckID(uint(deckID), page, pageSize)
+	result, err := h.cardService.ReorderCards(uint(deckID), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取卡片列表失败", err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡包不存在"))
+			return
+		}
+		if errors.Is(err, services.ErrReorderNoCards) || errors.Is(err, services.ErrReorderInvalidRange) ||
+			errors.Is(err, services.ErrReorderCardNotFound) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "调整卡片顺序失败", err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse(cards))
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
 }
 
-// GetCardsByTag 获取标签下的所有卡片
+// GetCardsByTag 获取标签下的所有卡片，q为搜索语句
 func (h *CardHandler) GetCardsByTag(c *gin.Context) {
 	tagIDStr := c.Param("id")
 	tagID, err := strconv.ParseUint(tagIDStr, 10, 32)
@@ -101,20 +234,12 @@ func (h *CardHandler) GetCardsByTag(c *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-
-	// 验证分页参数
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	cards, err := h.cardService.GetCardsByTagID(uint(tagID), page, pageSize)
+	cards, err := h.cardService.GetCardsByTagID(uint(tagID), c.Query("q"), cardPageRequest(c))
 	if err != nil {
+		if isCardListParamError(err) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取卡片列表失败", err.Error()))
 		return
 	}
@@ -142,6 +267,10 @@ func (h *CardHandler) SearchCards(c *gin.Context) {
 
 	cards, err := h.cardService.SearchCards(req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidFlag) || isCardListParamError(err) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "搜索卡片失败", err.Error()))
 		return
 	}
@@ -149,8 +278,55 @@ func (h *CardHandler) SearchCards(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(cards))
 }
 
-// UpdateCard 更新卡片
-func (h *CardHandler) UpdateCard(c *gin.Context) {
+// BulkUpdateCards 批量操作卡片，返回每张卡片的操作结果
+func (h *CardHandler) BulkUpdateCards(c *gin.Context) {
+	var req models.CardBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	result, err := h.cardService.BulkUpdateCards(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBulkAction) || errors.Is(err, services.ErrBulkNoCards) ||
+			errors.Is(err, services.ErrBulkDeckRequired) || errors.Is(err, services.ErrBulkTagRequired) ||
+			errors.Is(err, services.ErrBulkFlagRequired) || errors.Is(err, services.ErrInvalidFlag) ||
+			errors.Is(err, services.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "批量操作卡片失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
+// ReplaceCards 在卡包、标签或搜索结果范围内查找替换卡片内容，dry_run时只预览
+func (h *CardHandler) ReplaceCards(c *gin.Context) {
+	var req models.CardReplaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	result, err := h.cardService.ReplaceCards(req)
+	if err != nil {
+		if errors.Is(err, services.ErrReplaceNoScope) || errors.Is(err, services.ErrInvalidReplaceField) ||
+			errors.Is(err, services.ErrInvalidReplacePattern) || errors.Is(err, services.ErrInvalidFlag) ||
+			errors.Is(err, services.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "查找替换失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
+// SetCardFlag 设置卡片的标记颜色
+func (h *CardHandler) SetCardFlag(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -159,19 +335,57 @@ func (h *CardHandler) UpdateCard(c *gin.Context) {
 	}
 
 	var req struct {
-		DeckID   uint   `json:"deck_id" binding:"required"`
-		TagID    *uint  `json:"tag_id"`
-		Question string `json:"question" binding:"required"`
-		Answer   string `json:"answer" binding:"required"`
+		Flag *int `json:"flag" binding:"required"`
 	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请指定标记颜色"))
+		return
+	}
+
+	card, err := h.cardService.SetCardFlag(uint(id), *req.Flag)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡片不存在"))
+			return
+		}
+		if errors.Is(err, services.ErrInvalidFlag) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "标记卡片失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(services.NewCardResponse(*card)))
+}
+
+// UpdateCard 更新卡片
+func (h *CardHandler) UpdateCard(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡片ID"))
+		return
+	}
+
+	var req cardRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !req.valid() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
 		return
 	}
 
-	card, err := h.cardService.UpdateCard(uint(id), req.DeckID, req.TagID, req.Question, req.Answer)
+	var card *models.Card
+	if req.Type == models.CardTypeChoice {
+		card, err = h.cardService.UpdateChoiceCard(uint(id), req.DeckID, req.tagIDs(), req.Question, req.Answer, req.Format, req.Options)
+	} else {
+		card, err = h.cardService.UpdateCard(uint(id), req.DeckID, req.tagIDs(), req.Question, req.Answer, req.Format)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrCardManagedByNote) || errors.Is(err, services.ErrInvalidOptions) ||
+			errors.Is(err, services.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "更新卡片失败", err.Error()))
 		return
 	}
@@ -194,4 +408,92 @@ func (h *CardHandler) DeleteCard(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
-}
\ No newline at end of file
+}
+
+// GetCardRevisions 获取卡片的修订记录
+func (h *CardHandler) GetCardRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡片ID"))
+		return
+	}
+
+	revisions, err := h.cardService.GetCardRevisions(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡片不存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取修订记录失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(revisions))
+}
+
+// DiffCardRevisions 比较卡片的两个修订
+func (h *CardHandler) DiffCardRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡片ID"))
+		return
+	}
+
+	fromID, err := strconv.ParseUint(c.Query("from"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的起始修订ID"))
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的目标修订ID"))
+		return
+	}
+
+	diff, err := h.cardService.DiffCardRevisions(uint(id), uint(fromID), uint(toID))
+	if err != nil {
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "比较修订失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(diff))
+}
+
+// RevertCard 将卡片恢复到指定修订
+func (h *CardHandler) RevertCard(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡片ID"))
+		return
+	}
+
+	revisionIDStr := c.Param("revisionId")
+	revisionID, err := strconv.ParseUint(revisionIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的修订ID"))
+		return
+	}
+
+	card, err := h.cardService.RevertCard(uint(id), uint(revisionID))
+	if err != nil {
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, err.Error()))
+			return
+		}
+		if errors.Is(err, services.ErrCardManagedByNote) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复卡片失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(services.NewCardResponse(*card)))
+}
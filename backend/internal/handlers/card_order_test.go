@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// createOrderedCards 通过接口在卡包中依次创建卡片，返回卡片ID
+func createOrderedCards(t *testing.T, router http.Handler, deckID uint, count int) []uint {
+	var ids []uint
+	for i := 0; i < count; i++ {
+		var card models.CardResponse
+		w := sendJSON(router, "POST", "/api/v1/cards", map[string]interface{}{
+			"deck_id": deckID, "question": fmt.Sprintf("问题%d", i+1), "answer": "答案",
+		}, &card)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		ids = append(ids, card.ID)
+	}
+	return ids
+}
+
+// positionOrder 按卡包中的顺序获取卡片ID
+func positionOrder(router http.Handler, deckID uint) []uint {
+	_, list := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?sort=position&page_size=100", deckID))
+	return cardIDs(list.Cards)
+}
+
+// TestCardPositions 测试卡片位置：新建卡片排在最后，移动到其他卡包时排在目标卡包最后，导入保留文件中的顺序
+func TestCardPositions(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "顺序"}
+	db.Create(&deck)
+	other := models.Deck{Name: "其他"}
+	db.Create(&other)
+	ids := createOrderedCards(t, router, deck.ID, 3)
+	otherIDs := createOrderedCards(t, router, other.ID, 2)
+
+	var cards []models.Card
+	db.Where("deck_id = ?", deck.ID).Order("id").Find(&cards)
+	for i, card := range cards {
+		assert.Equal(t, i+1, card.Position)
+	}
+
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", ids[0]), map[string]interface{}{
+		"deck_id": other.ID, "question": "问题1", "answer": "答案",
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{otherIDs[0], otherIDs[1], ids[0]}, positionOrder(router, other.ID))
+	var moved models.Card
+	db.First(&moved, ids[0])
+	assert.Equal(t, 3, moved.Position)
+
+	deckID := importDeckFile(t, router, "deck.csv", "导入顺序", []byte("ID,Question,Answer\n1,z,1\n2,a,2\n3,m,3\n"))
+	_, list := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?sort=position", deckID))
+	var questions []string
+	for _, card := range list.Cards {
+		questions = append(questions, card.Question)
+	}
+	assert.Equal(t, []string{"z", "a", "m"}, questions)
+}
+
+// TestReorderCards 测试调整卡片顺序：按卡片ID或范围移动到指定位置
+func TestReorderCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "顺序"}
+	db.Create(&deck)
+	ids := createOrderedCards(t, router, deck.ID, 5)
+	reorderURL := fmt.Sprintf("/api/v1/decks/%d/cards/reorder", deck.ID)
+
+	// 将最后一张卡片移到最前面
+	var result models.CardReorderResponse
+	w := sendJSON(router, "POST", reorderURL, map[string]interface{}{"card_ids": []uint{ids[4]}, "position": 1}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{ids[4]}, result.CardIDs)
+	assert.Equal(t, 1, result.Position)
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, []uint{ids[4], ids[0], ids[1], ids[2], ids[3]}, positionOrder(router, deck.ID))
+
+	// 将第1到第2张卡片移到最后，位置超出范围时放在最后
+	w = sendJSON(router, "POST", reorderURL, map[string]interface{}{"start": 1, "end": 2, "position": 100}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, result.Position)
+	assert.Equal(t, []uint{ids[1], ids[2], ids[3], ids[4], ids[0]}, positionOrder(router, deck.ID))
+
+	// 多张卡片按请求中的顺序连续排列
+	w = sendJSON(router, "POST", reorderURL, map[string]interface{}{"card_ids": []uint{ids[3], ids[1]}, "position": 2}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{ids[2], ids[3], ids[1], ids[4], ids[0]}, positionOrder(router, deck.ID))
+
+	other := models.Deck{Name: "其他"}
+	db.Create(&other)
+	otherIDs := createOrderedCards(t, router, other.ID, 1)
+	for _, body := range []map[string]interface{}{
+		{"position": 1},
+		{"card_ids": []uint{otherIDs[0]}, "position": 1},
+		{"start": 3, "end": 2, "position": 1},
+		{"start": 1, "end": 6, "position": 1},
+		{"card_ids": []uint{ids[0]}},
+	} {
+		w = sendJSON(router, "POST", reorderURL, body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = sendJSON(router, "POST", "/api/v1/decks/999/cards/reorder", map[string]interface{}{"card_ids": []uint{ids[0]}, "position": 1}, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestSequentialNewCards 测试按顺序学习新卡片的卡包
+func TestSequentialNewCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "按顺序"}
+	db.Create(&deck)
+	ids := createOrderedCards(t, router, deck.ID, 6)
+	sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/cards/reorder", deck.ID), map[string]interface{}{
+		"card_ids": []uint{ids[5], ids[4]}, "position": 1,
+	}, nil)
+
+	var updated models.Deck
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{"new_card_order": "sequential"}, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.NewCardOrderSequential, updated.NewCardOrder)
+
+	var session models.StudySession
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/deck/%d?limit=3", deck.ID), nil, &session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var queue []uint
+	for _, item := range session.Queue {
+		queue = append(queue, item.CardID)
+	}
+	assert.Equal(t, []uint{ids[5], ids[4], ids[0]}, queue)
+
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{"new_card_order": "shuffle"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
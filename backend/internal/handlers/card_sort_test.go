@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// listCards 请求卡片列表接口，返回状态码和卡片列表响应
+func listCards(router http.Handler, url string) (int, models.CardListResponse) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", url, nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Data models.CardListResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Data
+}
+
+// cardIDs 获取卡片列表中的卡片ID
+func cardIDs(cards []models.CardResponse) []uint {
+	ids := []uint{}
+	for _, card := range cards {
+		ids = append(ids, card.ID)
+	}
+	return ids
+}
+
+// TestCardListSort 测试卡片列表的各种排序方式和排序方向
+func TestCardListSort(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "排序"}
+	db.Create(&deck)
+	now := time.Now()
+	apple := models.Card{DeckID: deck.ID, Question: "apple", Answer: "苹果"}
+	banana := models.Card{DeckID: deck.ID, Question: "Banana", Answer: "香蕉"}
+	cherry := models.Card{DeckID: deck.ID, Question: "cherry", Answer: "樱桃"}
+	for i, card := range []*models.Card{&apple, &banana, &cherry} {
+		db.Create(card)
+		db.Model(card).UpdateColumns(map[string]interface{}{
+			"created_at": now.AddDate(0, 0, i-10),
+			"updated_at": now.AddDate(0, 0, -i),
+		})
+	}
+	// cherry为新卡片，到期时间按创建时间计
+	db.Create(&models.Review{CardID: apple.ID, Interval: 10, EFactor: 2.1, Lapses: 2, NextReview: now.AddDate(0, 0, 3)})
+	db.Create(&models.Review{CardID: banana.ID, Interval: 3, EFactor: 2.7, Lapses: 0, NextReview: now.AddDate(0, 0, -1)})
+
+	cases := []struct {
+		query string
+		want  []uint
+	}{
+		{"", []uint{cherry.ID, banana.ID, apple.ID}},
+		{"sort=created&order=asc", []uint{apple.ID, banana.ID, cherry.ID}},
+		{"sort=updated", []uint{apple.ID, banana.ID, cherry.ID}},
+		{"sort=due", []uint{cherry.ID, banana.ID, apple.ID}},
+		{"sort=interval&order=desc", []uint{apple.ID, banana.ID, cherry.ID}},
+		{"sort=ease", []uint{apple.ID, cherry.ID, banana.ID}},
+		{"sort=lapses&order=desc", []uint{apple.ID, cherry.ID, banana.ID}},
+		{"sort=question", []uint{apple.ID, banana.ID, cherry.ID}},
+		{"sort=question&order=desc", []uint{cherry.ID, banana.ID, apple.ID}},
+		// 没有关键词时按相关度排序改为按创建时间
+		{"sort=relevance", []uint{cherry.ID, banana.ID, apple.ID}},
+	}
+	for _, c := range cases {
+		code, list := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?%s", deck.ID, c.query))
+		assert.Equal(t, http.StatusOK, code, c.query)
+		assert.Equal(t, c.want, cardIDs(list.Cards), c.query)
+	}
+
+	// 搜索接口和保存的搜索同样支持排序
+	code, list := listCards(router, "/api/v1/cards?page=1&page_size=10&sort=interval&order=desc&q=is:review")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint{apple.ID, banana.ID}, cardIDs(list.Cards))
+	var search models.SavedSearch
+	sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{"name": "全部"}, &search)
+	_, list = listCards(router, fmt.Sprintf("/api/v1/saved-searches/%d/cards?sort=question&order=desc", search.ID))
+	assert.Equal(t, []uint{cherry.ID, banana.ID, apple.ID}, cardIDs(list.Cards))
+
+	for _, query := range []string{"sort=size", "sort=due&order=up"} {
+		code, _ := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?%s", deck.ID, query))
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+// TestCardListCursor 测试按游标分页：翻页过程中新增卡片不会导致重复或遗漏
+func TestCardListCursor(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "游标"}
+	db.Create(&deck)
+	now := time.Now()
+	var want []uint
+	for i := 0; i < 7; i++ {
+		card := models.Card{DeckID: deck.ID, Question: fmt.Sprintf("问题%d", i), Answer: "答案"}
+		db.Create(&card)
+		// 两张卡片的创建时间相同，按ID区分先后
+		db.Model(&card).UpdateColumn("created_at", now.Add(-time.Duration(i/2)*time.Hour))
+		want = append(want, card.ID)
+	}
+	// 默认按创建时间降序，创建时间相同时按ID降序
+	want = []uint{want[1], want[0], want[3], want[2], want[5], want[4], want[6]}
+
+	listURL := fmt.Sprintf("/api/v1/decks/%d/cards?page_size=3", deck.ID)
+	code, list := listCards(router, listURL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, list.Page)
+	assert.Equal(t, int64(7), list.Total)
+	got := cardIDs(list.Cards)
+
+	// 翻页期间新增的卡片排在最前面，不影响后续页
+	db.Create(&models.Card{DeckID: deck.ID, Question: "新卡片", Answer: "答案"})
+
+	for pages := 1; list.NextCursor != ""; pages++ {
+		if !assert.Less(t, pages, 5) {
+			break
+		}
+		code, list = listCards(router, listURL+"&cursor="+list.NextCursor)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 0, list.Page)
+		got = append(got, cardIDs(list.Cards)...)
+	}
+	assert.Equal(t, want, got)
+
+	// 按其他方式排序时游标同样有效
+	listURL = fmt.Sprintf("/api/v1/decks/%d/cards?page_size=5&sort=question&order=asc", deck.ID)
+	_, list = listCards(router, listURL)
+	got = cardIDs(list.Cards)
+	if assert.NotEmpty(t, list.NextCursor) {
+		_, next := listCards(router, listURL+"&cursor="+list.NextCursor)
+		assert.Empty(t, next.NextCursor)
+		got = append(got, cardIDs(next.Cards)...)
+	}
+	assert.Equal(t, 8, len(got))
+
+	// 游标与排序方式不一致或格式错误时返回400
+	code, _ = listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?sort=due&cursor=%s", deck.ID, list.NextCursor))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?cursor=not-a-cursor", deck.ID))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 有关键词时默认按相关度排序，游标同样有效
+	listURL = "/api/v1/cards?page_size=1&keyword=" + url.QueryEscape("问题")
+	_, list = listCards(router, listURL)
+	got = cardIDs(list.Cards)
+	for pages := 1; list.NextCursor != ""; pages++ {
+		if !assert.Less(t, pages, 10) {
+			break
+		}
+		_, list = listCards(router, listURL+"&cursor="+list.NextCursor)
+		got = append(got, cardIDs(list.Cards)...)
+	}
+	assert.ElementsMatch(t, want, got)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// importDeckFile 通过接口导入卡包文件，返回导入的卡包ID
+func importDeckFile(t *testing.T, router http.Handler, filename, deckName string, content []byte) uint {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.WriteField("deck_name", deckName)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/import-export/decks", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Data struct {
+			DeckID uint `json:"deck_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data.DeckID
+}
+
+// exportDeckFile 通过接口导出卡包，返回导出文件的内容
+func exportDeckFile(t *testing.T, router http.Handler, deckID uint, format string) []byte {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/import-export/decks/%d?format=%s", deckID, format), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Filename string `json:"filename"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	content, err := os.ReadFile(response.Data.Filename)
+	assert.NoError(t, err)
+	os.Remove(response.Data.Filename)
+	return content
+}
+
+// TestCardWithMultipleTags 测试创建和更新带多个标签的卡片
+func TestCardWithMultipleTags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	tag1 := models.Tag{DeckID: &deck.ID, Name: "标签1", Path: "标签1"}
+	tag2 := models.Tag{DeckID: &deck.ID, Name: "标签2", Path: "标签2"}
+	tag3 := models.Tag{DeckID: &deck.ID, Name: "标签3", Path: "标签3"}
+	db.Create(&tag1)
+	db.Create(&tag2)
+	db.Create(&tag3)
+
+	// 旧版本的tag_id和tag_ids合并，重复的标签只保留一个
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"deck_id":  deck.ID,
+		"tag_id":   tag1.ID,
+		"tag_ids":  []uint{tag1.ID, tag2.ID},
+		"question": "问题",
+		"answer":   "答案",
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/cards", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Data models.Card `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, 2, len(created.Data.Tags))
+
+	// 更新时替换全部标签
+	jsonData, _ = json.Marshal(map[string]interface{}{
+		"deck_id":  deck.ID,
+		"tag_ids":  []uint{tag2.ID, tag3.ID},
+		"question": "问题",
+		"answer":   "答案",
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/api/v1/cards/%d", created.Data.ID), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d", created.Data.ID), nil)
+	router.ServeHTTP(w, req)
+	var fetched struct {
+		Data models.CardResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.ElementsMatch(t, []string{"标签2", "标签3"}, fetched.Data.TagNames)
+
+	// 修订记录保存标签变化
+	revisions := getCardRevisions(t, router, created.Data.ID)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, []uint{tag2.ID, tag3.ID}, revisions[0].TagIDs)
+	assert.Contains(t, revisions[0].ChangedFields, models.FieldTagIDs)
+
+	// 不存在的标签
+	jsonData, _ = json.Marshal(map[string]interface{}{
+		"deck_id":  deck.ID,
+		"tag_ids":  []uint{tag1.ID, 9999},
+		"question": "问题",
+		"answer":   "答案",
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/cards", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestUpdateCardKeepsTags 测试更新卡片时未提供标签字段则保留原有标签，以及兼容旧版本的tag_id和tag_name
+func TestUpdateCardKeepsTags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	tag1 := createDeckTag(db, deck.ID, nil, "标签1")
+	tag2 := createDeckTag(db, deck.ID, nil, "标签2")
+
+	var card models.Card
+	w := sendJSON(router, "POST", "/api/v1/cards", map[string]interface{}{
+		"deck_id": deck.ID, "tag_ids": []uint{tag1.ID, tag2.ID}, "question": "问题", "answer": "答案",
+	}, &card)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", card.ID), map[string]interface{}{
+		"deck_id": deck.ID, "question": "新问题", "answer": "答案",
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var fetched models.CardResponse
+	sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, &fetched)
+	assert.Equal(t, "新问题", fetched.Question)
+	assert.Equal(t, []string{"标签1", "标签2"}, fetched.TagNames)
+	if assert.NotNil(t, fetched.TagID) {
+		assert.Equal(t, tag1.ID, *fetched.TagID)
+	}
+	assert.Equal(t, "标签1", fetched.TagName)
+
+	// 学习队列同样返回标签ID和旧版本的字段
+	var session models.StudySession
+	sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/deck/%d", deck.ID), nil, &session)
+	if assert.Equal(t, 1, len(session.Queue)) {
+		assert.Equal(t, []uint{tag1.ID, tag2.ID}, session.Queue[0].TagIDs)
+		assert.Equal(t, "标签1", session.Queue[0].TagName)
+	}
+
+	// 旧版本客户端只提供tag_id时替换为该标签，空的tag_ids清除全部标签
+	sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", card.ID), map[string]interface{}{
+		"deck_id": deck.ID, "tag_id": tag2.ID, "question": "新问题", "answer": "答案",
+	}, nil)
+	sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, &fetched)
+	assert.Equal(t, []string{"标签2"}, fetched.TagNames)
+
+	sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", card.ID), map[string]interface{}{
+		"deck_id": deck.ID, "tag_ids": []uint{}, "question": "新问题", "answer": "答案",
+	}, nil)
+	fetched = models.CardResponse{}
+	sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, &fetched)
+	assert.Empty(t, fetched.TagNames)
+	assert.Nil(t, fetched.TagID)
+}
+
+// TestSearchCardsByTags 测试按多个标签搜索卡片和标签统计
+func TestSearchCardsByTags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	tag1 := models.Tag{DeckID: &deck.ID, Name: "标签1", Path: "标签1"}
+	tag2 := models.Tag{DeckID: &deck.ID, Name: "标签2", Path: "标签2"}
+	db.Create(&tag1)
+	db.Create(&tag2)
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{tag1, tag2}, Question: "两个标签", Answer: "答案"})
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{tag1}, Question: "一个标签", Answer: "答案"})
+	db.Create(&models.Card{DeckID: deck.ID, Question: "没有标签", Answer: "答案"})
+
+	var response struct {
+		Data models.CardListResponse `json:"data"`
+	}
+
+	// 同时指定多个标签时只返回带全部标签的卡片
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/cards?tag_ids=%d&tag_ids=%d&page=1&page_size=20", tag1.ID, tag2.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(1), response.Data.Total)
+	assert.Equal(t, "两个标签", response.Data.Cards[0].Question)
+	assert.ElementsMatch(t, []string{"标签1", "标签2"}, response.Data.Cards[0].TagNames)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/tags/%d/cards", tag1.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(2), response.Data.Total)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/tags/%d/stats", tag2.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		Data models.TagStats `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, 1, stats.Data.TotalCards)
+}
+
+// TestImportExportMultipleTags 测试各导入导出格式保留多个标签
+func TestImportExportMultipleTags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "多标签卡包"}
+	db.Create(&deck)
+	tag1 := models.Tag{DeckID: &deck.ID, Name: "标签1", Path: "标签1"}
+	tag2 := models.Tag{DeckID: &deck.ID, Name: "标签2", Path: "标签2"}
+	db.Create(&tag1)
+	db.Create(&tag2)
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{tag1, tag2}, Question: "两个标签", Answer: "答案1"})
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{tag2}, Question: "一个标签", Answer: "答案2"})
+
+	for _, format := range []string{"json", "csv", "txt"} {
+		content := exportDeckFile(t, router, deck.ID, format)
+		switch format {
+		case "csv":
+			assert.Contains(t, string(content), "标签1;标签2")
+		case "txt":
+			assert.True(t, strings.Contains(string(content), "# 标签1\n# 标签2\n"))
+		}
+
+		deckID := importDeckFile(t, router, "deck."+format, "导入"+format, content)
+		var cards []models.Card
+		db.Preload("Tags").Where("deck_id = ?", deckID).Order("question").Find(&cards)
+		if !assert.Equal(t, 2, len(cards), format) {
+			continue
+		}
+
+		var names []string
+		for _, tag := range cards[1].Tags {
+			names = append(names, tag.Name)
+		}
+		assert.ElementsMatch(t, []string{"标签1", "标签2"}, names, format)
+		assert.Equal(t, 1, len(cards[0].Tags), format)
+
+		// 同名标签只创建一次
+		var count int64
+		db.Model(&models.Tag{}).Where("deck_id = ?", deckID).Count(&count)
+		assert.Equal(t, int64(2), count, format)
+	}
+}
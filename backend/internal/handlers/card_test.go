@@ -22,10 +22,10 @@ func TestSearchCards(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
-	card1 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "Go语言是什么", Answer: "Go是一种编程语言"}
+	card1 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "Go语言是什么", Answer: "Go是一种编程语言"}
 	card2 := models.Card{DeckID: deck.ID, Question: "Python是什么", Answer: "Python是一种编程语言"}
 	db.Create(&card1)
 	db.Create(&card2)
@@ -68,7 +68,7 @@ func TestCreateCard(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	cardData := map[string]interface{}{
@@ -102,7 +102,9 @@ func TestCreateCard(t *testing.T) {
 	assert.Equal(t, "什么是Go语言？", card["question"])
 	assert.Equal(t, "Go是一种开源的编程语言", card["answer"])
 	assert.Equal(t, deck.ID, uint(card["deck_id"].(float64)))
-	assert.Equal(t, tag.ID, uint(card["tag_id"].(float64)))
+	tags := card["tags"].([]interface{})
+	assert.Equal(t, 1, len(tags))
+	assert.Equal(t, tag.ID, uint(tags[0].(map[string]interface{})["id"].(float64)))
 }
 
 // TestGetCard 测试获取卡片详情
@@ -256,11 +258,11 @@ func TestGetCardsByTag(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
-	card1 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "问题1", Answer: "答案1"}
-	card2 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "问题2", Answer: "答案2"}
+	card1 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题1", Answer: "答案1"}
+	card2 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题2", Answer: "答案2"}
 	db.Create(&card1)
 	db.Create(&card2)
 
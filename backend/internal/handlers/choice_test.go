@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+)
+
+// createChoiceCard 通过接口创建选择题卡片
+func createChoiceCard(t *testing.T, router http.Handler, deckID uint, options []map[string]interface{}) models.Card {
+	var card models.Card
+	w := sendJSON(router, "POST", "/api/v1/cards", map[string]interface{}{
+		"deck_id":  deckID,
+		"type":     "choice",
+		"question": "以下哪些是Go的关键字？",
+		"answer":   "解析",
+		"options":  options,
+	}, &card)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return card
+}
+
+// submitChoices 提交选择题答案
+func submitChoices(t *testing.T, router http.Handler, cardID uint, choices []uint) (int, models.ReviewResponse) {
+	var review models.ReviewResponse
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/review/%d", cardID), map[string]interface{}{"choices": choices}, &review)
+	return w.Code, review
+}
+
+// TestChoiceCardGrading 测试选择题评分映射为复习结果
+func TestChoiceCardGrading(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	card := createChoiceCard(t, router, deck.ID, []map[string]interface{}{
+		{"content": "func", "correct": true},
+		{"content": "defer", "correct": true},
+		{"content": "class", "correct": false},
+	})
+	assert.Equal(t, models.CardTypeChoice, card.Type)
+	assert.Equal(t, 3, len(card.Options))
+	funcID, deferID, classID := card.Options[0].ID, card.Options[1].ID, card.Options[2].ID
+
+	code, response := submitChoices(t, router, card.ID, []uint{funcID, deferID})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.Good, response.Result)
+	assert.True(t, *response.Correct)
+	assert.ElementsMatch(t, []uint{funcID, deferID}, response.CorrectOptions)
+
+	_, response = submitChoices(t, router, card.ID, []uint{funcID})
+	assert.Equal(t, models.Hard, response.Result)
+	assert.False(t, *response.Correct)
+
+	_, response = submitChoices(t, router, card.ID, []uint{funcID, classID})
+	assert.Equal(t, models.Again, response.Result)
+
+	var logCount int64
+	db.Model(&models.ReviewLog{}).Where("card_id = ?", card.ID).Count(&logCount)
+	assert.Equal(t, int64(3), logCount)
+
+	// 不属于该卡片的选项
+	code, _ = submitChoices(t, router, card.ID, []uint{classID + 100})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 选择题不能跳过选项直接自评
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/review/%d", card.ID), map[string]interface{}{"result": models.Good}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	code, _ = submitChoices(t, router, card.ID, []uint{})
+	assert.Equal(t, http.StatusBadRequest, code)
+	db.Model(&models.ReviewLog{}).Where("card_id = ?", card.ID).Count(&logCount)
+	assert.Equal(t, int64(3), logCount)
+}
+
+// TestChoiceCardValidation 测试选择题选项校验
+func TestChoiceCardValidation(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	cardData := map[string]interface{}{
+		"deck_id":  deck.ID,
+		"type":     "choice",
+		"question": "没有正确选项",
+		"options":  []map[string]interface{}{{"content": "A"}, {"content": "B"}},
+	}
+	jsonData, _ := json.Marshal(cardData)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/cards", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestStudyShufflesChoiceOptions 测试学习队列返回选项但不泄露正确答案
+func TestStudyShufflesChoiceOptions(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	createChoiceCard(t, router, deck.ID, []map[string]interface{}{
+		{"content": "A", "correct": true},
+		{"content": "B", "correct": false},
+		{"content": "C", "correct": false},
+		{"content": "D", "correct": false},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/study/deck/%d", deck.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "correct")
+
+	var response struct {
+		Data models.StudySession `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(response.Data.Queue))
+	assert.Equal(t, 4, len(response.Data.Queue[0].Options))
+	assert.False(t, response.Data.Queue[0].Multiple)
+}
+
+// TestChoiceImportExportRoundTrip 测试选择题在JSON格式中往返
+func TestChoiceImportExportRoundTrip(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "选择题卡包"}
+	db.Create(&deck)
+	createChoiceCard(t, router, deck.ID, []map[string]interface{}{
+		{"content": "func", "correct": true},
+		{"content": "class", "correct": false},
+	})
+
+	importExportService := services.NewImportExportService()
+	filename, err := importExportService.ExportDeck(deck.ID, "json")
+	assert.NoError(t, err)
+
+	imported, err := importExportService.ImportDeckWithName(filename, "导入的选择题卡包")
+	os.Remove(filename)
+	assert.NoError(t, err)
+
+	var card models.Card
+	db.Where("deck_id = ?", imported.ID).Preload("Options").First(&card)
+	assert.Equal(t, models.CardTypeChoice, card.Type)
+	assert.Equal(t, 2, len(card.Options))
+	assert.Equal(t, "func", card.Options[0].Content)
+	assert.True(t, card.Options[0].Correct)
+	assert.False(t, card.Options[1].Correct)
+}
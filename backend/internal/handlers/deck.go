@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // DeckHandler 卡包处理器
@@ -24,7 +26,8 @@ func NewDeckHandler() *DeckHandler {
 // CreateDeck 创建卡包
 func (h *DeckHandler) CreateDeck(c *gin.Context) {
 	var req struct {
-		Name string `json:"name" binding:"required,min=1,max=100"`
+		Name     string `json:"name" binding:"required,min=1,max=100"`
+		ParentID *uint  `json:"parent_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -32,9 +35,9 @@ func (h *DeckHandler) CreateDeck(c *gin.Context) {
 		return
 	}
 
-	deck, err := h.deckService.CreateDeck(req.Name)
+	deck, err := h.deckService.CreateDeck(req.Name, req.ParentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "创建卡包失败", err.Error()))
+		respondDeckError(c, err, "创建卡包失败")
 		return
 	}
 
@@ -93,30 +96,21 @@ func (h *DeckHandler) UpdateDeck(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name     *string `json:"name"`
-		Archived *bool   `json:"archived"`
-	}
-
+	var req models.UpdateDeckRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
 		return
 	}
 
 	// 检查至少有一个字段需要更新
-	if req.Name == nil && req.Archived == nil {
+	if req.IsEmpty() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "至少需要提供一个更新字段"))
 		return
 	}
 
-	var name string
-	if req.Name != nil {
-		name = *req.Name
-	}
-
-	deck, err := h.deckService.UpdateDeck(uint(id), name, req.Archived)
+	deck, err := h.deckService.UpdateDeck(uint(id), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "更新卡包失败", err.Error()))
+		respondDeckError(c, err, "更新卡包失败")
 		return
 	}
 
@@ -132,14 +126,144 @@ func (h *DeckHandler) DeleteDeck(c *gin.Context) {
 		return
 	}
 
-	if err := h.deckService.DeleteDeck(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "删除卡包失败", err.Error()))
+	// 包含子卡包时需要指定子卡包的处理方式：delete 一起删除，reparent 移动到上级卡包
+	children := c.Query("children")
+	if children != "" && children != models.DeckChildrenDelete && children != models.DeckChildrenReparent {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "children参数只能是delete或reparent"))
+		return
+	}
+
+	if err := h.deckService.DeleteDeck(uint(id), children); err != nil {
+		respondDeckError(c, err, "删除卡包失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
 }
 
+// MoveDeck 移动卡包到新的上级卡包下
+func (h *DeckHandler) MoveDeck(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
+	}
+
+	var req struct {
+		ParentID *uint `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	deck, err := h.deckService.MoveDeck(uint(id), req.ParentID)
+	if err != nil {
+		respondDeckError(c, err, "移动卡包失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(deck))
+}
+
+// CloneDeck 复制卡包，可选同时复制复习进度
+func (h *DeckHandler) CloneDeck(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
+	}
+
+	var req models.DeckCloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	result, err := h.deckService.CloneDeck(uint(id), req)
+	if err != nil {
+		respondDeckError(c, err, "复制卡包失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(result))
+}
+
+// MergeDeck 将卡包合并到目标卡包并删除该卡包
+func (h *DeckHandler) MergeDeck(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
+	}
+
+	var req models.DeckMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	result, err := h.deckService.MergeDeck(uint(id), req)
+	if err != nil {
+		respondDeckError(c, err, "合并卡包失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
+// GetDeckTree 获取卡包树及各卡包的统计信息
+func (h *DeckHandler) GetDeckTree(c *gin.Context) {
+	tree, err := h.deckService.GetDeckTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取卡包树失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]interface{}{
+		"decks": tree,
+	}))
+}
+
+// GetDeckDuplicates 获取卡包中问题重复的卡片
+func (h *DeckHandler) GetDeckDuplicates(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+		return
+	}
+
+	groups, err := h.deckService.GetDuplicates(uint(id))
+	if err != nil {
+		respondDeckError(c, err, "获取重复卡片失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]interface{}{
+		"duplicates": groups,
+	}))
+}
+
+// respondDeckError 根据卡包服务返回的错误写入响应
+func respondDeckError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡包不存在"))
+	case errors.Is(err, services.ErrDeckHasChildren), errors.Is(err, services.ErrDeckNameExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error()))
+	case errors.Is(err, services.ErrInvalidDeckParent), errors.Is(err, services.ErrDeckCycle),
+		errors.Is(err, services.ErrDeckMergeCycle), errors.Is(err, services.ErrInvalidColor):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, message, err.Error()))
+	}
+}
+
 // GetDeckStats 获取卡包统计信息
 func (h *DeckHandler) GetDeckStats(c *gin.Context) {
 	idStr := c.Param("id")
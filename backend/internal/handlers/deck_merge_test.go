@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"flashcard/internal/models"
+)
+
+// createDeckTag 在卡包中创建标签，parent不为空时作为其子标签
+func createDeckTag(db *gorm.DB, deckID uint, parent *models.Tag, name string) models.Tag {
+	tag := models.Tag{DeckID: &deckID, Name: name, Path: name}
+	if parent != nil {
+		tag.ParentID = &parent.ID
+		tag.Path = parent.Path + models.TagPathSeparator + name
+	}
+	db.Create(&tag)
+	return tag
+}
+
+// TestCloneDeck 测试复制卡包：复制标签树、卡片、选项和笔记，保持顺序，默认不复制复习进度
+func TestCloneDeck(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "生物", StudyLimit: 30, NewCardOrder: models.NewCardOrderSequential}
+	db.Create(&deck)
+	cell := createDeckTag(db, deck.ID, nil, "细胞")
+	organelle := createDeckTag(db, deck.ID, &cell, "细胞器")
+
+	ids := createOrderedCards(t, router, deck.ID, 2)
+	db.Model(&models.Card{ID: ids[0]}).Association("Tags").Append(&organelle)
+	db.Model(&models.Card{}).Where("id = ?", ids[1]).UpdateColumns(map[string]interface{}{"flag": models.FlagRed, "suspended": true})
+	var choice models.CardResponse
+	sendJSON(router, "POST", "/api/v1/cards", map[string]interface{}{
+		"deck_id": deck.ID, "type": "choice", "question": "细胞的能量工厂",
+		"options": []map[string]interface{}{{"content": "线粒体", "correct": true}, {"content": "核糖体"}},
+	}, &choice)
+	var note models.Note
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "type": "cloze", "content": "{{c1::线粒体}}有{{c2::双层膜}}", "tag_ids": []uint{cell.ID},
+	}, &note)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	// 将第二张卡片移到最后
+	sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/cards/reorder", deck.ID), map[string]interface{}{
+		"card_ids": []uint{ids[1]}, "position": 100,
+	}, nil)
+	db.Create(&models.Review{CardID: ids[0], Interval: 12, NextReview: time.Now().AddDate(0, 0, 12)})
+
+	var result models.DeckCloneResult
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", deck.ID), map[string]interface{}{}, &result)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if !assert.NotNil(t, result.Deck) {
+		return
+	}
+	clone := result.Deck
+	assert.Equal(t, "生物 (副本)", clone.Name)
+	assert.Equal(t, 30, clone.StudyLimit)
+	assert.Equal(t, models.NewCardOrderSequential, clone.NewCardOrder)
+	assert.Equal(t, 5, result.Cards)
+	assert.Equal(t, 1, result.Notes)
+	assert.Equal(t, 2, result.Tags)
+
+	// 卡片保持原来的顺序，标签改为新卡包中的标签
+	_, original := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?sort=position", deck.ID))
+	_, copied := listCards(router, fmt.Sprintf("/api/v1/decks/%d/cards?sort=position", clone.ID))
+	if assert.Equal(t, len(original.Cards), len(copied.Cards)) {
+		for i := range original.Cards {
+			assert.Equal(t, original.Cards[i].Question, copied.Cards[i].Question)
+			assert.NotEqual(t, original.Cards[i].ID, copied.Cards[i].ID)
+		}
+	}
+	var cards []models.Card
+	db.Preload("Tags").Preload("Options").Where("deck_id = ?", clone.ID).Order("position").Find(&cards)
+	if assert.Equal(t, 5, len(cards)) {
+		if assert.Equal(t, 1, len(cards[0].Tags)) {
+			assert.Equal(t, clone.ID, *cards[0].Tags[0].DeckID)
+			assert.Equal(t, "细胞::细胞器", cards[0].Tags[0].Path)
+		}
+		assert.Equal(t, 2, len(cards[1].Options))
+		assert.NotNil(t, cards[2].NoteID)
+		assert.NotEqual(t, note.ID, *cards[2].NoteID)
+		assert.Equal(t, models.FlagRed, cards[4].Flag)
+		assert.False(t, cards[4].Suspended)
+	}
+	var reviews int64
+	db.Model(&models.Review{}).Joins("JOIN cards ON cards.id = reviews.card_id").Where("cards.deck_id = ?", clone.ID).Count(&reviews)
+	assert.Equal(t, int64(0), reviews)
+
+	// 再次复制时名称添加编号，指定已存在的名称返回409
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", deck.ID), map[string]interface{}{}, &result)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "生物 (副本 2)", result.Deck.Name)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", deck.ID), map[string]interface{}{"name": "生物"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/decks/999/clone", map[string]interface{}{}, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestCloneDeckWithScheduling 测试复制卡包时同时复制复习进度
+func TestCloneDeckWithScheduling(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	ids := createOrderedCards(t, router, deck.ID, 2)
+	next := time.Now().AddDate(0, 0, 20).Truncate(time.Second)
+	db.Create(&models.Review{CardID: ids[0], Interval: 20, EFactor: 2.3, Repetitions: 3, NextReview: next})
+	db.Create(&models.ReviewLog{CardID: ids[0], Result: models.Good, Interval: 20, EFactor: 2.3, ReviewedAt: time.Now()})
+	db.Model(&models.Card{}).Where("id = ?", ids[1]).UpdateColumn("suspended", true)
+
+	var result models.DeckCloneResult
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", deck.ID), map[string]interface{}{
+		"name": "日语 进阶", "include_scheduling": true,
+	}, &result)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if !assert.NotNil(t, result.Deck) {
+		return
+	}
+	assert.Equal(t, "日语 进阶", result.Deck.Name)
+	assert.True(t, result.IncludeScheduling)
+
+	var cards []models.Card
+	db.Preload("Review").Where("deck_id = ?", result.Deck.ID).Order("position").Find(&cards)
+	if assert.Equal(t, 2, len(cards)) && assert.NotNil(t, cards[0].Review) {
+		assert.Equal(t, 20, cards[0].Review.Interval)
+		assert.Equal(t, 2.3, cards[0].Review.EFactor)
+		assert.True(t, next.Equal(cards[0].Review.NextReview))
+		assert.True(t, cards[1].Suspended)
+
+		var logs int64
+		db.Model(&models.ReviewLog{}).Where("card_id = ?", cards[0].ID).Count(&logs)
+		assert.Equal(t, int64(1), logs)
+	}
+	// 原卡片的复习记录不受影响
+	var original models.Review
+	db.Where("card_id = ?", ids[0]).First(&original)
+	assert.Equal(t, 20, original.Interval)
+}
+
+// TestMergeDeck 测试合并卡包：同路径标签合并，卡片排在目标卡包最后，子卡包移动到目标卡包下，源卡包移入回收站
+func TestMergeDeck(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	source := models.Deck{Name: "日语 N5"}
+	db.Create(&source)
+	target := models.Deck{Name: "日语"}
+	db.Create(&target)
+	child := models.Deck{Name: "N5 动词", ParentID: &source.ID}
+	db.Create(&child)
+
+	targetVerbs := createDeckTag(db, target.ID, nil, "动词")
+	sourceVerbs := createDeckTag(db, source.ID, nil, "动词")
+	irregular := createDeckTag(db, source.ID, &sourceVerbs, "不规则")
+	kana := createDeckTag(db, source.ID, nil, "假名")
+
+	targetIDs := createOrderedCards(t, router, target.ID, 1)
+	var sourceIDs []uint
+	for _, question := range []string{"する", "くる", "あ"} {
+		var card models.CardResponse
+		sendJSON(router, "POST", "/api/v1/cards", map[string]interface{}{"deck_id": source.ID, "question": question, "answer": "答案"}, &card)
+		sourceIDs = append(sourceIDs, card.ID)
+	}
+	db.Model(&models.Card{ID: sourceIDs[0]}).Association("Tags").Append(&sourceVerbs)
+	db.Model(&models.Card{ID: sourceIDs[1]}).Association("Tags").Append(&irregular)
+	db.Model(&models.Card{ID: sourceIDs[2]}).Association("Tags").Append(&kana)
+	var note models.Note
+	sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": source.ID, "type": "cloze", "content": "{{c1::食べる}}是动词", "tag_ids": []uint{sourceVerbs.ID},
+	}, &note)
+
+	var result models.DeckMergeResult
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", source.ID), map[string]interface{}{"target_id": target.ID}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, source.ID, result.SourceID)
+	assert.Equal(t, 4, result.MovedCards)
+	assert.Equal(t, 1, result.MovedNotes)
+	assert.Equal(t, 1, result.MergedTags)
+	assert.Equal(t, 2, result.MovedTags)
+	assert.Equal(t, 1, result.MovedDecks)
+	assert.Empty(t, result.DuplicateCardIDs)
+
+	// 卡片按原来的顺序排在目标卡包已有卡片之后
+	order := positionOrder(router, target.ID)
+	if assert.Equal(t, 5, len(order)) {
+		assert.Equal(t, append(targetIDs, sourceIDs...), order[:4])
+	}
+
+	// 同路径的标签合并为目标卡包的标签，其余标签保留ID移动到目标卡包下
+	var card models.Card
+	db.Preload("Tags").First(&card, sourceIDs[0])
+	assert.Equal(t, []uint{targetVerbs.ID}, tagIDsOfCard(card))
+	var moved models.Tag
+	db.First(&moved, irregular.ID)
+	assert.Equal(t, target.ID, *moved.DeckID)
+	assert.Equal(t, targetVerbs.ID, *moved.ParentID)
+	assert.Equal(t, "动词::不规则", moved.Path)
+	var mergedNote models.Note
+	db.Preload("Tags").First(&mergedNote, note.ID)
+	assert.Equal(t, target.ID, mergedNote.DeckID)
+	if assert.Equal(t, 1, len(mergedNote.Tags)) {
+		assert.Equal(t, targetVerbs.ID, mergedNote.Tags[0].ID)
+	}
+
+	// 移动卡包记录在修订中
+	var revision models.CardRevision
+	db.Where("card_id = ?", sourceIDs[2]).Order("id DESC").First(&revision)
+	assert.Equal(t, []string{models.FieldDeckID}, revision.ChangedFields)
+
+	var movedChild models.Deck
+	db.First(&movedChild, child.ID)
+	assert.Equal(t, target.ID, *movedChild.ParentID)
+	var deleted models.Deck
+	assert.Error(t, db.First(&deleted, source.ID).Error)
+	assert.NoError(t, db.Unscoped().First(&deleted, source.ID).Error)
+}
+
+// tagIDsOfCard 获取卡片的标签ID
+func tagIDsOfCard(card models.Card) []uint {
+	ids := []uint{}
+	for _, tag := range card.Tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
+
+// TestMergeDeckDuplicates 测试合并卡包时按选项处理问题重复的卡片，以及无效的合并
+func TestMergeDeckDuplicates(t *testing.T) {
+	for _, mode := range []string{"keep", "skip", "update"} {
+		db := setupTestDB()
+		router := setupRouter(db)
+
+		source := models.Deck{Name: "源"}
+		db.Create(&source)
+		target := models.Deck{Name: "目标"}
+		db.Create(&target)
+		existing := models.Card{DeckID: target.ID, Question: "Apple", Answer: "苹果", Position: 1}
+		db.Create(&existing)
+		duplicate := models.Card{DeckID: source.ID, Question: "ａｐｐｌｅ", Answer: "苹果（水果）", Position: 1}
+		db.Create(&duplicate)
+		unique := models.Card{DeckID: source.ID, Question: "Banana", Answer: "香蕉", Position: 2}
+		db.Create(&unique)
+
+		var result models.DeckMergeResult
+		w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", source.ID), map[string]interface{}{
+			"target_id": target.ID, "on_duplicate": mode,
+		}, &result)
+		assert.Equal(t, http.StatusOK, w.Code, mode)
+
+		var count int64
+		db.Model(&models.Card{}).Where("deck_id = ?", target.ID).Count(&count)
+		var updated models.Card
+		db.First(&updated, existing.ID)
+		switch mode {
+		case "keep":
+			assert.Equal(t, 2, result.MovedCards)
+			assert.Equal(t, int64(3), count)
+		case "skip":
+			assert.Equal(t, 1, result.Skipped)
+			assert.Equal(t, []uint{duplicate.ID}, result.DuplicateCardIDs)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, "苹果", updated.Answer)
+		case "update":
+			assert.Equal(t, 1, result.Updated)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, "苹果（水果）", updated.Answer)
+		}
+	}
+
+	db := setupTestDB()
+	router := setupRouter(db)
+	parent := models.Deck{Name: "上级"}
+	db.Create(&parent)
+	child := models.Deck{Name: "子卡包", ParentID: &parent.ID}
+	db.Create(&child)
+
+	for _, body := range []map[string]interface{}{
+		{"target_id": parent.ID},
+		{"target_id": child.ID},
+		{"target_id": child.ID, "on_duplicate": "replace"},
+		{},
+	} {
+		w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", parent.ID), body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", parent.ID), map[string]interface{}{"target_id": 999}, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"flashcard/internal/models"
+	"flashcard/internal/services"
 )
 
 // TestListDecks 测试获取卡包列表
@@ -174,11 +175,11 @@ func TestGetDeckStats(t *testing.T) {
 	db.Create(&deck)
 
 	// 创建标签
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	// 创建卡片
-	card1 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "问题1", Answer: "答案1"}
+	card1 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题1", Answer: "答案1"}
 	card2 := models.Card{DeckID: deck.ID, Question: "问题2", Answer: "答案2"}
 	db.Create(&card1)
 	db.Create(&card2)
@@ -206,4 +207,58 @@ func TestGetDeckStats(t *testing.T) {
 	assert.True(t, ok, "Stats should be a map")
 	assert.Equal(t, float64(2), stats["total_cards"])
 	assert.Equal(t, float64(1), stats["tag_count"])
-}
\ No newline at end of file
+}
+
+// TestListDecksWithStats 测试带统计信息的卡包列表与单个卡包统计一致
+func TestListDecksWithStats(t *testing.T) {
+	db := setupTestDB()
+	seedStatsData(db, 3, 2, 5)
+
+	deckService := services.NewDeckService()
+	decks, err := deckService.GetAllDecksWithStats()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(decks))
+
+	for _, deck := range decks {
+		stats, err := deckService.GetDeckStats(deck.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, *stats, deck.Stats)
+		assert.Equal(t, 10, deck.Stats.TotalCards)
+		assert.Equal(t, 4, deck.Stats.DueCards)
+		assert.Equal(t, 2, deck.Stats.TagCount)
+	}
+}
+
+// BenchmarkGetAllDecksWithStats 分组聚合查询获取卡包统计
+func BenchmarkGetAllDecksWithStats(b *testing.B) {
+	db := setupTestDB()
+	seedStatsData(db, 100, 10, 5)
+	deckService := services.NewDeckService()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := deckService.GetAllDecksWithStats(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetDeckStatsPerDeck 逐个卡包查询统计（对照组）
+func BenchmarkGetDeckStatsPerDeck(b *testing.B) {
+	db := setupTestDB()
+	seedStatsData(db, 100, 10, 5)
+	deckService := services.NewDeckService()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decks, err := deckService.GetAllDecks()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, deck := range decks {
+			if _, err := deckService.GetDeckStats(deck.ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// createChildDeck 通过接口创建卡包，parentID为0时创建顶层卡包
+func createChildDeck(t *testing.T, router http.Handler, parentID uint, name string) models.Deck {
+	body := map[string]interface{}{"name": name}
+	if parentID != 0 {
+		body["parent_id"] = parentID
+	}
+	var deck models.Deck
+	w := sendJSON(router, "POST", "/api/v1/decks", body, &deck)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return deck
+}
+
+// TestDeckTree 测试创建子卡包、获取卡包树和递归统计（包括记忆分析）
+func TestDeckTree(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	language := createChildDeck(t, router, 0, "语言")
+	english := createChildDeck(t, router, language.ID, "英语")
+	grammar := createChildDeck(t, router, english.ID, "英语语法")
+	createChildDeck(t, router, 0, "数学")
+	assert.Equal(t, english.ID, *grammar.ParentID)
+
+	// 上级卡包不存在
+	w := sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "孤立", "parent_id": 9999}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for i, deckID := range []uint{language.ID, english.ID, grammar.ID, grammar.ID} {
+		db.Create(&models.Card{DeckID: deckID, Question: fmt.Sprintf("问题%d", i), Answer: "答案"})
+	}
+	db.Create(&models.Tag{DeckID: &grammar.ID, Name: "时态", Path: "时态"})
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/decks/%d/stats", english.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		Data struct {
+			Stats models.DeckStats `json:"stats"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, 3, stats.Data.Stats.TotalCards)
+	assert.Equal(t, 1, stats.Data.Stats.TagCount)
+
+	var analytics models.AnalyticsStats
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/decks/%d/analytics", english.ID), nil, &analytics)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, analytics.CardStates.New)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/decks/tree", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tree struct {
+		Data struct {
+			Decks []models.DeckTreeNode `json:"decks"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tree)
+	if assert.Equal(t, 2, len(tree.Data.Decks)) {
+		root := tree.Data.Decks[0]
+		assert.Equal(t, "语言", root.Name)
+		assert.Equal(t, 4, root.Stats.TotalCards)
+		if assert.Equal(t, 1, len(root.Children)) {
+			assert.Equal(t, 3, root.Children[0].Stats.TotalCards)
+			assert.Equal(t, "英语语法", root.Children[0].Children[0].Name)
+		}
+		assert.Empty(t, tree.Data.Decks[1].Children)
+	}
+
+	// 不能移动到自身的子卡包下
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/move", language.ID), map[string]interface{}{"parent_id": grammar.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var moved models.Deck
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/move", grammar.ID), map[string]interface{}{"parent_id": nil}, &moved)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, moved.ParentID)
+}
+
+// TestStudyDeckTree 测试学习上级卡包时抽取子卡包的卡片，每一级卡包的学习上限都生效
+func TestStudyDeckTree(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	parent := createChildDeck(t, router, 0, "上级卡包")
+	child := createChildDeck(t, router, parent.ID, "子卡包")
+	for i := 0; i < 3; i++ {
+		db.Create(&models.Card{DeckID: parent.ID, Question: fmt.Sprintf("上级%d", i), Answer: "答案"})
+		db.Create(&models.Card{DeckID: child.ID, Question: fmt.Sprintf("子%d", i), Answer: "答案"})
+	}
+
+	startStudy := func() models.StudySession {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/study/deck/%d", parent.ID), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var session struct {
+			Data models.StudySession `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &session)
+		return session.Data
+	}
+
+	assert.Equal(t, 6, startStudy().Total)
+
+	// 子卡包每次最多抽取1张
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", child.ID), map[string]interface{}{"study_limit": 1}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	session := startStudy()
+	assert.Equal(t, 4, session.Total)
+	childCards := 0
+	for _, item := range session.Queue {
+		var card models.Card
+		db.First(&card, item.CardID)
+		if card.DeckID == child.ID {
+			childCards++
+		}
+	}
+	assert.Equal(t, 1, childCards)
+
+	// 上级卡包的上限包括子卡包的卡片
+	sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", parent.ID), map[string]interface{}{"study_limit": 2}, nil)
+	assert.Equal(t, 2, startStudy().Total)
+
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", parent.ID), map[string]interface{}{"study_limit": -1}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestDeleteDeckWithChildren 测试删除包含子卡包的卡包时选择删除或移动子卡包，以及从回收站恢复
+func TestDeleteDeckWithChildren(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deleteDeck := func(deckID uint, children string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/decks/%d?children=%s", deckID, children), nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	root := createChildDeck(t, router, 0, "根卡包")
+	parent := createChildDeck(t, router, root.ID, "上级卡包")
+	child := createChildDeck(t, router, parent.ID, "子卡包")
+	grandchild := createChildDeck(t, router, child.ID, "孙卡包")
+	db.Create(&models.Card{DeckID: grandchild.ID, Question: "孙卡片", Answer: "答案"})
+
+	// 未指定子卡包的处理方式
+	assert.Equal(t, http.StatusConflict, deleteDeck(parent.ID, ""))
+	assert.Equal(t, http.StatusBadRequest, deleteDeck(parent.ID, "keep"))
+
+	// 子卡包移动到被删除卡包的上级
+	assert.Equal(t, http.StatusOK, deleteDeck(parent.ID, models.DeckChildrenReparent))
+	var deck models.Deck
+	db.First(&deck, child.ID)
+	assert.Equal(t, root.ID, *deck.ParentID)
+
+	// 子孙卡包和卡片一起删除，回收站只列出被删除的卡包
+	assert.Equal(t, http.StatusOK, deleteDeck(child.ID, models.DeckChildrenDelete))
+	var count int64
+	db.Model(&models.Deck{}).Where("id = ?", grandchild.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.Card{}).Where("deck_id = ?", grandchild.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/trash?type=deck", nil)
+	router.ServeHTTP(w, req)
+	var trash struct {
+		Data []models.TrashItem `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &trash)
+	var names []string
+	for _, item := range trash.Data {
+		names = append(names, item.Name)
+	}
+	assert.ElementsMatch(t, []string{"上级卡包", "子卡包"}, names)
+
+	// 恢复时一起恢复子孙卡包和卡片
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", child.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Card{}).Where("deck_id = ?", grandchild.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	var restored models.Deck
+	db.First(&restored, grandchild.ID)
+	assert.Equal(t, child.ID, *restored.ParentID)
+}
+
+// TestDeckNamesScopedByParent 测试卡包名称只在同一上级卡包下唯一：创建、改名、移动、复制、合并、删除时移动子卡包和从回收站恢复
+func TestDeckNamesScopedByParent(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	english := createChildDeck(t, router, 0, "英语")
+	japanese := createChildDeck(t, router, 0, "日语")
+	englishWords := createChildDeck(t, router, english.ID, "单词")
+	japaneseWords := createChildDeck(t, router, japanese.ID, "单词")
+	assert.NotEqual(t, englishWords.ID, japaneseWords.ID)
+
+	// 同一上级卡包下的同名卡包被拒绝
+	w := sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "单词", "parent_id": english.ID}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	grammar := createChildDeck(t, router, english.ID, "语法")
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", grammar.ID), map[string]interface{}{"name": "单词"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/move", japaneseWords.ID), map[string]interface{}{"parent_id": english.ID}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/move", japaneseWords.ID), map[string]interface{}{"parent_id": japanese.ID}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 复制的卡包名称按上级卡包判断是否已存在
+	var clone models.DeckCloneResult
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", englishWords.ID), map[string]interface{}{}, &clone)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if assert.NotNil(t, clone.Deck) {
+		assert.Equal(t, "单词 (副本)", clone.Deck.Name)
+		assert.Equal(t, english.ID, *clone.Deck.ParentID)
+	}
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", japaneseWords.ID), map[string]interface{}{"name": "语法"}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// 合并和删除时移动到的上级卡包下已有同名卡包，操作被拒绝
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", japanese.ID), map[string]interface{}{"target_id": english.ID}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d?children=reparent", japanese.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var moved models.Deck
+	db.First(&moved, japaneseWords.ID)
+	assert.Nil(t, moved.ParentID)
+
+	// 子卡包可以与被删除的上级卡包同名，恢复时与同一上级卡包下的卡包同名则不能恢复
+	verbs := createChildDeck(t, router, grammar.ID, "语法")
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d?children=reparent", grammar.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var reparented models.Deck
+	db.First(&reparented, verbs.ID)
+	assert.Equal(t, english.ID, *reparented.ParentID)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", grammar.ID), nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", japanese.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestUpdateDetails 测试通过PATCH接口修改卡包和标签的描述、颜色、图标和元数据
+func TestUpdateDetails(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语", StudyLimit: 20}
+	db.Create(&deck)
+	tag := models.Tag{DeckID: &deck.ID, Name: "动词", Path: "动词"}
+	db.Create(&tag)
+
+	var updated models.Deck
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{
+		"description": "JLPT N5 词汇", "color": "#4A90E2", "icon": "🇯🇵",
+		"metadata": map[string]string{"author": "小林", "source": "JLPT", "languages": "ja-zh"},
+	}, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "JLPT N5 词汇", updated.Description)
+	assert.Equal(t, "#4A90E2", updated.Color)
+	assert.Equal(t, "ja-zh", updated.Metadata["languages"])
+	// 未提供的字段保持不变
+	assert.Equal(t, "日语", updated.Name)
+	assert.Equal(t, 20, updated.StudyLimit)
+
+	// 只修改描述，空的元数据清除元数据
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{
+		"description": "", "metadata": map[string]string{},
+	}, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var saved models.Deck
+	db.First(&saved, deck.ID)
+	assert.Equal(t, "", saved.Description)
+	assert.Equal(t, "#4A90E2", saved.Color)
+	assert.Equal(t, "🇯🇵", saved.Icon)
+	assert.Empty(t, saved.Metadata)
+
+	// 只修改标签的描述等信息时不改变名称和卡包
+	var updatedTag models.Tag
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/tags/%d", tag.ID), map[string]interface{}{
+		"description": "五段动词和一段动词", "color": "#e94e77", "metadata": map[string]string{"source": "教材"},
+	}, &updatedTag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "动词", updatedTag.Name)
+	if assert.NotNil(t, updatedTag.DeckID) {
+		assert.Equal(t, deck.ID, *updatedTag.DeckID)
+	}
+	assert.Equal(t, "五段动词和一段动词", updatedTag.Description)
+	assert.Equal(t, "教材", updatedTag.Metadata["source"])
+
+	for _, c := range []struct {
+		url  string
+		body map[string]interface{}
+	}{
+		{fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{"color": "blue"}},
+		{fmt.Sprintf("/api/v1/decks/%d", deck.ID), map[string]interface{}{"metadata": map[string]string{"": "空键"}}},
+		{fmt.Sprintf("/api/v1/tags/%d", tag.ID), map[string]interface{}{"color": "#12345"}},
+		{fmt.Sprintf("/api/v1/tags/%d", tag.ID), map[string]interface{}{}},
+	} {
+		w = sendJSON(router, "PATCH", c.url, c.body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, c.body)
+	}
+}
+
+// TestDetailsRoundTrip 测试描述、颜色、图标和元数据通过JSON导出导入和完整备份恢复保留
+func TestDetailsRoundTrip(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	details := models.Details{
+		Description: "常用动词", Color: "#50E3C2", Icon: "book",
+		Metadata: map[string]string{"author": "小林", "languages": "ja-zh"},
+	}
+	deck := models.Deck{Name: "日语", Details: details}
+	db.Create(&deck)
+	tag := models.Tag{DeckID: &deck.ID, Name: "动词", Path: "动词", Details: models.Details{Description: "动词", Icon: "🏃"}}
+	db.Create(&tag)
+	card := models.Card{DeckID: deck.ID, Question: "食べる", Answer: "吃", Tags: []models.Tag{tag}}
+	db.Create(&card)
+
+	content := exportDeckFile(t, router, deck.ID, "json")
+	assert.Contains(t, string(content), `"languages": "ja-zh"`)
+	deckID := importDeckFile(t, router, "deck.json", "日语 导入", content)
+
+	var imported models.Deck
+	if assert.NoError(t, db.First(&imported, deckID).Error) {
+		assert.Equal(t, details, imported.Details)
+	}
+	var importedTag models.Tag
+	if assert.NoError(t, db.Where("deck_id = ? AND path = ?", deckID, "动词").First(&importedTag).Error) {
+		assert.Equal(t, "动词", importedTag.Description)
+		assert.Equal(t, "🏃", importedTag.Icon)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/system/backup", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "backup.zip")
+	part.Write(w.Body.Bytes())
+	writer.Close()
+
+	// 恢复前修改，恢复后应回到备份时的内容
+	db.Model(&deck).Updates(models.Deck{Details: models.Details{Description: "已修改"}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/system/restore", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var restored models.Deck
+	if assert.NoError(t, db.First(&restored, deck.ID).Error) {
+		assert.Equal(t, details, restored.Details)
+	}
+	var restoredTag models.Tag
+	if assert.NoError(t, db.First(&restoredTag, tag.ID).Error) {
+		assert.Equal(t, "🏃", restoredTag.Icon)
+	}
+}
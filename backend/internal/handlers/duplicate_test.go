@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// importIntoDeck 通过接口将文件导入到已有卡包，返回响应和解析出的导入结果
+func importIntoDeck(router http.Handler, deckID uint, filename, onDuplicate string, content []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.WriteField("deck_id", fmt.Sprint(deckID))
+	writer.WriteField("on_duplicate", onDuplicate)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/import-export/decks", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response.Data
+}
+
+// TestCreateDuplicateCard 测试创建卡片时提示或拒绝重复的问题
+func TestCreateDuplicateCard(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	other := models.Deck{Name: "其他卡包"}
+	db.Create(&deck)
+	db.Create(&other)
+	existing := models.Card{DeckID: deck.ID, Question: "What is  Go?", Answer: "答案"}
+	db.Create(&existing)
+
+	createCard := func(deckID uint, question, onDuplicate string) (*httptest.ResponseRecorder, models.Response) {
+		jsonData, _ := json.Marshal(map[string]interface{}{"deck_id": deckID, "question": question, "answer": "新答案"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/cards?on_duplicate="+onDuplicate, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var response models.Response
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	w, response := createCard(deck.ID, "What is Rust?", models.DuplicateReject)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Nil(t, response.Details)
+
+	// 忽略大小写、多余空白和全角字符
+	w, response = createCard(deck.ID, "ｗｈａｔ is go？", models.DuplicateReject)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, map[string]interface{}{"duplicate_card_ids": []interface{}{float64(existing.ID)}}, response.Details)
+
+	// 默认照常创建并提示
+	w, response = createCard(deck.ID, "WHAT IS GO?", models.DuplicateWarn)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotNil(t, response.Details)
+
+	// 选择题卡片同样查重
+	w = sendJSON(router, "POST", "/api/v1/cards?on_duplicate=reject", map[string]interface{}{
+		"deck_id": deck.ID, "type": models.CardTypeChoice, "question": "what is go?",
+		"options": []map[string]interface{}{{"content": "语言", "correct": true}, {"content": "游戏"}},
+	}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 其他卡包中的相同问题不算重复
+	w, response = createCard(other.ID, "What is Go?", models.DuplicateReject)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w, _ = createCard(deck.ID, "问题", "ignore")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 重复报告按问题分组
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/decks/%d/duplicates", deck.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report struct {
+		Data struct {
+			Duplicates []models.DuplicateGroup `json:"duplicates"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if assert.Equal(t, 1, len(report.Data.Duplicates)) {
+		assert.Equal(t, "what is go?", report.Data.Duplicates[0].Question)
+		assert.Equal(t, 2, len(report.Data.Duplicates[0].Cards))
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/decks/9999/duplicates", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestImportDuplicateCards 测试导入到已有卡包时跳过、更新或保留重复的卡片
+func TestImportDuplicateCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	existing := models.Card{DeckID: deck.ID, Question: "问题1", Answer: "旧答案"}
+	db.Create(&existing)
+
+	content := []byte("ID,Question,Answer,Tag\n1,问题1,新答案,标签\n2,问题2,答案2,\n3,问题2,答案3,\n")
+	countCards := func() int64 {
+		var count int64
+		db.Model(&models.Card{}).Where("deck_id = ?", deck.ID).Count(&count)
+		return count
+	}
+
+	// 文件内部的重复同样跳过
+	w, result := importIntoDeck(router, deck.ID, "deck.csv", models.DuplicateSkip, content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(1), result["created"])
+	assert.Equal(t, float64(2), result["skipped"])
+	assert.Equal(t, int64(2), countCards())
+
+	w, result = importIntoDeck(router, deck.ID, "deck.csv", models.DuplicateUpdate, content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(3), result["updated"])
+	assert.Equal(t, int64(2), countCards())
+	var card models.Card
+	db.Preload("Tags").First(&card, existing.ID)
+	assert.Equal(t, "新答案", card.Answer)
+	if assert.Equal(t, 1, len(card.Tags)) {
+		assert.Equal(t, "标签", card.Tags[0].Name)
+	}
+
+	w, result = importIntoDeck(router, deck.ID, "deck.csv", models.DuplicateKeep, content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(3), result["created"])
+	assert.Equal(t, int64(5), countCards())
+
+	w, _ = importIntoDeck(router, 9999, "deck.csv", models.DuplicateKeep, content)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = importIntoDeck(router, deck.ID, "deck.csv", "merge", content)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestImportDuplicateNotes 测试导入和合并时跳过或更新内容重复的笔记，更新后保留原有卡片
+func TestImportDuplicateNotes(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	note := createClozeNote(t, router, deck.ID, "{{c1::Tokyo}} is the capital of Japan", "旧附加")
+	var cardIDs []uint
+	db.Model(&models.Card{}).Where("note_id = ?", note.ID).Pluck("id", &cardIDs)
+
+	content := []byte("ID,Question,Answer,Tag,Type\n1,{{c1::tokyo}}  is the capital of japan,新附加,地理,cloze\n")
+	w, result := importIntoDeck(router, deck.ID, "deck.csv", models.DuplicateSkip, content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(1), result["skipped"])
+
+	w, result = importIntoDeck(router, deck.ID, "deck.csv", models.DuplicateUpdate, content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(1), result["updated"])
+	assert.Equal(t, float64(0), result["skipped"])
+
+	var updated models.Note
+	db.Preload("Tags").Preload("Cards").First(&updated, note.ID)
+	assert.Equal(t, "{{c1::tokyo}}  is the capital of japan", updated.Content)
+	assert.Equal(t, "新附加", updated.Extra)
+	if assert.Equal(t, 1, len(updated.Tags)) {
+		assert.Equal(t, "地理", updated.Tags[0].Name)
+	}
+	if assert.Equal(t, 1, len(updated.Cards)) {
+		assert.Equal(t, cardIDs[0], updated.Cards[0].ID)
+		assert.Contains(t, updated.Cards[0].Question, "is the capital of japan")
+	}
+
+	// 合并卡包时同样用源卡包的笔记更新目标卡包中的笔记
+	source := models.Deck{Name: "源"}
+	db.Create(&source)
+	createClozeNote(t, router, source.ID, "{{c1::TOKYO}} is the capital of Japan", "合并附加")
+	var merged models.DeckMergeResult
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", source.ID), map[string]interface{}{
+		"target_id": deck.ID, "on_duplicate": models.DuplicateUpdate,
+	}, &merged)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, merged.Updated)
+	assert.Equal(t, 0, merged.MovedNotes)
+	db.First(&updated, note.ID)
+	assert.Equal(t, "合并附加", updated.Extra)
+}
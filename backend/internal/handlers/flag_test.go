@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestCardFlags 测试通过卡片接口、批量操作和学习流程设置标记，并按标记搜索和学习
+func TestCardFlags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card1 := models.Card{DeckID: deck.ID, Question: "问题1", Answer: "答案"}
+	card2 := models.Card{DeckID: deck.ID, Question: "问题2", Answer: "答案"}
+	card3 := models.Card{DeckID: deck.ID, Question: "问题3", Answer: "答案"}
+	db.Create(&card1)
+	db.Create(&card2)
+	db.Create(&card3)
+	note := models.Note{DeckID: deck.ID, Type: models.CardTypeCloze, Content: "{{c1::笔记}}"}
+	db.Create(&note)
+	noteCard := models.Card{DeckID: deck.ID, NoteID: &note.ID, Type: models.CardTypeCloze, Ord: 1, Question: note.Content}
+	db.Create(&noteCard)
+
+	// 由笔记生成的卡片同样可以标记，且不记录修订
+	var flagged models.CardResponse
+	w := sendJSON(router, "PUT", fmt.Sprintf("/api/v1/cards/%d/flag", noteCard.ID), map[string]int{"flag": models.FlagRed}, &flagged)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.FlagRed, flagged.Flag)
+	var count int64
+	db.Model(&models.CardRevision{}).Where("card_id = ?", noteCard.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = sendJSON(router, "PUT", fmt.Sprintf("/api/v1/cards/%d/flag", card1.ID), map[string]int{"flag": 8}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, "PUT", "/api/v1/cards/9999/flag", map[string]int{"flag": models.FlagRed}, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 批量标记
+	w, result := bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionFlag, "card_ids": []uint{card1.ID, card2.ID}, "flag": models.FlagBlue})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Succeeded)
+	w, _ = bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionFlag, "card_ids": []uint{card1.ID}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 提交复习结果时修改标记
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/review/%d", card2.ID), map[string]interface{}{"result": models.Good, "flag": models.FlagNone}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/study/review/%d", card2.ID), map[string]interface{}{"result": models.Good, "flag": -1}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	search := func(query string) []uint {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/cards?page=1&page_size=20&"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data models.CardListResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		ids := []uint{}
+		for _, card := range response.Data.Cards {
+			ids = append(ids, card.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []uint{card1.ID}, search(fmt.Sprintf("flag=%d", models.FlagBlue)))
+	assert.ElementsMatch(t, []uint{card1.ID, noteCard.ID}, search("flagged=true"))
+	assert.ElementsMatch(t, []uint{card2.ID, card3.ID}, search("flagged=false"))
+
+	// 按标记学习
+	var session models.StudySession
+	w = sendJSON(router, "POST", "/api/v1/study/filtered", map[string]interface{}{"deck_id": deck.ID, "flag": models.FlagRed}, &session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(session.Queue)) {
+		assert.Equal(t, noteCard.ID, session.Queue[0].CardID)
+		assert.Equal(t, models.FlagRed, session.Queue[0].Flag)
+	}
+	w = sendJSON(router, "POST", "/api/v1/study/filtered", map[string]interface{}{"flagged": true, "due_only": true}, &session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(session.Queue))
+}
+
+// TestExportImportFlags 测试JSON导出和导入保留卡片标记
+func TestExportImportFlags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "标记卡包"}
+	db.Create(&deck)
+	db.Create(&models.Card{DeckID: deck.ID, Question: "问题1", Answer: "答案", Flag: models.FlagGreen})
+	db.Create(&models.Card{DeckID: deck.ID, Question: "问题2", Answer: "答案"})
+
+	content := exportDeckFile(t, router, deck.ID, "json")
+	assert.Contains(t, string(content), `"flag": 3`)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "deck.json")
+	part.Write(content)
+	writer.WriteField("deck_name", "导入的卡包")
+	writer.Close()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/import-export/decks", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var card models.Card
+	db.Joins("JOIN decks ON decks.id = cards.deck_id").Where("decks.name = ? AND cards.question = ?", "导入的卡包", "问题1").First(&card)
+	assert.Equal(t, models.FlagGreen, card.Flag)
+}
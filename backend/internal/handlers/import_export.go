@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"fmt"
@@ -11,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ImportExportHandler 导入导出处理器
@@ -37,8 +40,15 @@ func (h *ImportExportHandler) ExportDeck(c *gin.Context) {
 	// 获取导出格式
 	format := c.DefaultQuery("format", "json")
 
+	// 获取导出内容类型：源文本或渲染后的HTML
+	content := c.DefaultQuery("content", models.ExportContentSource)
+	if content != models.ExportContentSource && content != models.ExportContentRendered {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "导出内容类型必须为source或rendered"))
+		return
+	}
+
 	// 导出卡包
-	filename, err := h.importExportService.ExportDeck(uint(deckID), format)
+	filename, err := h.importExportService.ExportDeckWithContent(uint(deckID), format, content)
 	if err != nil {
 		// 检查是否是记录不存在的错误
 		if err.Error() == "record not found" {
@@ -121,17 +131,74 @@ func (h *ImportExportHandler) ImportDeck(c *gin.Context) {
 		}
 	}
 
+	opts := models.ImportOptions{
+		DeckName:    deckName,
+		OnDuplicate: c.DefaultPostForm("on_duplicate", models.DuplicateKeep),
+	}
+	switch opts.OnDuplicate {
+	case models.DuplicateKeep, models.DuplicateSkip, models.DuplicateUpdate:
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "on_duplicate参数只能是keep、skip或update"))
+		return
+	}
+
+	// 指定卡包ID时导入到已有卡包
+	if deckIDStr := c.PostForm("deck_id"); deckIDStr != "" {
+		deckID, err := strconv.ParseUint(deckIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡包ID"))
+			return
+		}
+		id := uint(deckID)
+		opts.DeckID = &id
+	}
+
+	// 指定笔记类型时CSV的列按字段映射导入为模板笔记
+	if noteTypeIDStr := c.PostForm("note_type_id"); noteTypeIDStr != "" {
+		noteTypeID, err := strconv.ParseUint(noteTypeIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记类型ID"))
+			return
+		}
+		if strings.ToLower(filepath.Ext(file.Filename)) != ".csv" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "按笔记类型导入只支持CSV文件"))
+			return
+		}
+		id := uint(noteTypeID)
+		opts.NoteTypeID = &id
+
+		if fieldMap := c.PostForm("field_map"); fieldMap != "" {
+			if err := json.Unmarshal([]byte(fieldMap), &opts.FieldMap); err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "field_map必须是列名到字段名的JSON对象"))
+				return
+			}
+		}
+	}
+
 	// 导入卡包
-	deck, err := h.importExportService.ImportDeckWithName(tempFile.Name(), deckName)
+	result, err := h.importExportService.ImportDeckWithOptions(tempFile.Name(), opts)
 	if err != nil {
 		// 检查是否是不支持的格式错误
 		if strings.Contains(err.Error(), "不支持的导入格式") {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "导入失败", "不支持的文件格式"))
 			return
 		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡包不存在"))
+			return
+		}
+		if errors.Is(err, services.ErrNoteTypeNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, err.Error()))
+			return
+		}
+		if errors.Is(err, services.ErrNoteTypeColumns) || errors.Is(err, services.ErrUnknownNoteField) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "导入失败", err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "导入卡包失败", err.Error()))
 		return
 	}
+	deck := result.Deck
 
 	// 获取导入的卡片数量
 	var cardCount int64
@@ -141,6 +208,9 @@ func (h *ImportExportHandler) ImportDeck(c *gin.Context) {
 		"deck_id":     deck.ID,
 		"deck_name":   deck.Name,
 		"card_count":  cardCount,
+		"created":     result.Created,
+		"updated":     result.Updated,
+		"skipped":     result.Skipped,
 		"import_time": deck.CreatedAt,
 	})
 	response.Message = fmt.Sprintf("成功导入卡包\"%s\"，包含 %d 张卡片", deck.Name, cardCount)
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+)
+
+const markdownQuestion = "# 标题\n\n```go\nfmt.Println(\"hi\")\n```\n\n| 名称 | 值 |\n| --- | --- |\n| a_1 | 1 |\n\n公式 $a_1 * b_2$ 和 \\(x_i\\)\n\n$$\\sum_{i=1}^n i$$\n\n<script>alert(1)</script>"
+
+// TestMarkdownCardRendering 测试Markdown卡片渲染为清洗后的HTML
+func TestMarkdownCardRendering(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	cardData := map[string]interface{}{
+		"deck_id":  deck.ID,
+		"question": markdownQuestion,
+		"answer":   "**答案**",
+		"format":   "markdown",
+	}
+	jsonData, _ := json.Marshal(cardData)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/cards", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Data models.Card `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, models.ContentFormatMarkdown, created.Data.Format)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d", created.Data.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.CardResponse `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	html := response.Data.QuestionHTML
+	assert.Contains(t, html, "<h1")
+	assert.Contains(t, html, `<code class="language-go">`)
+	assert.Contains(t, html, "<table>")
+	assert.Contains(t, html, "$a_1 * b_2$")
+	assert.Contains(t, html, `\(x_i\)`)
+	assert.Contains(t, html, `$$\sum_{i=1}^n i$$`)
+	assert.NotContains(t, html, "<script")
+	assert.Equal(t, "<p><strong>答案</strong></p>\n", response.Data.AnswerHTML)
+
+	// 学习队列同样返回渲染结果
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/study/deck/%d", deck.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var session struct {
+		Data models.StudySession `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &session)
+	assert.Equal(t, 1, len(session.Data.Queue))
+	assert.Equal(t, html, session.Data.Queue[0].QuestionHTML)
+}
+
+// TestPlainCardRendering 测试纯文本卡片只做转义
+func TestPlainCardRendering(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	card := models.Card{DeckID: deck.ID, Question: "a < b\n**不加粗**", Answer: "答案"}
+	db.Create(&card)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Data models.CardResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.ContentFormatPlain, response.Data.Format)
+	assert.Equal(t, "a &lt; b<br>\n**不加粗**", response.Data.QuestionHTML)
+}
+
+// TestExportRenderedContent 测试TXT/CSV导出可选择源文本或渲染后的HTML
+func TestExportRenderedContent(t *testing.T) {
+	db := setupTestDB()
+
+	deck := models.Deck{Name: "渲染导出卡包"}
+	db.Create(&deck)
+	db.Create(&models.Card{DeckID: deck.ID, Question: "**粗体**", Answer: "答案", Format: models.ContentFormatMarkdown})
+
+	importExportService := services.NewImportExportService()
+
+	filename, err := importExportService.ExportDeckWithContent(deck.ID, "csv", models.ExportContentRendered)
+	assert.NoError(t, err)
+	content, _ := os.ReadFile(filename)
+	os.Remove(filename)
+	assert.Contains(t, string(content), "<strong>粗体</strong>")
+
+	filename, err = importExportService.ExportDeckWithContent(deck.ID, "txt", models.ExportContentSource)
+	assert.NoError(t, err)
+	content, _ = os.ReadFile(filename)
+	os.Remove(filename)
+	assert.Contains(t, string(content), "**粗体**")
+
+	// JSON始终导出源文本并保留内容格式
+	filename, err = importExportService.ExportDeckWithContent(deck.ID, "json", models.ExportContentRendered)
+	assert.NoError(t, err)
+	imported, err := importExportService.ImportDeckWithName(filename, "导入的渲染卡包")
+	os.Remove(filename)
+	assert.NoError(t, err)
+
+	var card models.Card
+	db.Where("deck_id = ?", imported.ID).First(&card)
+	assert.Equal(t, "**粗体**", card.Question)
+	assert.Equal(t, models.ContentFormatMarkdown, card.Format)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MediaHandler 媒体处理器
+type MediaHandler struct {
+	mediaService *services.MediaService
+}
+
+// NewMediaHandler 创建媒体处理器实例
+func NewMediaHandler() *MediaHandler {
+	return &MediaHandler{
+		mediaService: services.NewMediaService(),
+	}
+}
+
+// UploadMedia 上传媒体文件（图片或音频）
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请选择要上传的文件"))
+		return
+	}
+
+	if file.Size > services.MaxMediaSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrMediaTooLarge.Error()))
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "读取上传文件失败", err.Error()))
+		return
+	}
+	defer src.Close()
+
+	media, err := h.mediaService.SaveMedia(file.Filename, src)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedMedia) || errors.Is(err, services.ErrMediaTooLarge) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "保存媒体文件失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(media))
+}
+
+// GetMedia 获取媒体文件内容
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的媒体ID"))
+		return
+	}
+
+	media, err := h.mediaService.GetMediaByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "媒体不存在"))
+		return
+	}
+
+	// 内容寻址的文件不会变化，可以长期缓存
+	c.Header("Content-Type", media.MimeType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(services.MediaPath(media.Hash))
+}
+
+// SetCardMedia 设置卡片引用的媒体
+func (h *MediaHandler) SetCardMedia(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的卡片ID"))
+		return
+	}
+
+	var req struct {
+		MediaIDs []uint `json:"media_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	card, err := h.mediaService.SetCardMedia(uint(id), req.MediaIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡片或媒体不存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "设置卡片媒体失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(card))
+}
+
+// CollectGarbage 清理未被卡片引用的媒体文件
+func (h *MediaHandler) CollectGarbage(c *gin.Context) {
+	// 宽限期内上传的媒体可能尚未关联卡片，默认保留24小时
+	graceHours, err := strconv.Atoi(c.DefaultQuery("grace_hours", "24"))
+	if err != nil || graceHours < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "grace_hours必须是非负整数"))
+		return
+	}
+
+	result, err := h.mediaService.CollectGarbage(time.Duration(graceHours) * time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "清理媒体文件失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
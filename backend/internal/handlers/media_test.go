@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/config"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+)
+
+// useTempMediaDir 将媒体目录指向测试临时目录
+func useTempMediaDir(t *testing.T) {
+	original := config.AppConfig
+	config.AppConfig = &config.Config{MediaDir: t.TempDir()}
+	t.Cleanup(func() { config.AppConfig = original })
+}
+
+// pngBytes 生成指定宽度的PNG图片内容
+func pngBytes(width int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, 1)))
+	return buf.Bytes()
+}
+
+// uploadMedia 通过接口上传媒体文件
+func uploadMedia(router http.Handler, filename string, content []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/media", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeMedia 解析上传媒体的响应
+func decodeMedia(t *testing.T, w *httptest.ResponseRecorder) models.Media {
+	var response struct {
+		Data models.Media `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	return response.Data
+}
+
+// TestUploadMedia 测试上传媒体文件按内容去重并可下载
+func TestUploadMedia(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	content := pngBytes(2)
+	w := uploadMedia(router, "图片.png", content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	media := decodeMedia(t, w)
+	assert.Equal(t, "image/png", media.MimeType)
+	assert.Equal(t, int64(len(content)), media.Size)
+
+	// 相同内容返回已有记录
+	w = uploadMedia(router, "另一个名字.png", content)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, media.ID, decodeMedia(t, w).ID)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/media/%d", media.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, content, w.Body.Bytes())
+
+	// 非图片和音频文件被拒绝
+	w = uploadMedia(router, "脚本.html", []byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestCardMediaAndGarbageCollection 测试卡片引用媒体以及清理未引用的媒体
+func TestCardMediaAndGarbageCollection(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	used := decodeMedia(t, uploadMedia(router, "used.png", pngBytes(1)))
+	unused := decodeMedia(t, uploadMedia(router, "unused.png", pngBytes(3)))
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"media_ids": []uint{used.ID}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/cards/%d/media", card.ID), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil)
+	router.ServeHTTP(w, req)
+	var cardResponse struct {
+		Data models.CardResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &cardResponse)
+	assert.Equal(t, 1, len(cardResponse.Data.Media))
+	assert.Equal(t, used.ID, cardResponse.Data.Media[0].ID)
+
+	// 宽限期内不清理
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/media/gc", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.Media{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/media/gc?grace_hours=0", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result struct {
+		Data models.MediaGCResult `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 1, result.Data.DeletedMedia)
+	assert.Equal(t, 1, result.Data.DeletedFiles)
+
+	_, err := os.Stat(services.MediaPath(unused.Hash))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(services.MediaPath(used.Hash))
+	assert.NoError(t, err)
+}
+
+// TestTrashedCardMedia 测试回收站中的卡片引用的媒体不会被清理，彻底删除最后引用它的卡片时才删除
+func TestTrashedCardMedia(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	first := models.Card{DeckID: deck.ID, Question: "问题1", Answer: "答案"}
+	second := models.Card{DeckID: deck.ID, Question: "问题2", Answer: "答案"}
+	db.Create(&first)
+	db.Create(&second)
+
+	media := decodeMedia(t, uploadMedia(router, "shared.png", pngBytes(4)))
+	for _, card := range []models.Card{first, second} {
+		w := sendJSON(router, "PUT", fmt.Sprintf("/api/v1/cards/%d/media", card.ID), map[string]interface{}{"media_ids": []uint{media.ID}}, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var result models.MediaGCResult
+	w := sendJSON(router, "POST", "/api/v1/media/gc?grace_hours=0", nil, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, result.DeletedMedia)
+
+	// 恢复的卡片仍然引用媒体
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/card/%d/restore", first.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var card models.CardResponse
+	sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d", first.ID), nil, &card)
+	if assert.Equal(t, 1, len(card.Media)) {
+		assert.Equal(t, media.ID, card.Media[0].ID)
+	}
+
+	// 彻底删除一张卡片时媒体仍被另一张卡片引用
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/trash/card/%d", second.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.Media{}).Where("id = ?", media.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", first.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "DELETE", "/api/v1/trash", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Media{}).Where("id = ?", media.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	_, err := os.Stat(services.MediaPath(media.Hash))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// restoreBackup 通过接口上传备份文件恢复数据
+func restoreBackup(router http.Handler, filename string, backup []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(backup)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/system/restore", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// backupZip 生成包含backup.json和媒体文件的备份压缩包
+func backupZip(data string, media map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	entry, _ := zipWriter.Create("backup.json")
+	entry.Write([]byte(data))
+	for hash, content := range media {
+		entry, _ = zipWriter.Create("media/" + hash)
+		entry.Write(content)
+	}
+	zipWriter.Close()
+	return buf.Bytes()
+}
+
+// TestBackupRestoreWithMedia 测试压缩包备份包含媒体文件并能恢复
+func TestBackupRestoreWithMedia(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "备份卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	content := pngBytes(4)
+	media := decodeMedia(t, uploadMedia(router, "backup.png", content))
+	services.NewMediaService().SetCardMedia(card.ID, []uint{media.ID})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/system/backup", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	backup := w.Body.Bytes()
+
+	// 删除媒体文件后从备份恢复
+	os.Remove(services.MediaPath(media.Hash))
+
+	w = restoreBackup(router, "backup.zip", backup)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	restored, err := os.ReadFile(services.MediaPath(media.Hash))
+	assert.NoError(t, err)
+	assert.Equal(t, content, restored)
+
+	var restoredCard models.Card
+	db.Preload("Media").First(&restoredCard, card.ID)
+	assert.Equal(t, 1, len(restoredCard.Media))
+	assert.Equal(t, media.Hash, restoredCard.Media[0].Hash)
+}
+
+// TestMediaGarbageCollectionKeepsForeignFiles 测试清理媒体时只删除符合存储布局的文件
+func TestMediaGarbageCollectionKeepsForeignFiles(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	dir := config.AppConfig.MediaDir
+	orphan := services.MediaPath("ab" + strings.Repeat("0", 62))
+	foreign := []string{
+		filepath.Join(dir, "README.txt"),
+		filepath.Join(dir, "ab", "notes.txt"),
+		filepath.Join(dir, "cd", "ab"+strings.Repeat("0", 62)),
+		filepath.Join(dir, "backup", "ab", "ab"+strings.Repeat("0", 62)),
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, path := range append([]string{orphan}, foreign...) {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("内容"), 0644)
+		os.Chtimes(path, old, old)
+	}
+
+	var result models.MediaGCResult
+	w := sendJSON(router, "POST", "/api/v1/media/gc?grace_hours=0", nil, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, result.DeletedFiles)
+	_, err := os.Stat(orphan)
+	assert.True(t, os.IsNotExist(err))
+	for _, path := range foreign {
+		_, err := os.Stat(path)
+		assert.NoError(t, err, path)
+	}
+}
+
+// TestRestoreRejectedBackupWritesNoMedia 测试备份数据无效或媒体文件过大时不写入媒体文件
+func TestRestoreRejectedBackupWritesNoMedia(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	content := pngBytes(5)
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	// 缺少版本号的备份被拒绝，媒体文件不写入
+	w := restoreBackup(router, "backup.zip", backupZip(`{"media": [{"id": 1, "hash": "`+hash+`"}]}`, map[string][]byte{hash: content}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err := os.Stat(services.MediaPath(hash))
+	assert.True(t, os.IsNotExist(err))
+
+	// 超过大小上限的媒体文件被拒绝，已恢复的数据回滚
+	large := make([]byte, services.MaxMediaSize+1)
+	sum = sha256.Sum256(large)
+	largeHash := hex.EncodeToString(sum[:])
+	w = restoreBackup(router, "backup.zip", backupZip(
+		`{"version": "1.0", "decks": [{"id": 1, "name": "卡包"}], "media": [{"id": 1, "hash": "`+largeHash+`"}]}`,
+		map[string][]byte{largeHash: large},
+	))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err = os.Stat(services.MediaPath(largeHash))
+	assert.True(t, os.IsNotExist(err))
+	var count int64
+	db.Model(&models.Deck{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"flashcard/internal/metrics"
+	"flashcard/internal/middleware"
+	"flashcard/internal/models"
+)
+
+var registerMetricsOnce sync.Once
+
+// setupMetricsRouter 注册监控指标并设置带请求指标中间件和 /metrics 接口的测试路由
+func setupMetricsRouter(db *gorm.DB) *gin.Engine {
+	registerMetricsOnce.Do(func() {
+		if err := metrics.RegisterDBCallbacks(db); err != nil {
+			panic(err)
+		}
+		if err := metrics.RegisterCardGauges(db); err != nil {
+			panic(err)
+		}
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Metrics())
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/api/v1/decks/:id", NewDeckHandler().GetDeck)
+	return r
+}
+
+// TestMetricsEndpoint 测试请求后抓取 /metrics 包含请求、数据库操作和卡片数量指标
+func TestMetricsEndpoint(t *testing.T) {
+	db := setupTestDB()
+	router := setupMetricsRouter(db)
+
+	deck := models.Deck{Name: "监控"}
+	db.Create(&deck)
+	db.Create(&models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"})
+	db.Create(&models.Card{DeckID: deck.ID, Question: "暂停", Answer: "答案", Suspended: true})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/decks/%d", deck.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/decks/999", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	// 路由标签使用路由模板而不是实际路径
+	assert.Contains(t, body, `flashcard_http_requests_total{method="GET",route="/api/v1/decks/:id",status="200"}`)
+	assert.Contains(t, body, `flashcard_http_requests_total{method="GET",route="/api/v1/decks/:id",status="404"}`)
+	assert.Contains(t, body, `flashcard_http_request_duration_seconds_count{method="GET",route="/api/v1/decks/:id"}`)
+	assert.Contains(t, body, `flashcard_db_query_duration_seconds_count{operation="create",table="cards"}`)
+	assert.Contains(t, body, `flashcard_db_query_duration_seconds_count{operation="query",table="decks"}`)
+	assert.Contains(t, body, "\nflashcard_cards 2\n")
+	assert.Contains(t, body, "\nflashcard_cards_due 1\n")
+	assert.NotContains(t, body, "flashcard_cards_total")
+}
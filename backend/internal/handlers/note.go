@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NoteHandler 笔记处理器
+type NoteHandler struct {
+	noteService *services.NoteService
+}
+
+// NewNoteHandler 创建笔记处理器实例
+func NewNoteHandler() *NoteHandler {
+	return &NoteHandler{
+		noteService: services.NewNoteService(),
+	}
+}
+
+// CreateNote 创建笔记（生成对应的卡片）
+func (h *NoteHandler) CreateNote(c *gin.Context) {
+	var req struct {
+		DeckID     uint                  `json:"deck_id" binding:"required"`
+		TagID      *uint                 `json:"tag_id"`
+		TagIDs     []uint                `json:"tag_ids"`
+		Type       string                `json:"type"`
+		Content    string                `json:"content"` // 填空笔记的填空内容，图片遮挡笔记的提示文字
+		Extra      string                `json:"extra"`
+		MediaID    *uint                 `json:"media_id"`     // 图片遮挡笔记的图片
+		Masks      models.OcclusionMasks `json:"masks"`        // 图片遮挡区域
+		NoteTypeID *uint                 `json:"note_type_id"` // 模板笔记的笔记类型
+		Fields     map[string]string     `json:"fields"`       // 模板笔记的字段内容
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = models.CardTypeCloze
+		if req.NoteTypeID != nil {
+			req.Type = models.CardTypeTemplate
+		}
+	}
+
+	var note *models.Note
+	var err error
+	if req.Type == models.CardTypeTemplate {
+		if req.NoteTypeID == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrNoteTypeNotFound.Error()))
+			return
+		}
+		note, err = h.noteService.CreateTemplateNote(req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), *req.NoteTypeID, req.Fields)
+	} else if req.Type == models.CardTypeOcclusion {
+		if req.MediaID == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrOcclusionImage.Error()))
+			return
+		}
+		note, err = h.noteService.CreateOcclusionNote(req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), *req.MediaID, req.Masks, req.Content, req.Extra)
+	} else {
+		note, err = h.noteService.CreateNote(req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), req.Type, req.Content, req.Extra)
+	}
+	if err != nil {
+		if isNoteInputError(err) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "创建笔记失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(note))
+}
+
+// GetNote 获取单个笔记
+func (h *NoteHandler) GetNote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记ID"))
+		return
+	}
+
+	note, err := h.noteService.GetNoteByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "笔记不存在"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(note))
+}
+
+// UpdateNote 更新笔记
+func (h *NoteHandler) UpdateNote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记ID"))
+		return
+	}
+
+	var req struct {
+		DeckID  uint                  `json:"deck_id" binding:"required"`
+		TagID   *uint                 `json:"tag_id"`
+		TagIDs  []uint                `json:"tag_ids"`
+		Content string                `json:"content"`
+		Extra   string                `json:"extra"`
+		MediaID *uint                 `json:"media_id"`
+		Masks   models.OcclusionMasks `json:"masks"`
+		Fields  map[string]string     `json:"fields"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	// 提供了字段内容时按模板笔记更新，提供了遮挡区域时按图片遮挡笔记更新
+	var note *models.Note
+	if req.Fields != nil {
+		note, err = h.noteService.UpdateTemplateNote(uint(id), req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), req.Fields)
+	} else if req.Masks != nil {
+		if req.MediaID == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrOcclusionImage.Error()))
+			return
+		}
+		note, err = h.noteService.UpdateOcclusionNote(uint(id), req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), *req.MediaID, req.Masks, req.Content, req.Extra)
+	} else {
+		note, err = h.noteService.UpdateNote(uint(id), req.DeckID, mergeTagIDs(req.TagID, req.TagIDs), req.Content, req.Extra)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "笔记不存在"))
+			return
+		}
+		if isNoteInputError(err) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "更新笔记失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(note))
+}
+
+// DeleteNote 删除笔记及其卡片
+func (h *NoteHandler) DeleteNote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记ID"))
+		return
+	}
+
+	if err := h.noteService.DeleteNote(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "笔记不存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "删除笔记失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// isNoteInputError 判断是否为笔记内容不合法导致的错误
+func isNoteInputError(err error) bool {
+	return errors.Is(err, services.ErrNoCloze) ||
+		errors.Is(err, services.ErrUnsupportedNoteType) ||
+		errors.Is(err, services.ErrInvalidMasks) ||
+		errors.Is(err, services.ErrOcclusionImage) ||
+		errors.Is(err, services.ErrInvalidTags) ||
+		errors.Is(err, services.ErrInvalidNoteType) ||
+		errors.Is(err, services.ErrNoteTypeNotFound) ||
+		errors.Is(err, services.ErrUnknownNoteField) ||
+		errors.Is(err, services.ErrEmptyTemplateNote)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+)
+
+// createClozeNote 通过接口创建填空笔记
+func createClozeNote(t *testing.T, router http.Handler, deckID uint, content, extra string) models.Note {
+	var note models.Note
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deckID,
+		"type":    "cloze",
+		"content": content,
+		"extra":   extra,
+	}, &note)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return note
+}
+
+// TestCreateClozeNote 测试创建填空笔记按填空编号生成卡片
+func TestCreateClozeNote(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	note := createClozeNote(t, router, deck.ID, "{{c1::北京}}是{{c2::中国::国家}}的首都，{{c1::北京}}历史悠久", "")
+
+	assert.Equal(t, 2, len(note.Cards))
+	assert.Equal(t, 1, note.Cards[0].Ord)
+	assert.Equal(t, 2, note.Cards[1].Ord)
+	assert.Equal(t, models.CardTypeCloze, note.Cards[0].Type)
+
+	// 没有填空标记的内容应被拒绝
+	jsonData, _ := json.Marshal(map[string]interface{}{"deck_id": deck.ID, "content": "没有填空"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/notes", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestUpdateClozeNoteKeepsScheduling 测试修改填空笔记保留未变化编号的复习进度
+func TestUpdateClozeNoteKeepsScheduling(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	note := createClozeNote(t, router, deck.ID, "{{c1::甲}}和{{c2::乙}}", "")
+	firstCardID := note.Cards[0].ID
+	db.Create(&models.Review{CardID: firstCardID, EFactor: 2.5, Interval: 6, Repetitions: 2, NextReview: time.Now()})
+
+	updateData := map[string]interface{}{
+		"deck_id": deck.ID,
+		"content": "{{c1::甲}}和{{c3::丙}}",
+	}
+	jsonData, _ := json.Marshal(updateData)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/notes/%d", note.ID), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cards []models.Card
+	db.Where("note_id = ?", note.ID).Order("ord").Find(&cards)
+	assert.Equal(t, 2, len(cards))
+	assert.Equal(t, firstCardID, cards[0].ID)
+	assert.Equal(t, 3, cards[1].Ord)
+
+	var review models.Review
+	db.Where("card_id = ?", firstCardID).First(&review)
+	assert.Equal(t, 6, review.Interval)
+
+	// 由笔记生成的卡片不能直接修改
+	cardData := map[string]interface{}{"deck_id": deck.ID, "question": "问题", "answer": "答案"}
+	jsonData, _ = json.Marshal(cardData)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/api/v1/cards/%d", firstCardID), bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestStudyRendersCloze 测试学习队列中填空卡片隐藏当前填空
+func TestStudyRendersCloze(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	createClozeNote(t, router, deck.ID, "{{c1::北京}}是{{c2::中国::国家}}的首都", "华北")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/study/deck/%d", deck.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.StudySession `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(response.Data.Queue))
+
+	questions := []string{}
+	for _, item := range response.Data.Queue {
+		questions = append(questions, item.Question)
+		assert.Equal(t, "北京是中国的首都\n\n华北", item.Answer)
+	}
+	assert.Contains(t, questions, "[...]是中国的首都")
+	assert.Contains(t, questions, "北京是[国家]的首都")
+}
+
+// TestClozeImportExportRoundTrip 测试填空笔记在各导出格式中往返
+func TestClozeImportExportRoundTrip(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "填空卡包"}
+	db.Create(&deck)
+	createClozeNote(t, router, deck.ID, "{{c1::甲}}和{{c2::乙}}", "附加")
+
+	importExportService := services.NewImportExportService()
+	for _, format := range []string{"json", "csv", "txt"} {
+		filename, err := importExportService.ExportDeck(deck.ID, format)
+		assert.NoError(t, err)
+
+		imported, err := importExportService.ImportDeckWithName(filename, "导入的填空卡包_"+format)
+		os.Remove(filename)
+		assert.NoError(t, err, format)
+
+		var notes []models.Note
+		db.Where("deck_id = ?", imported.ID).Find(&notes)
+		assert.Equal(t, 1, len(notes), format)
+		assert.Equal(t, "{{c1::甲}}和{{c2::乙}}", notes[0].Content, format)
+		assert.Equal(t, "附加", notes[0].Extra, format)
+
+		var count int64
+		db.Model(&models.Card{}).Where("deck_id = ?", imported.ID).Count(&count)
+		assert.Equal(t, int64(2), count, format)
+	}
+}
+
+// TestTrashedClozeCard 测试删除的填空卡片在修改笔记时不会重新生成，恢复后不会出现编号相同的卡片，复制卡包时也不复制
+func TestTrashedClozeCard(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	note := createClozeNote(t, router, deck.ID, "{{c1::甲}}和{{c2::乙}}", "")
+	deletedID := note.Cards[1].ID
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", deletedID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/notes/%d", note.ID), map[string]interface{}{
+		"deck_id": deck.ID, "content": "{{c1::甲}}和{{c2::丙}}",
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var active []models.Card
+	db.Where("note_id = ?", note.ID).Find(&active)
+	assert.Equal(t, 1, len(active))
+
+	// 回收站中的卡片内容随笔记更新，恢复后每个编号只有一张卡片
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/card/%d/restore", deletedID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Where("note_id = ?", note.ID).Order("ord").Find(&active)
+	if assert.Equal(t, 2, len(active)) {
+		assert.Equal(t, []int{1, 2}, []int{active[0].Ord, active[1].Ord})
+		assert.Equal(t, deletedID, active[1].ID)
+		assert.Equal(t, "{{c1::甲}}和{{c2::丙}}", active[1].Question)
+	}
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", deletedID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result models.DeckCloneResult
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/clone", deck.ID), map[string]interface{}{}, &result)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, result.Cards)
+	var cloned []models.Card
+	db.Unscoped().Where("deck_id = ?", result.Deck.ID).Find(&cloned)
+	if assert.Equal(t, 1, len(cloned)) {
+		assert.Equal(t, 1, cloned[0].Ord)
+	}
+}
+
+// TestDeleteNote 测试删除笔记同时删除生成的卡片，笔记不存在时返回404
+func TestDeleteNote(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	note := createClozeNote(t, router, deck.ID, "{{c1::北京}}是{{c2::中国}}的首都", "")
+
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/notes/%d", note.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cardCount int64
+	db.Model(&models.Card{}).Where("note_id = ?", note.ID).Count(&cardCount)
+	assert.Equal(t, int64(0), cardCount)
+
+	// 已删除或不存在的笔记
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/notes/%d", note.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendJSON(router, "DELETE", "/api/v1/notes/9999", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
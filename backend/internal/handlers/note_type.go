@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NoteTypeHandler 笔记类型处理器
+type NoteTypeHandler struct {
+	noteTypeService *services.NoteTypeService
+}
+
+// NewNoteTypeHandler 创建笔记类型处理器实例
+func NewNoteTypeHandler() *NoteTypeHandler {
+	return &NoteTypeHandler{
+		noteTypeService: services.NewNoteTypeService(),
+	}
+}
+
+// CreateNoteType 创建笔记类型
+func (h *NoteTypeHandler) CreateNoteType(c *gin.Context) {
+	var req struct {
+		Name      string                `json:"name" binding:"required,min=1,max=100"`
+		Fields    models.NoteFieldNames `json:"fields" binding:"required"`
+		Templates models.CardTemplates  `json:"templates" binding:"required"`
+		Format    string                `json:"format"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	noteType, err := h.noteTypeService.CreateNoteType(req.Name, req.Fields, req.Templates, req.Format)
+	if err != nil {
+		respondNoteTypeError(c, err, "创建笔记类型失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(noteType))
+}
+
+// GetNoteTypes 获取所有笔记类型
+func (h *NoteTypeHandler) GetNoteTypes(c *gin.Context) {
+	noteTypes, err := h.noteTypeService.GetNoteTypes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取笔记类型列表失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]interface{}{
+		"note_types": noteTypes,
+	}))
+}
+
+// GetNoteType 获取单个笔记类型
+func (h *NoteTypeHandler) GetNoteType(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记类型ID"))
+		return
+	}
+
+	noteType, err := h.noteTypeService.GetNoteTypeByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "笔记类型不存在"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(noteType))
+}
+
+// UpdateNoteType 更新笔记类型，并重新生成该类型所有笔记的卡片
+func (h *NoteTypeHandler) UpdateNoteType(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记类型ID"))
+		return
+	}
+
+	var req struct {
+		Name      string                `json:"name" binding:"max=100"`
+		Fields    models.NoteFieldNames `json:"fields"`
+		Templates models.CardTemplates  `json:"templates"`
+		Format    string                `json:"format"`
+		Renames   map[string]string     `json:"field_renames"` // 旧字段名到新字段名，重命名的字段保留笔记内容
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	noteType, err := h.noteTypeService.UpdateNoteType(uint(id), req.Name, req.Fields, req.Templates, req.Format, req.Renames)
+	if err != nil {
+		respondNoteTypeError(c, err, "更新笔记类型失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(noteType))
+}
+
+// DeleteNoteType 删除笔记类型
+func (h *NoteTypeHandler) DeleteNoteType(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的笔记类型ID"))
+		return
+	}
+
+	if err := h.noteTypeService.DeleteNoteType(uint(id)); err != nil {
+		respondNoteTypeError(c, err, "删除笔记类型失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// respondNoteTypeError 根据笔记类型服务返回的错误类型返回对应的HTTP状态
+func respondNoteTypeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "笔记类型不存在"))
+	case errors.Is(err, services.ErrNoteTypeNameConflict), errors.Is(err, services.ErrNoteTypeInUse),
+		errors.Is(err, services.ErrNoteFieldInUse):
+		c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error()))
+	case isNoteInputError(err):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, message, err.Error()))
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// createVocabularyType 通过接口创建词汇笔记类型：认读和拼写两个模板，例句只在有内容时显示
+func createVocabularyType(t *testing.T, router http.Handler) models.NoteType {
+	var noteType models.NoteType
+	w := sendJSON(router, "POST", "/api/v1/note-types", map[string]interface{}{
+		"name":   "词汇",
+		"fields": []string{"Word", "Reading", "Meaning", "Example", "Audio"},
+		"templates": []map[string]string{
+			{"name": "认读", "front": "{{Word}}", "back": "{{FrontSide}}\n{{Reading}} {{Meaning}}{{#Example}}\n例句: {{Example}}{{/Example}}"},
+			{"name": "拼写", "front": "{{#Reading}}{{Meaning}}（{{Reading}}）{{/Reading}}", "back": "{{Word}}"},
+		},
+	}, &noteType)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return noteType
+}
+
+// TestNoteTypeTemplates 测试按笔记类型的模板生成卡片，修改模板后重新生成
+func TestNoteTypeTemplates(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	noteType := createVocabularyType(t, router)
+
+	var note models.Note
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id":      deck.ID,
+		"note_type_id": noteType.ID,
+		"fields":       map[string]string{"word": "猫", "Reading": "ねこ", "Meaning": "cat", "Example": "猫が好きです"},
+	}, &note)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "猫", note.Content)
+	if assert.Equal(t, 2, len(note.Cards)) {
+		assert.Equal(t, models.CardTypeTemplate, note.Cards[0].Type)
+		assert.Equal(t, "猫", note.Cards[0].Question)
+		assert.Equal(t, "猫\nねこ cat\n例句: 猫が好きです", note.Cards[0].Answer)
+		assert.Equal(t, "cat（ねこ）", note.Cards[1].Question)
+		assert.Equal(t, "猫", note.Cards[1].Answer)
+	}
+
+	// 条件字段为空时不生成对应的卡片，例句区块不显示
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/notes/%d", note.ID), map[string]interface{}{
+		"deck_id": deck.ID,
+		"fields":  map[string]string{"Word": "犬", "Meaning": "dog"},
+	}, &note)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(note.Cards)) {
+		assert.Equal(t, "犬\n dog", note.Cards[0].Answer)
+	}
+
+	// 未知字段和没有生成卡片的笔记被拒绝
+	w = sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "note_type_id": noteType.ID, "fields": map[string]string{"Kanji": "猫"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "note_type_id": noteType.ID, "fields": map[string]string{"Meaning": "cat"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 修改模板后重新生成该类型所有笔记的卡片，保留原有卡片
+	cardID := note.Cards[0].ID
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), map[string]interface{}{
+		"templates": []map[string]string{{"front": "{{Word}}?", "back": "{{Meaning}}"}},
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var card models.Card
+	db.First(&card, cardID)
+	assert.Equal(t, "犬?", card.Question)
+	assert.Equal(t, "dog", card.Answer)
+
+	// 仍被使用的笔记类型不能删除
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestNoteTypeFieldRenames 测试修改字段时按field_renames保留笔记内容，回收站中的笔记一并更新
+func TestNoteTypeFieldRenames(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	noteType := createVocabularyType(t, router)
+
+	var cat, dog models.Note
+	sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "note_type_id": noteType.ID, "fields": map[string]string{"Word": "猫", "Meaning": "cat"},
+	}, &cat)
+	sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "note_type_id": noteType.ID, "fields": map[string]string{"Word": "犬", "Meaning": "dog"},
+	}, &dog)
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/notes/%d", dog.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	update := map[string]interface{}{
+		"fields": []string{"Word", "Definition"},
+		"templates": []map[string]string{
+			{"front": "{{Word}}", "back": "{{Definition}}"},
+			{"front": "{{Definition}}", "back": "{{Word}}"},
+		},
+	}
+
+	// 没有指定重命名时删除仍有内容的字段被拒绝，笔记不变
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), update, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var note models.Note
+	db.First(&note, cat.ID)
+	assert.Equal(t, "cat", note.Fields["Meaning"])
+
+	// 重命名的来源或目标字段不存在时返回400
+	update["field_renames"] = map[string]string{"Kanji": "Definition"}
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), update, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	update["field_renames"] = map[string]string{"Meaning": "Definition"}
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), update, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Preload("Cards").First(&note, cat.ID)
+	assert.Equal(t, models.NoteFields{"Word": "猫", "Definition": "cat"}, note.Fields)
+	if assert.Equal(t, 2, len(note.Cards)) {
+		assert.Equal(t, "cat", note.Cards[1].Question)
+	}
+
+	// 回收站中的笔记同样更新，新生成的卡片留在回收站中，恢复时一并恢复
+	var trashedNote models.Note
+	db.Unscoped().First(&trashedNote, dog.ID)
+	assert.Equal(t, "dog", trashedNote.Fields["Definition"])
+	var trashed []models.Card
+	db.Unscoped().Where("note_id = ?", dog.ID).Order("ord ASC").Find(&trashed)
+	if assert.Equal(t, 2, len(trashed)) {
+		assert.True(t, trashed[1].DeletedAt.Valid)
+		assert.Equal(t, "dog", trashed[1].Question)
+	}
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/card/%d/restore", trashed[0].ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.Card{}).Where("note_id = ?", dog.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+// TestNoteTypeValidation 测试笔记类型的字段和模板校验
+func TestNoteTypeValidation(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+	createVocabularyType(t, router)
+
+	cases := []map[string]interface{}{
+		{"name": "重复字段", "fields": []string{"Word", "word"}, "templates": []map[string]string{{"front": "{{Word}}"}}},
+		{"name": "未知字段", "fields": []string{"Word"}, "templates": []map[string]string{{"front": "{{Meaning}}"}}},
+		{"name": "正面没有字段", "fields": []string{"Word"}, "templates": []map[string]string{{"front": "问题", "back": "{{Word}}"}}},
+		{"name": "没有模板", "fields": []string{"Word"}, "templates": []map[string]string{}},
+	}
+	for _, body := range cases {
+		w := sendJSON(router, "POST", "/api/v1/note-types", body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body["name"])
+	}
+
+	w := sendJSON(router, "POST", "/api/v1/note-types", map[string]interface{}{
+		"name": "词汇", "fields": []string{"Word"}, "templates": []map[string]string{{"front": "{{Word}}"}},
+	}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestImportCSVWithNoteType 测试按笔记类型导入CSV，列按字段名或字段映射对应到字段
+func TestImportCSVWithNoteType(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	noteType := createVocabularyType(t, router)
+
+	importCSV := func(content string, fieldMap string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "words.csv")
+		part.Write([]byte(content))
+		writer.WriteField("deck_id", fmt.Sprint(deck.ID))
+		writer.WriteField("note_type_id", fmt.Sprint(noteType.ID))
+		if fieldMap != "" {
+			writer.WriteField("field_map", fieldMap)
+		}
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/import-export/decks", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.Data
+	}
+
+	w, result := importCSV("单词,reading,Meaning,Tags\n猫,ねこ,cat,动物\n犬,,dog,\n,,,\n", `{"单词":"Word"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, float64(2), result["created"])
+	assert.Equal(t, float64(1), result["skipped"])
+
+	var notes []models.Note
+	db.Preload("Tags").Preload("Cards").Where("note_type_id = ?", noteType.ID).Order("id ASC").Find(&notes)
+	if assert.Equal(t, 2, len(notes)) {
+		assert.Equal(t, "ねこ", notes[0].Fields["Reading"])
+		assert.Equal(t, 2, len(notes[0].Cards))
+		if assert.Equal(t, 1, len(notes[0].Tags)) {
+			assert.Equal(t, "动物", notes[0].Tags[0].Name)
+		}
+		assert.Equal(t, 1, len(notes[1].Cards))
+	}
+
+	w, _ = importCSV("Front,Back\n猫,cat\n", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = importCSV("Word\n猫\n", `{"Word":"Kanji"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
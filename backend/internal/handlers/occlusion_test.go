@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestOcclusionNote 测试图片遮挡笔记按区域生成卡片并在学习时返回遮挡区域
+func TestOcclusionNote(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "解剖学"}
+	db.Create(&deck)
+	image := decodeMedia(t, uploadMedia(router, "heart.png", pngBytes(5)))
+
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id":  deck.ID,
+		"type":     "occlusion",
+		"content":  "心脏结构",
+		"media_id": image.ID,
+		"masks": []map[string]interface{}{
+			{"shape": "rect", "x": 0.1, "y": 0.1, "width": 0.2, "height": 0.2, "label": "左心房"},
+			{"shape": "rect", "x": 0.5, "y": 0.1, "width": 0.2, "height": 0.2, "label": "右心房"},
+			{"shape": "polygon", "points": [][2]float64{{0.1, 0.6}, {0.3, 0.6}, {0.2, 0.9}}, "label": "心尖"},
+		},
+	}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.Note `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 3, len(response.Data.Cards))
+	assert.Equal(t, []int{1, 2, 3}, []int{response.Data.Masks[0].ID, response.Data.Masks[1].ID, response.Data.Masks[2].ID})
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/study/deck/%d", deck.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var session struct {
+		Data models.StudySession `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &session)
+	assert.Equal(t, 3, len(session.Data.Queue))
+	for _, item := range session.Data.Queue {
+		assert.Equal(t, models.CardTypeOcclusion, item.Type)
+		assert.NotNil(t, item.HideMask)
+		assert.Equal(t, 2, len(item.ShowMasks))
+		assert.Equal(t, 1, len(item.Media))
+		assert.Contains(t, item.Answer, item.HideMask.Label)
+	}
+
+	// 没有遮挡区域或引用的不是图片
+	w = sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "type": "occlusion", "media_id": image.ID, "masks": []map[string]interface{}{},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "type": "occlusion", "media_id": image.ID + 100,
+		"masks": []map[string]interface{}{{"shape": "rect", "width": 1, "height": 1}},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestUpdateOcclusionKeepsScheduling 测试编辑遮挡区域时未变化的区域保留复习进度
+func TestUpdateOcclusionKeepsScheduling(t *testing.T) {
+	useTempMediaDir(t)
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "地图"}
+	db.Create(&deck)
+	image := decodeMedia(t, uploadMedia(router, "map.png", pngBytes(6)))
+
+	kept := map[string]interface{}{"shape": "rect", "x": 0.1, "y": 0.1, "width": 0.2, "height": 0.2, "label": "北京"}
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id":  deck.ID,
+		"type":     "occlusion",
+		"media_id": image.ID,
+		"masks": []map[string]interface{}{
+			kept,
+			{"shape": "rect", "x": 0.5, "y": 0.5, "width": 0.1, "height": 0.1, "label": "上海"},
+		},
+	}, nil)
+	var created struct {
+		Data models.Note `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	keptCardID := created.Data.Cards[0].ID
+	db.Create(&models.Review{CardID: keptCardID, EFactor: 2.5, Interval: 10, Repetitions: 3, NextReview: time.Now()})
+
+	// 保留区域只修改标签，移动第二个区域
+	kept["label"] = "首都"
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/notes/%d", created.Data.ID), map[string]interface{}{
+		"deck_id":  deck.ID,
+		"media_id": image.ID,
+		"masks": []map[string]interface{}{
+			kept,
+			{"shape": "rect", "x": 0.6, "y": 0.5, "width": 0.1, "height": 0.1, "label": "上海"},
+		},
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cards []models.Card
+	db.Where("note_id = ?", created.Data.ID).Order("ord").Find(&cards)
+	assert.Equal(t, 2, len(cards))
+	assert.Equal(t, keptCardID, cards[0].ID)
+	assert.Equal(t, 1, cards[0].Ord)
+	assert.Equal(t, 3, cards[1].Ord)
+	assert.Equal(t, "首都", cards[0].Masks[0].Label)
+
+	var review models.Review
+	db.Where("card_id = ?", keptCardID).First(&review)
+	assert.Equal(t, 10, review.Interval)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// queryCards 通过卡片列表接口按搜索语句查询，返回状态码、卡片和错误信息
+func queryCards(router http.Handler, path, q string) (int, []models.CardResponse, string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path+"?page=1&page_size=50&q="+url.QueryEscape(q), nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Message string                  `json:"message"`
+		Data    models.CardListResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Data.Cards, response.Message
+}
+
+// TestCardSearchQuery 测试搜索语句：卡包、标签、复习状态、复习属性、时间、字段匹配、短语、OR、排除和分组
+func TestCardSearchQuery(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	japanese := models.Deck{Name: "日语"}
+	db.Create(&japanese)
+	n3 := models.Deck{Name: "N3", ParentID: &japanese.ID}
+	db.Create(&n3)
+	english := models.Deck{Name: "英语"}
+	db.Create(&english)
+	verbs := models.Tag{Name: "verbs", Path: "verbs"}
+	db.Create(&verbs)
+
+	now := time.Now()
+	eat := models.Card{DeckID: japanese.ID, Question: "食べる", Answer: "to eat"}
+	drink := models.Card{DeckID: n3.ID, Question: "飲む", Answer: "to drink"}
+	phrase := models.Card{DeckID: japanese.ID, Question: "an exact phrase here", Answer: "foo"}
+	foobar := models.Card{DeckID: english.ID, Question: "foobar baz", Answer: "something"}
+	for _, card := range []*models.Card{&eat, &drink, &phrase, &foobar} {
+		db.Create(card)
+	}
+	db.Model(&eat).Association("Tags").Append(&verbs)
+	db.Model(&drink).Association("Tags").Append(&verbs)
+	db.Create(&models.Review{CardID: eat.ID, Interval: 40, Repetitions: 3, EFactor: 2.1, NextReview: now.AddDate(0, 0, -1)})
+	db.Create(&models.Review{CardID: phrase.ID, Interval: 10, Repetitions: 2, NextReview: now.AddDate(0, 0, 5)})
+	db.Create(&models.Review{CardID: foobar.ID, Interval: 31, Repetitions: 4, NextReview: now.AddDate(0, 0, -3)})
+	db.Model(&foobar).UpdateColumn("created_at", now.AddDate(0, 0, -10))
+
+	cases := []struct {
+		query string
+		want  []uint
+	}{
+		{`deck:"日语" tag:verbs is:due -is:new prop:ivl>30`, []uint{eat.ID}},
+		{`deck:日语`, []uint{eat.ID, drink.ID, phrase.ID}},
+		{`deck:日* -deck:n3`, []uint{eat.ID, phrase.ID}},
+		{`is:new`, []uint{drink.ID}},
+		{`is:due`, []uint{eat.ID, drink.ID, foobar.ID}},
+		{`"exact phrase"`, []uint{phrase.ID}},
+		{`"phrase exact"`, []uint{}},
+		{`front:foo*`, []uint{foobar.ID}},
+		{`front:foo`, []uint{}},
+		{`back:foo`, []uint{phrase.ID}},
+		{`tag:none`, []uint{phrase.ID, foobar.ID}},
+		{`(deck:英语 OR tag:verbs) -飲む`, []uint{eat.ID, foobar.ID}},
+		{`added:7`, []uint{eat.ID, drink.ID, phrase.ID}},
+		{`prop:due>0`, []uint{phrase.ID}},
+		{`prop:ivl>=31 prop:reps!=3`, []uint{foobar.ID}},
+		{`prop:ease<2.2`, []uint{eat.ID}},
+	}
+	for _, c := range cases {
+		code, cards, _ := queryCards(router, "/api/v1/cards", c.query)
+		assert.Equal(t, http.StatusOK, code, c.query)
+		ids := []uint{}
+		for _, card := range cards {
+			ids = append(ids, card.ID)
+		}
+		assert.ElementsMatch(t, c.want, ids, c.query)
+	}
+
+	// 搜索语句中的文本在摘要中高亮
+	_, cards, _ := queryCards(router, "/api/v1/cards", "eat -drink")
+	if assert.Equal(t, 1, len(cards)) {
+		assert.Equal(t, "to <mark>eat</mark>", cards[0].Snippet)
+	}
+
+	// 卡包和标签下的卡片列表同样支持搜索语句
+	_, cards, _ = queryCards(router, fmt.Sprintf("/api/v1/decks/%d/cards", japanese.ID), "prop:ivl>30")
+	if assert.Equal(t, 1, len(cards)) {
+		assert.Equal(t, eat.ID, cards[0].ID)
+	}
+	_, cards, _ = queryCards(router, fmt.Sprintf("/api/v1/tags/%d/cards", verbs.ID), "is:new")
+	if assert.Equal(t, 1, len(cards)) {
+		assert.Equal(t, drink.ID, cards[0].ID)
+	}
+
+	// 批量操作和按条件学习的筛选条件同样支持搜索语句
+	w, result := bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionFlag, "flag": models.FlagRed, "filter": map[string]string{"q": "tag:verbs"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Succeeded)
+	var session models.StudySession
+	w = sendJSON(router, "POST", "/api/v1/study/filtered", map[string]interface{}{"q": "flag:red is:new"}, &session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(session.Queue)) {
+		assert.Equal(t, drink.ID, session.Queue[0].CardID)
+	}
+}
+
+// TestCardSearchQueryErrors 测试格式错误的搜索语句返回带有出错位置的错误信息
+func TestCardSearchQueryErrors(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+
+	cases := map[string]string{
+		`deck:"日语`:           "第1个字符）: 引号没有闭合",
+		`foo http://example`: "第5个字符）: 未知的搜索条件 http:",
+		`prop:ivl>>3`:        "prop: 的格式应为",
+		`prop:size>3`:        "prop: 的属性必须是",
+		`prop:ivl>1.5`:       "prop:ivl 的值必须是整数",
+		`(a OR b`:            "第1个字符）: 括号没有闭合",
+		`a b)`:               "第4个字符）: 多余的右括号",
+		`a OR`:               "OR 后缺少搜索条件",
+		`OR a`:               "OR 前缺少搜索条件",
+		`a ()`:               "括号内缺少搜索条件",
+		`a - b`:              "第3个字符）: - 后缺少搜索条件",
+		`is:later`:           "is: 的值必须是",
+		`flag:black`:         "flag: 的值必须是",
+		`added:0`:            "added: 的值必须是正整数天数",
+		`tag:`:               "tag: 后缺少内容",
+	}
+	for query, message := range cases {
+		code, _, msg := queryCards(router, "/api/v1/cards", query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+		assert.Contains(t, msg, "搜索语句格式错误", query)
+		assert.Contains(t, msg, message, query)
+	}
+
+	code, _, _ := queryCards(router, fmt.Sprintf("/api/v1/decks/%d/cards", deck.ID), "is:")
+	assert.Equal(t, http.StatusBadRequest, code)
+	w, _ := bulkUpdateCards(router, map[string]interface{}{"action": models.BulkActionSuspend, "filter": map[string]string{"q": "(is:new"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/study/filtered", map[string]interface{}{"q": "deck:"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 加引号的冒号和 OR 按普通文本搜索
+	code, _, _ = queryCards(router, "/api/v1/cards", `"http://example" "or"`)
+	assert.Equal(t, http.StatusOK, code)
+}
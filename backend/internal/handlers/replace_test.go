@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestReplaceCards 测试查找替换：预览、普通文本和正则替换、字段和范围限制、修订记录
+func TestReplaceCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "生物"}
+	db.Create(&deck)
+	other := models.Deck{Name: "化学"}
+	db.Create(&other)
+	tag := models.Tag{Name: "细胞", Path: "细胞"}
+	db.Create(&tag)
+
+	cell := models.Card{DeckID: deck.ID, Question: "Mitochondira 是什么？", Answer: "mitochondira 是细胞的能量工厂"}
+	plant := models.Card{DeckID: deck.ID, Question: "叶绿体", Answer: "进行光合作用，与 Mitochondira 不同"}
+	elsewhere := models.Card{DeckID: other.ID, Question: "Mitochondira", Answer: "其他卡包"}
+	for _, card := range []*models.Card{&cell, &plant, &elsewhere} {
+		db.Create(card)
+	}
+	db.Model(&cell).Association("Tags").Append(&tag)
+	reload := func(id uint) models.Card {
+		var card models.Card
+		db.First(&card, id)
+		return card
+	}
+
+	// 预览不修改卡片
+	var preview models.CardReplaceResponse
+	w := sendJSON(router, "POST", "/api/v1/cards/replace", map[string]interface{}{
+		"find": "Mitochondira", "replace": "Mitochondria", "ignore_case": true, "deck_id": deck.ID, "dry_run": true,
+	}, &preview)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, 2, preview.Total)
+	assert.Equal(t, 2, preview.Changed)
+	assert.Equal(t, 3, preview.Replacements)
+	if assert.Equal(t, 2, len(preview.Cards)) && assert.Equal(t, 2, len(preview.Cards[0].Changes)) {
+		change := preview.Cards[0].Changes[1]
+		assert.Equal(t, models.ReplaceFieldAnswer, change.Field)
+		assert.Equal(t, "mitochondira 是细胞的能量工厂", change.Before)
+		assert.Equal(t, "Mitochondria 是细胞的能量工厂", change.After)
+	}
+	assert.Equal(t, cell.Question, reload(cell.ID).Question)
+
+	// 只替换问题，区分大小写
+	var result models.CardReplaceResponse
+	w = sendJSON(router, "POST", "/api/v1/cards/replace", map[string]interface{}{
+		"find": "Mitochondira", "replace": "Mitochondria", "field": "question", "deck_id": deck.ID,
+	}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, result.Changed)
+	updated := reload(cell.ID)
+	assert.Equal(t, "Mitochondria 是什么？", updated.Question)
+	assert.Equal(t, "mitochondira 是细胞的能量工厂", updated.Answer)
+	var revisions int64
+	db.Model(&models.CardRevision{}).Where("card_id = ?", cell.ID).Count(&revisions)
+	assert.Equal(t, int64(2), revisions)
+
+	// 正则替换可以引用分组，范围为标签和搜索结果
+	w = sendJSON(router, "POST", "/api/v1/cards/replace", map[string]interface{}{
+		"find": `(?i)(mito)chondira`, "replace": "${1}chondria", "regex": true, "tag_id": tag.ID,
+	}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "mitochondria 是细胞的能量工厂", reload(cell.ID).Answer)
+	w = sendJSON(router, "POST", "/api/v1/cards/replace", map[string]interface{}{
+		"find": "Mitochondira", "replace": "Mitochondria", "filter": map[string]interface{}{"q": "deck:化学"},
+	}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "Mitochondria", reload(elsewhere.ID).Question)
+	assert.Equal(t, plant.Answer, reload(plant.ID).Answer)
+
+	// 缺少范围、字段或正则表达式无效时返回400
+	for _, body := range []map[string]interface{}{
+		{"find": "a", "replace": "b"},
+		{"find": "a", "replace": "b", "deck_id": deck.ID, "field": "tags"},
+		{"find": "(a", "replace": "b", "deck_id": deck.ID, "regex": true},
+		{"find": "a", "replace": "b", "filter": map[string]interface{}{"q": "is:"}},
+		{"find": "Mitochondria", "replace": "x", "filter": map[string]interface{}{}},
+		{"find": "Mitochondria", "replace": "x", "filter": map[string]interface{}{"q": " "}},
+	} {
+		w = sendJSON(router, "POST", "/api/v1/cards/replace", body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Equal(t, "Mitochondria", reload(elsewhere.ID).Question)
+}
+
+// TestReplaceCardsSkipsNoteCards 测试由笔记生成的卡片不被替换
+func TestReplaceCardsSkipsNoteCards(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "笔记"}
+	db.Create(&deck)
+	var note models.Note
+	w := sendJSON(router, "POST", "/api/v1/notes", map[string]interface{}{
+		"deck_id": deck.ID, "type": "cloze", "content": "{{c1::Paris}} is the capital of Frnace",
+	}, &note)
+	if !assert.Equal(t, http.StatusCreated, w.Code) {
+		return
+	}
+
+	var result models.CardReplaceResponse
+	w = sendJSON(router, "POST", "/api/v1/cards/replace", map[string]interface{}{
+		"find": "Frnace", "replace": "France", "deck_id": deck.ID,
+	}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, result.Changed)
+	assert.NotEmpty(t, result.SkippedCardIDs)
+}
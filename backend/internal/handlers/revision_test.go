@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/config"
+	"flashcard/internal/models"
+)
+
+// updateCardContent 通过接口更新卡片的问题和答案
+func updateCardContent(t *testing.T, router http.Handler, card models.Card, question, answer string) {
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", card.ID), map[string]interface{}{
+		"deck_id":  card.DeckID,
+		"question": question,
+		"answer":   answer,
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// getCardRevisions 通过接口获取卡片修订记录
+func getCardRevisions(t *testing.T, router http.Handler, cardID uint) []models.CardRevision {
+	var revisions []models.CardRevision
+	w := sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d/revisions", cardID), nil, &revisions)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return revisions
+}
+
+// TestCardRevisionHistory 测试编辑卡片记录修订
+func TestCardRevisionHistory(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	// 未编辑的卡片没有修订
+	assert.Empty(t, getCardRevisions(t, router, card.ID))
+
+	updateCardContent(t, router, card, "新问题", "答案")
+	revisions := getCardRevisions(t, router, card.ID)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "新问题", revisions[0].Question)
+	assert.Equal(t, []string{models.FieldQuestion}, revisions[0].ChangedFields)
+	assert.Equal(t, "问题", revisions[1].Question)
+	assert.Empty(t, revisions[1].ChangedFields)
+
+	// 内容没有变化时不记录修订
+	updateCardContent(t, router, card, "新问题", "答案")
+	assert.Equal(t, 2, len(getCardRevisions(t, router, card.ID)))
+
+	updateCardContent(t, router, card, "新问题", "新答案")
+	revisions = getCardRevisions(t, router, card.ID)
+	assert.Equal(t, 3, len(revisions))
+	assert.Equal(t, []string{models.FieldAnswer}, revisions[0].ChangedFields)
+	assert.False(t, revisions[0].CreatedAt.IsZero())
+
+	// 不存在的卡片
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/cards/99999/revisions", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestCardRevisionDiff 测试比较两个修订
+func TestCardRevisionDiff(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "第一行\n第二行", Answer: "答案"}
+	db.Create(&card)
+
+	updateCardContent(t, router, card, "第一行\n修改的第二行\n第三行", "答案")
+	revisions := getCardRevisions(t, router, card.ID)
+	assert.Equal(t, 2, len(revisions))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d/revisions/diff?from=%d&to=%d", card.ID, revisions[1].ID, revisions[0].ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.RevisionDiff `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(response.Data.Fields))
+
+	field := response.Data.Fields[0]
+	assert.Equal(t, models.FieldQuestion, field.Field)
+	assert.Equal(t, []models.DiffLine{
+		{Op: "equal", Text: "第一行"},
+		{Op: "delete", Text: "第二行"},
+		{Op: "insert", Text: "修改的第二行"},
+		{Op: "insert", Text: "第三行"},
+	}, field.Lines)
+
+	// 其他卡片的修订
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d/revisions/diff?from=%d&to=99999", card.ID, revisions[1].ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 缺少参数
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/cards/%d/revisions/diff", card.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestRevertCard 测试恢复到旧修订
+func TestRevertCard(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "原问题", Answer: "原答案"}
+	db.Create(&card)
+
+	updateCardContent(t, router, card, "新问题", "新答案")
+	revisions := getCardRevisions(t, router, card.ID)
+	original := revisions[len(revisions)-1]
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/cards/%d/revisions/%d/revert", card.ID, original.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var reverted models.Card
+	db.First(&reverted, card.ID)
+	assert.Equal(t, "原问题", reverted.Question)
+	assert.Equal(t, "原答案", reverted.Answer)
+
+	// 恢复操作记录为新的修订
+	revisions = getCardRevisions(t, router, card.ID)
+	assert.Equal(t, 3, len(revisions))
+	assert.Equal(t, "原问题", revisions[0].Question)
+	assert.ElementsMatch(t, []string{models.FieldQuestion, models.FieldAnswer}, revisions[0].ChangedFields)
+
+	// 不存在的修订
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/cards/%d/revisions/99999/revert", card.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestCardRevisionRetention 测试修订保留数量
+func TestCardRevisionRetention(t *testing.T) {
+	original := config.AppConfig
+	config.AppConfig = &config.Config{CardRevisionLimit: 3}
+	t.Cleanup(func() { config.AppConfig = original })
+
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题0", Answer: "答案"}
+	db.Create(&card)
+
+	for i := 1; i <= 5; i++ {
+		updateCardContent(t, router, card, fmt.Sprintf("问题%d", i), "答案")
+	}
+
+	revisions := getCardRevisions(t, router, card.ID)
+	assert.Equal(t, 3, len(revisions))
+	assert.Equal(t, "问题5", revisions[0].Question)
+	assert.Equal(t, "问题3", revisions[2].Question)
+}
+
+// TestChoiceOptionRevisions 测试只修改选择题选项也记录修订，比较和恢复时包含选项
+func TestChoiceOptionRevisions(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := createChoiceCard(t, router, deck.ID, []map[string]interface{}{
+		{"content": "func", "correct": true},
+		{"content": "class", "correct": false},
+	})
+
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", card.ID), map[string]interface{}{
+		"deck_id": deck.ID, "type": "choice", "question": card.Question, "answer": card.Answer,
+		"options": []map[string]interface{}{
+			{"content": "func", "correct": true},
+			{"content": "defer", "correct": true},
+			{"content": "class", "correct": false},
+		},
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	revisions := getCardRevisions(t, router, card.ID)
+	if !assert.Equal(t, 2, len(revisions)) {
+		return
+	}
+	assert.Equal(t, []string{models.FieldOptions}, revisions[0].ChangedFields)
+	assert.Equal(t, 3, len(revisions[0].Options))
+	original := revisions[1]
+	assert.Equal(t, []models.OptionExport{{Content: "func", Correct: true}, {Content: "class"}}, original.Options)
+
+	var diff models.RevisionDiff
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/cards/%d/revisions/diff?from=%d&to=%d", card.ID, original.ID, revisions[0].ID), nil, &diff)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(diff.Fields)) {
+		assert.Equal(t, models.FieldOptions, diff.Fields[0].Field)
+	}
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/cards/%d/revisions/%d/revert", card.ID, original.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var options []models.CardOption
+	db.Where("card_id = ?", card.ID).Order("position").Find(&options)
+	if assert.Equal(t, 2, len(options)) {
+		assert.Equal(t, "func", options[0].Content)
+		assert.True(t, options[0].Correct)
+		assert.Equal(t, "class", options[1].Content)
+	}
+	revisions = getCardRevisions(t, router, card.ID)
+	assert.Equal(t, []string{models.FieldOptions}, revisions[0].ChangedFields)
+}
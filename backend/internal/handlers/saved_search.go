@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SavedSearchHandler 保存搜索处理器
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+}
+
+// NewSavedSearchHandler 创建保存搜索处理器实例
+func NewSavedSearchHandler() *SavedSearchHandler {
+	return &SavedSearchHandler{
+		savedSearchService: services.NewSavedSearchService(),
+	}
+}
+
+// CreateSavedSearch 保存搜索
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	var req struct {
+		Name      string             `json:"name" binding:"required,min=1,max=100"`
+		Filter    models.SavedFilter `json:"filter"`
+		SortOrder *int               `json:"sort_order"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	search, err := h.savedSearchService.CreateSavedSearch(req.Name, req.Filter, req.SortOrder)
+	if err != nil {
+		respondSavedSearchError(c, err, "保存搜索失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(search))
+}
+
+// GetSavedSearches 获取所有保存的搜索
+func (h *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	searches, err := h.savedSearchService.GetSavedSearches()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取保存的搜索失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]interface{}{
+		"saved_searches": searches,
+	}))
+}
+
+// GetSavedSearch 获取单个保存的搜索
+func (h *SavedSearchHandler) GetSavedSearch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的保存搜索ID"))
+		return
+	}
+
+	search, err := h.savedSearchService.GetSavedSearchByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "保存的搜索不存在"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(search))
+}
+
+// UpdateSavedSearch 更新保存的搜索
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的保存搜索ID"))
+		return
+	}
+
+	var req struct {
+		Name      string              `json:"name" binding:"max=100"`
+		Filter    *models.SavedFilter `json:"filter"`
+		SortOrder *int                `json:"sort_order"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	search, err := h.savedSearchService.UpdateSavedSearch(uint(id), req.Name, req.Filter, req.SortOrder)
+	if err != nil {
+		respondSavedSearchError(c, err, "更新保存的搜索失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(search))
+}
+
+// DeleteSavedSearch 删除保存的搜索
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的保存搜索ID"))
+		return
+	}
+
+	if err := h.savedSearchService.DeleteSavedSearch(uint(id)); err != nil {
+		respondSavedSearchError(c, err, "删除保存的搜索失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// GetSavedSearchCards 执行保存的搜索，返回匹配的卡片
+func (h *SavedSearchHandler) GetSavedSearchCards(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的保存搜索ID"))
+		return
+	}
+
+	cards, err := h.savedSearchService.SearchCards(uint(id), cardPageRequest(c))
+	if err != nil {
+		respondSavedSearchError(c, err, "执行保存的搜索失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(cards))
+}
+
+// respondSavedSearchError 根据保存搜索服务返回的错误类型返回对应的HTTP状态
+func respondSavedSearchError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "保存的搜索不存在"))
+	case errors.Is(err, services.ErrSavedSearchNameConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error()))
+	case errors.Is(err, services.ErrInvalidFlag), isCardListParamError(err):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, message, err.Error()))
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestSavedSearches 测试保存搜索的增删改查、排序和执行
+func TestSavedSearches(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	flagged := models.Card{DeckID: deck.ID, Question: "食べる", Answer: "to eat", Flag: models.FlagRed}
+	db.Create(&flagged)
+	db.Create(&models.Card{DeckID: deck.ID, Question: "飲む", Answer: "to drink"})
+
+	var first, second models.SavedSearch
+	w := sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{
+		"name":   "待跟进",
+		"filter": map[string]interface{}{"deck_id": deck.ID, "q": "is:new flag:red"},
+	}, &first)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "is:new flag:red", first.Filter.Query)
+	w = sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{
+		"name": "全部", "filter": map[string]interface{}{}, "sort_order": -1,
+	}, &second)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// 名称重复和格式错误的搜索语句被拒绝
+	w = sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{"name": "全部"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{
+		"name": "错误", "filter": map[string]interface{}{"q": "prop:ivl>"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 列表按顺序排列
+	var list struct {
+		SavedSearches []models.SavedSearch `json:"saved_searches"`
+	}
+	w = sendJSON(router, "GET", "/api/v1/saved-searches", nil, &list)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 2, len(list.SavedSearches)) {
+		assert.Equal(t, second.ID, list.SavedSearches[0].ID)
+	}
+
+	// 执行保存的搜索
+	var cards models.CardListResponse
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards?page=1&page_size=10", first.ID), nil, &cards)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(cards.Cards)) {
+		assert.Equal(t, flagged.ID, cards.Cards[0].ID)
+	}
+
+	// 修改筛选条件时整体替换，未指定的字段保持原值
+	var updated models.SavedSearch
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/saved-searches/%d", first.ID), map[string]interface{}{
+		"filter": map[string]interface{}{"q": "drink"},
+	}, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "待跟进", updated.Name)
+	assert.Nil(t, updated.Filter.DeckID)
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards", first.ID), nil, &cards)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(cards.Cards)) {
+		assert.Equal(t, "飲む", cards.Cards[0].Question)
+	}
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/saved-searches/%d", first.ID), map[string]interface{}{"name": "全部"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/saved-searches/%d", first.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards", first.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/saved-searches/%d", first.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestSavedSearchSort 测试保存搜索的排序方式：执行时默认使用保存的排序，请求参数可以覆盖
+func TestSavedSearchSort(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "水果"}
+	db.Create(&deck)
+	for _, question := range []string{"banana", "apple", "cherry"} {
+		db.Create(&models.Card{DeckID: deck.ID, Question: question, Answer: "答案"})
+	}
+	questions := func(cards models.CardListResponse) []string {
+		result := []string{}
+		for _, card := range cards.Cards {
+			result = append(result, card.Question)
+		}
+		return result
+	}
+
+	var search models.SavedSearch
+	w := sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{
+		"name": "按问题", "filter": map[string]interface{}{"deck_id": deck.ID, "sort": "question", "order": "desc"},
+	}, &search)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, models.CardSortQuestion, search.Filter.Sort)
+
+	var cards models.CardListResponse
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards", search.ID), nil, &cards)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"cherry", "banana", "apple"}, questions(cards))
+
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards?sort=question&order=asc", search.ID), nil, &cards)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"apple", "banana", "cherry"}, questions(cards))
+
+	// 修改筛选条件时排序方式一起替换
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/saved-searches/%d", search.ID), map[string]interface{}{
+		"filter": map[string]interface{}{"deck_id": deck.ID, "sort": "question", "order": "asc"},
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "GET", fmt.Sprintf("/api/v1/saved-searches/%d/cards", search.ID), nil, &cards)
+	assert.Equal(t, []string{"apple", "banana", "cherry"}, questions(cards))
+
+	for _, filter := range []map[string]interface{}{{"sort": "random"}, {"sort": "question", "order": "up"}} {
+		w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/saved-searches/%d", search.ID), map[string]interface{}{"filter": filter}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestBackupSavedSearches 测试完整备份和恢复包含保存的搜索
+func TestBackupSavedSearches(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	var search models.SavedSearch
+	w := sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{
+		"name": "到期", "filter": map[string]interface{}{"q": "is:due", "flagged": true, "sort": "due"}, "sort_order": 3,
+	}, &search)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/system/backup", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "backup.zip")
+	part.Write(w.Body.Bytes())
+	writer.Close()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/system/restore", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var restored models.SavedSearch
+	if assert.NoError(t, db.First(&restored, search.ID).Error) {
+		assert.Equal(t, "到期", restored.Name)
+		assert.Equal(t, "is:due", restored.Filter.Query)
+		assert.Equal(t, models.CardSortDue, restored.Filter.Sort)
+		if assert.NotNil(t, restored.Filter.Flagged) {
+			assert.True(t, *restored.Filter.Flagged)
+		}
+		assert.Equal(t, 3, restored.SortOrder)
+	}
+}
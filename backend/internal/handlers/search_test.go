@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"flashcard/pkg/database"
+)
+
+// searchCards 通过接口按关键词搜索卡片
+func searchCards(t *testing.T, router http.Handler, keyword string) []models.CardResponse {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/cards?page=1&page_size=20&keyword="+url.QueryEscape(keyword), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.CardListResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data.Cards
+}
+
+// TestSearchCardsFullText 测试关键词搜索：中文和英文按子串匹配、短词、相关度排序、高亮摘要，以及索引随卡片修改和删除同步
+func TestSearchCardsFullText(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "搜索卡包"}
+	db.Create(&deck)
+	inAnswer := models.Card{DeckID: deck.ID, Question: "什么是光合作用？", Answer: "植物利用光能把二氧化碳和水转化为有机物"}
+	inQuestion := models.Card{DeckID: deck.ID, Question: "二氧化碳的化学式", Answer: "CO2"}
+	english := models.Card{DeckID: deck.ID, Question: "Photosynthesis happens in the chloroplast", Answer: "叶绿体"}
+	fullwidth := models.Card{DeckID: deck.ID, Question: "ＤＮＡ的全称", Answer: "脱氧核糖核酸"}
+	db.Create(&inAnswer)
+	db.Create(&inQuestion)
+	db.Create(&english)
+	db.Create(&fullwidth)
+
+	ids := func(cards []models.CardResponse) []uint {
+		result := []uint{}
+		for _, card := range cards {
+			result = append(result, card.ID)
+		}
+		return result
+	}
+
+	// 中文按连续字符匹配，问题中的匹配排在答案中的匹配之前
+	cards := searchCards(t, router, "二氧化碳")
+	assert.ElementsMatch(t, []uint{inAnswer.ID, inQuestion.ID}, ids(cards))
+	if database.FTSEnabled() && assert.Equal(t, 2, len(cards)) {
+		assert.Equal(t, inQuestion.ID, cards[0].ID)
+	}
+	for _, card := range cards {
+		assert.Contains(t, card.Snippet, "<mark>二氧化碳</mark>")
+	}
+	assert.Empty(t, searchCards(t, router, "氧碳"))
+
+	// 英文忽略大小写并按子串匹配，多个词需要同时匹配
+	cards = searchCards(t, router, "photo CHLORO")
+	if assert.Equal(t, []uint{english.ID}, ids(cards)) {
+		assert.Equal(t, "<mark>Photo</mark>synthesis happens in the <mark>chloro</mark>plast", cards[0].Snippet)
+	}
+	assert.Equal(t, []uint{english.ID}, ids(searchCards(t, router, "synthesis")))
+	assert.Empty(t, searchCards(t, router, "photo mitochondria"))
+	assert.Equal(t, []uint{fullwidth.ID}, ids(searchCards(t, router, "ＤＮＡ")))
+
+	// 不足三个字符的词不能使用全文索引，与其他词同时指定时同样需要匹配
+	assert.Equal(t, []uint{inAnswer.ID}, ids(searchCards(t, router, "光合")))
+	assert.Equal(t, []uint{inAnswer.ID}, ids(searchCards(t, router, "二氧化碳 光合")))
+
+	// 修改和删除卡片后索引同步更新
+	w := sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/cards/%d", inQuestion.ID), map[string]interface{}{
+		"deck_id": deck.ID, "question": "水的化学式", "answer": "H2O",
+	}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{inAnswer.ID}, ids(searchCards(t, router, "二氧化碳")))
+	assert.Equal(t, []uint{inQuestion.ID}, ids(searchCards(t, router, "水的化学式")))
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", inAnswer.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, searchCards(t, router, "二氧化碳"))
+	db.Unscoped().Delete(&models.Card{}, inAnswer.ID)
+	assert.Empty(t, searchCards(t, router, "光合作用"))
+}
+
+// TestSearchIndexExternalWrites 测试没有注册本服务函数的SQLite连接（如sqlite3命令行）也能写入卡片，索引同步更新
+func TestSearchIndexExternalWrites(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "外部写入"}
+	db.Create(&deck)
+
+	external, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer external.Close()
+
+	now := time.Now()
+	result, err := external.Exec("INSERT INTO cards (deck_id, question, answer, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		deck.ID, "线粒体的功能", "有氧呼吸的主要场所", now, now)
+	if !assert.NoError(t, err) {
+		return
+	}
+	id, _ := result.LastInsertId()
+	cards := searchCards(t, router, "有氧呼吸")
+	if assert.Equal(t, 1, len(cards)) {
+		assert.Equal(t, uint(id), cards[0].ID)
+	}
+
+	_, err = external.Exec("UPDATE cards SET answer = ? WHERE id = ?", "细胞的能量工厂", id)
+	assert.NoError(t, err)
+	assert.Empty(t, searchCards(t, router, "有氧呼吸"))
+	assert.Equal(t, 1, len(searchCards(t, router, "能量工厂")))
+
+	_, err = external.Exec("DELETE FROM cards WHERE id = ?", id)
+	assert.NoError(t, err)
+	assert.Empty(t, searchCards(t, router, "能量工厂"))
+}
+
+// benchTopics 基准测试卡片的主题，每个主题约占全部卡片的1%
+var benchTopics = []string{"线粒体", "叶绿体", "核糖体", "高尔基体", "内质网", "溶酶体", "中心体", "液泡", "细胞核", "细胞膜"}
+
+// seedSearchData 批量创建用于搜索基准测试的卡片，每张卡片属于100个主题之一（中文名称和英文编号）
+func seedSearchData(db *gorm.DB, count int) {
+	deck := models.Deck{Name: "基准卡包"}
+	db.Create(&deck)
+
+	cards := make([]models.Card, 0, count)
+	for i := 0; i < count; i++ {
+		topic := i % 100
+		name := benchTopics[topic%len(benchTopics)] + fmt.Sprintf("第%d型", topic/len(benchTopics))
+		cards = append(cards, models.Card{
+			DeckID:   deck.ID,
+			Question: fmt.Sprintf("第%d题：%s的功能 organelle%d", i, name, topic),
+			Answer:   fmt.Sprintf("答案%d：第%d章的要点 chapter%d summary", i, topic, topic),
+		})
+	}
+	db.CreateInBatches(cards, 500)
+}
+
+// searchBenchCards BenchmarkSearchCards 创建的卡片数，如 go test -bench SearchCards -search-cards 20000
+var searchBenchCards = flag.Int("search-cards", 100000, "BenchmarkSearchCards 创建的卡片数")
+
+// BenchmarkSearchCards 在指定数量的卡片中按匹配约1%卡片的关键词搜索，分别使用全文索引（需要 sqlite_fts5 构建标签）和LIKE查询
+func BenchmarkSearchCards(b *testing.B) {
+	db := setupTestDB()
+	seedSearchData(db, *searchBenchCards)
+	cardService := services.NewCardService()
+	req := models.CardSearchRequest{
+		CardFilter:      models.CardFilter{Keyword: "organelle42 核糖体第4型"},
+		CardPageRequest: models.CardPageRequest{Page: 1, PageSize: 20},
+	}
+
+	fts := database.FTSEnabled()
+	defer database.SetFTSEnabled(fts)
+	for _, mode := range []struct {
+		name string
+		fts  bool
+	}{{"fts", true}, {"like", false}} {
+		if mode.fts && !fts {
+			continue
+		}
+		b.Run(fmt.Sprintf("cards=%d/%s", *searchBenchCards, mode.name), func(b *testing.B) {
+			database.SetFTSEnabled(mode.fts)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				result, err := cardService.SearchCards(req)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if result.Total != int64(*searchBenchCards/100) {
+					b.Fatalf("匹配%d张卡片，应为%d张", result.Total, *searchBenchCards/100)
+				}
+			}
+		})
+	}
+}
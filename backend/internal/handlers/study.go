@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // StudyHandler 学习处理器
@@ -98,6 +100,37 @@ func (h *StudyHandler) StartRandomStudy(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(session))
 }
 
+// StartFilteredStudy 按筛选条件开始学习
+func (h *StudyHandler) StartFilteredStudy(c *gin.Context) {
+	var req models.FilteredStudyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
+		return
+	}
+
+	// 获取学习队列限制
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	session, err := h.studyService.StartFilteredStudy(req, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFlag) || errors.Is(err, services.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "开始学习失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(session))
+}
+
 // SubmitReview 提交复习结果
 func (h *StudyHandler) SubmitReview(c *gin.Context) {
 	cardIDStr := c.Param("cardId")
@@ -113,6 +146,42 @@ func (h *StudyHandler) SubmitReview(c *gin.Context) {
 		return
 	}
 
+	// 提交复习结果时可以同时标记卡片
+	if req.Flag != nil {
+		if err := h.studyService.SetCardFlag(uint(cardID), *req.Flag); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡片不存在"))
+				return
+			}
+			if errors.Is(err, services.ErrInvalidFlag) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "标记卡片失败", err.Error()))
+			return
+		}
+	}
+
+	// 提交了选择题选项时由服务端评分
+	if len(req.Choices) > 0 {
+		response, err := h.studyService.SubmitChoiceReview(uint(cardID), req.Choices)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "卡片不存在"))
+				return
+			}
+			if errors.Is(err, services.ErrNotChoiceCard) || errors.Is(err, services.ErrInvalidChoice) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "提交复习结果失败", err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, models.SuccessResponse(response))
+		return
+	}
+
 	// 验证 ReviewResult 范围
 	if req.Result < 0 || req.Result > 2 {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "复习结果必须在0-2之间"))
@@ -121,6 +190,10 @@ func (h *StudyHandler) SubmitReview(c *gin.Context) {
 
 	response, err := h.studyService.SubmitReview(uint(cardID), req.Result)
 	if err != nil {
+		if errors.Is(err, services.ErrChoicesRequired) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "提交复习结果失败", err.Error()))
 		return
 	}
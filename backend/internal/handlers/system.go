@@ -1,18 +1,31 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 备份压缩包中的数据文件和媒体目录
+const (
+	backupDataEntry   = "backup.json"
+	backupMediaPrefix = "media/"
+)
+
+// maxBackupSize 备份文件（包含媒体文件）大小上限
+const maxBackupSize = 500 * 1024 * 1024
+
 // SystemHandler 系统管理处理器
 type SystemHandler struct {
 	deckService         *services.DeckService
@@ -43,12 +56,20 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 
 	// 初始化备份数据结构
 	backupData := models.BackupData{
-		Version:    "1.0.0",
-		ExportDate: time.Now(),
-		Decks:      []models.DeckBackup{},
-		Tags:       []models.TagBackup{},
-		Cards:      []models.CardBackup{},
-		Reviews:    []models.ReviewBackup{},
+		Version:       "1.0.0",
+		ExportDate:    time.Now(),
+		Decks:         []models.DeckBackup{},
+		Tags:          []models.TagBackup{},
+		NoteTypes:     []models.NoteTypeBackup{},
+		Notes:         []models.NoteBackup{},
+		Cards:         []models.CardBackup{},
+		Options:       []models.OptionBackup{},
+		Reviews:       []models.ReviewBackup{},
+		ReviewLogs:    []models.ReviewLogBackup{},
+		Media:         []models.MediaBackup{},
+		CardMedia:     []models.CardMediaBackup{},
+		Revisions:     []models.RevisionBackup{},
+		SavedSearches: []models.SavedSearchBackup{},
 	}
 
 	// 备份所有卡包
@@ -59,11 +80,15 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 	}
 	for _, deck := range decks {
 		backupData.Decks = append(backupData.Decks, models.DeckBackup{
-			ID:        deck.ID,
-			Name:      deck.Name,
-			Archived:  deck.Archived,
-			CreatedAt: deck.CreatedAt,
-			UpdatedAt: deck.UpdatedAt,
+			ID:           deck.ID,
+			ParentID:     deck.ParentID,
+			Name:         deck.Name,
+			Archived:     deck.Archived,
+			StudyLimit:   deck.StudyLimit,
+			NewCardOrder: deck.NewCardOrder,
+			CreatedAt:    deck.CreatedAt,
+			UpdatedAt:    deck.UpdatedAt,
+			Details:      deck.Details,
 		})
 	}
 
@@ -77,15 +102,76 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 		backupData.Tags = append(backupData.Tags, models.TagBackup{
 			ID:        tag.ID,
 			DeckID:    tag.DeckID,
+			ParentID:  tag.ParentID,
 			Name:      tag.Name,
+			Path:      tag.Path,
 			CreatedAt: tag.CreatedAt,
 			UpdatedAt: tag.UpdatedAt,
+			Details:   tag.Details,
+		})
+	}
+
+	// 备份所有笔记类型
+	var noteTypes []models.NoteType
+	if err := h.cardService.GetDB().Find(&noteTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份笔记类型失败", err.Error()))
+		return
+	}
+	for _, noteType := range noteTypes {
+		backupData.NoteTypes = append(backupData.NoteTypes, models.NoteTypeBackup{
+			ID:        noteType.ID,
+			Name:      noteType.Name,
+			Fields:    noteType.Fields,
+			Templates: noteType.Templates,
+			Format:    noteType.Format,
+			CreatedAt: noteType.CreatedAt,
+			UpdatedAt: noteType.UpdatedAt,
+		})
+	}
+
+	// 备份所有保存的搜索
+	var savedSearches []models.SavedSearch
+	if err := h.cardService.GetDB().Find(&savedSearches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份保存的搜索失败", err.Error()))
+		return
+	}
+	for _, search := range savedSearches {
+		backupData.SavedSearches = append(backupData.SavedSearches, models.SavedSearchBackup{
+			ID:        search.ID,
+			Name:      search.Name,
+			Filter:    search.Filter,
+			SortOrder: search.SortOrder,
+			CreatedAt: search.CreatedAt,
+			UpdatedAt: search.UpdatedAt,
+		})
+	}
+
+	// 备份所有笔记
+	var notes []models.Note
+	if err := h.cardService.GetDB().Preload("Tags").Find(&notes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份笔记失败", err.Error()))
+		return
+	}
+	for _, note := range notes {
+		backupData.Notes = append(backupData.Notes, models.NoteBackup{
+			ID:         note.ID,
+			DeckID:     note.DeckID,
+			TagIDs:     backupTagIDs(note.Tags),
+			Type:       note.Type,
+			NoteTypeID: note.NoteTypeID,
+			Fields:     note.Fields,
+			Content:    note.Content,
+			Extra:      note.Extra,
+			MediaID:    note.MediaID,
+			Masks:      note.Masks,
+			CreatedAt:  note.CreatedAt,
+			UpdatedAt:  note.UpdatedAt,
 		})
 	}
 
 	// 备份所有卡片
 	var cards []models.Card
-	if err := h.cardService.GetDB().Find(&cards).Error; err != nil {
+	if err := h.cardService.GetDB().Preload("Tags").Find(&cards).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份卡片失败", err.Error()))
 		return
 	}
@@ -93,14 +179,38 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 		backupData.Cards = append(backupData.Cards, models.CardBackup{
 			ID:        card.ID,
 			DeckID:    card.DeckID,
-			TagID:     card.TagID,
+			TagIDs:    backupTagIDs(card.Tags),
+			NoteID:    card.NoteID,
+			Type:      card.Type,
+			Ord:       card.Ord,
 			Question:  card.Question,
 			Answer:    card.Answer,
+			Format:    card.Format,
+			Masks:     card.Masks,
+			Suspended: card.Suspended,
+			Flag:      card.Flag,
+			Position:  card.Position,
 			CreatedAt: card.CreatedAt,
 			UpdatedAt: card.UpdatedAt,
 		})
 	}
 
+	// 备份所有选择题选项
+	var options []models.CardOption
+	if err := h.cardService.GetDB().Find(&options).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份选择题选项失败", err.Error()))
+		return
+	}
+	for _, option := range options {
+		backupData.Options = append(backupData.Options, models.OptionBackup{
+			ID:       option.ID,
+			CardID:   option.CardID,
+			Position: option.Position,
+			Content:  option.Content,
+			Correct:  option.Correct,
+		})
+	}
+
 	// 备份所有复习记录
 	var reviews []models.Review
 	if err := h.cardService.GetDB().Find(&reviews).Error; err != nil {
@@ -114,24 +224,77 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 			EFactor:     review.EFactor,
 			Interval:    review.Interval,
 			Repetitions: review.Repetitions,
+			Lapses:      review.Lapses,
 			NextReview:  review.NextReview,
 			CreatedAt:   review.CreatedAt,
 			UpdatedAt:   review.UpdatedAt,
 		})
 	}
 
-	// 创建备份文件
-	backupFilename := filepath.Join(tempDir, fmt.Sprintf("flashmind_complete_backup_%s.json", time.Now().Format("2006-01-02_15-04-05")))
-	backupFile, err := os.Create(backupFilename)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "创建备份文件失败", err.Error()))
+	// 备份所有复习日志
+	var reviewLogs []models.ReviewLog
+	if err := h.cardService.GetDB().Find(&reviewLogs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份复习日志失败", err.Error()))
 		return
 	}
-	defer backupFile.Close()
+	for _, reviewLog := range reviewLogs {
+		backupData.ReviewLogs = append(backupData.ReviewLogs, models.ReviewLogBackup{
+			ID:           reviewLog.ID,
+			CardID:       reviewLog.CardID,
+			Result:       int(reviewLog.Result),
+			LastInterval: reviewLog.LastInterval,
+			Interval:     reviewLog.Interval,
+			EFactor:      reviewLog.EFactor,
+			ReviewedAt:   reviewLog.ReviewedAt,
+		})
+	}
 
-	encoder := json.NewEncoder(backupFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(backupData); err != nil {
+	// 备份所有卡片修订记录
+	var revisions []models.CardRevision
+	if err := h.cardService.GetDB().Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份卡片修订记录失败", err.Error()))
+		return
+	}
+	for _, revision := range revisions {
+		backupData.Revisions = append(backupData.Revisions, models.RevisionBackup{
+			ID:            revision.ID,
+			CardID:        revision.CardID,
+			DeckID:        revision.DeckID,
+			TagIDs:        revision.TagIDs,
+			Question:      revision.Question,
+			Answer:        revision.Answer,
+			Format:        revision.Format,
+			ChangedFields: revision.ChangedFields,
+			CreatedAt:     revision.CreatedAt,
+		})
+	}
+
+	// 备份所有媒体记录
+	var mediaList []models.Media
+	if err := h.cardService.GetDB().Find(&mediaList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份媒体记录失败", err.Error()))
+		return
+	}
+	for _, media := range mediaList {
+		backupData.Media = append(backupData.Media, models.MediaBackup{
+			ID:        media.ID,
+			Hash:      media.Hash,
+			Filename:  media.Filename,
+			MimeType:  media.MimeType,
+			Size:      media.Size,
+			CreatedAt: media.CreatedAt,
+		})
+	}
+
+	// 备份卡片与媒体的关联
+	if err := h.cardService.GetDB().Table("card_media").Find(&backupData.CardMedia).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "备份卡片媒体关联失败", err.Error()))
+		return
+	}
+
+	// 创建备份压缩包：backup.json保存数据表，media目录保存媒体文件
+	backupFilename := filepath.Join(tempDir, fmt.Sprintf("flashmind_complete_backup_%s.zip", time.Now().Format("2006-01-02_15-04-05")))
+	if err := writeBackupZip(backupFilename, backupData); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "写入备份文件失败", err.Error()))
 		return
 	}
@@ -139,10 +302,121 @@ func (h *SystemHandler) BackupData(c *gin.Context) {
 	// 直接下载文件
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(backupFilename))
-	c.Header("Content-Type", "application/json")
+	c.Header("Content-Type", "application/zip")
 	c.File(backupFilename)
 }
 
+// writeBackupZip 将备份数据和媒体文件写入压缩包
+func writeBackupZip(filename string, backupData models.BackupData) error {
+	backupFile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer backupFile.Close()
+
+	zipWriter := zip.NewWriter(backupFile)
+
+	dataWriter, err := zipWriter.Create(backupDataEntry)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(dataWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(backupData); err != nil {
+		return err
+	}
+
+	for _, media := range backupData.Media {
+		if err := addMediaToZip(zipWriter, media.Hash); err != nil {
+			return fmt.Errorf("备份媒体文件 %s 失败: %v", media.Filename, err)
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// addMediaToZip 将单个媒体文件写入压缩包的media目录
+func addMediaToZip(zipWriter *zip.Writer, hash string) error {
+	mediaFile, err := os.Open(services.MediaPath(hash))
+	if err != nil {
+		return err
+	}
+	defer mediaFile.Close()
+
+	// 媒体文件多为已压缩格式，直接存储
+	entryWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: backupMediaPrefix + hash, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entryWriter, mediaFile)
+	return err
+}
+
+// readBackupZip 读取备份压缩包，返回backup.json内容和按哈希索引的媒体文件，媒体文件在数据恢复成功后再写入
+func readBackupZip(zipReader *zip.Reader) ([]byte, map[string]*zip.File, error) {
+	var content []byte
+	mediaFiles := make(map[string]*zip.File)
+	for _, entry := range zipReader.File {
+		switch {
+		case entry.Name == backupDataEntry:
+			data, err := readZipEntry(entry, maxBackupSize)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = data
+		case strings.HasPrefix(entry.Name, backupMediaPrefix):
+			mediaFiles[strings.TrimPrefix(entry.Name, backupMediaPrefix)] = entry
+		}
+	}
+
+	if content == nil {
+		return nil, nil, fmt.Errorf("备份压缩包中缺少%s", backupDataEntry)
+	}
+
+	return content, mediaFiles, nil
+}
+
+// readZipEntry 读取压缩包中的文件，声明的大小超过上限或实际内容超过声明的大小时返回错误
+func readZipEntry(entry *zip.File, limit uint64) ([]byte, error) {
+	if entry.UncompressedSize64 > limit {
+		return nil, fmt.Errorf("%s超过大小上限", entry.Name)
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, int64(entry.UncompressedSize64)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) > entry.UncompressedSize64 {
+		return nil, fmt.Errorf("%s的大小与声明不一致", entry.Name)
+	}
+	return data, nil
+}
+
+// restoreBackupMedia 写入备份数据中记录的媒体文件，压缩包中多余的文件忽略
+func restoreBackupMedia(mediaFiles map[string]*zip.File, media []models.MediaBackup) error {
+	for _, mediaBackup := range media {
+		entry, ok := mediaFiles[mediaBackup.Hash]
+		if !ok {
+			continue
+		}
+		data, err := readZipEntry(entry, services.MaxMediaSize)
+		if err != nil {
+			return err
+		}
+		if err := services.RestoreMediaFile(mediaBackup.Hash, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // RestoreData 恢复所有数据表
 func (h *SystemHandler) RestoreData(c *gin.Context) {
 	// 获取上传的文件
@@ -152,9 +426,9 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 		return
 	}
 
-	// 检查文件大小（限制为50MB）
-	if file.Size > 50*1024*1024 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "文件大小不能超过50MB"))
+	// 检查文件大小（包含媒体文件，限制为500MB）
+	if file.Size > maxBackupSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "文件大小不能超过500MB"))
 		return
 	}
 
@@ -184,6 +458,16 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 		return
 	}
 
+	// 压缩包格式的备份包含媒体文件，旧版备份为单个JSON文件
+	var mediaFiles map[string]*zip.File
+	if zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content))); err == nil {
+		content, mediaFiles, err = readBackupZip(zipReader)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的备份压缩包", err.Error()))
+			return
+		}
+	}
+
 	var backupData models.BackupData
 	if err := json.Unmarshal(content, &backupData); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的备份文件格式"))
@@ -207,10 +491,17 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 
 	db := h.deckService.GetDB()
 	restoredCounts := gin.H{
-		"decks":   0,
-		"tags":    0,
-		"cards":   0,
-		"reviews": 0,
+		"decks":          0,
+		"tags":           0,
+		"note_types":     0,
+		"notes":          0,
+		"cards":          0,
+		"options":        0,
+		"reviews":        0,
+		"review_logs":    0,
+		"media":          0,
+		"revisions":      0,
+		"saved_searches": 0,
 	}
 
 	// 开始数据库事务
@@ -224,11 +515,15 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 	// 恢复卡包数据
 	for _, deckBackup := range backupData.Decks {
 		deck := models.Deck{
-			ID:        deckBackup.ID,
-			Name:      deckBackup.Name,
-			Archived:  deckBackup.Archived,
-			CreatedAt: deckBackup.CreatedAt,
-			UpdatedAt: deckBackup.UpdatedAt,
+			ID:           deckBackup.ID,
+			ParentID:     deckBackup.ParentID,
+			Name:         deckBackup.Name,
+			Archived:     deckBackup.Archived,
+			StudyLimit:   deckBackup.StudyLimit,
+			NewCardOrder: deckBackup.NewCardOrder,
+			CreatedAt:    deckBackup.CreatedAt,
+			UpdatedAt:    deckBackup.UpdatedAt,
+			Details:      deckBackup.Details,
 		}
 		if err := tx.Create(&deck).Error; err != nil {
 			tx.Rollback()
@@ -240,12 +535,19 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 
 	// 恢复标签数据
 	for _, tagBackup := range backupData.Tags {
+		path := tagBackup.Path
+		if path == "" {
+			path = tagBackup.Name
+		}
 		tag := models.Tag{
 			ID:        tagBackup.ID,
 			DeckID:    tagBackup.DeckID,
+			ParentID:  tagBackup.ParentID,
 			Name:      tagBackup.Name,
+			Path:      path,
 			CreatedAt: tagBackup.CreatedAt,
 			UpdatedAt: tagBackup.UpdatedAt,
+			Details:   tagBackup.Details,
 		}
 		if err := tx.Create(&tag).Error; err != nil {
 			tx.Rollback()
@@ -255,14 +557,106 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 		restoredCounts["tags"] = restoredCounts["tags"].(int) + 1
 	}
 
+	// 恢复媒体数据
+	for _, mediaBackup := range backupData.Media {
+		media := models.Media{
+			ID:        mediaBackup.ID,
+			Hash:      mediaBackup.Hash,
+			Filename:  mediaBackup.Filename,
+			MimeType:  mediaBackup.MimeType,
+			Size:      mediaBackup.Size,
+			CreatedAt: mediaBackup.CreatedAt,
+		}
+		if err := tx.Create(&media).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复媒体失败", err.Error()))
+			return
+		}
+		restoredCounts["media"] = restoredCounts["media"].(int) + 1
+	}
+
+	// 恢复笔记类型数据
+	for _, noteTypeBackup := range backupData.NoteTypes {
+		noteType := models.NoteType{
+			ID:        noteTypeBackup.ID,
+			Name:      noteTypeBackup.Name,
+			Fields:    noteTypeBackup.Fields,
+			Templates: noteTypeBackup.Templates,
+			Format:    noteTypeBackup.Format,
+			CreatedAt: noteTypeBackup.CreatedAt,
+			UpdatedAt: noteTypeBackup.UpdatedAt,
+		}
+		if err := tx.Create(&noteType).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复笔记类型失败", err.Error()))
+			return
+		}
+		restoredCounts["note_types"] = restoredCounts["note_types"].(int) + 1
+	}
+
+	// 恢复保存的搜索
+	for _, searchBackup := range backupData.SavedSearches {
+		search := models.SavedSearch{
+			ID:        searchBackup.ID,
+			Name:      searchBackup.Name,
+			Filter:    searchBackup.Filter,
+			SortOrder: searchBackup.SortOrder,
+			CreatedAt: searchBackup.CreatedAt,
+			UpdatedAt: searchBackup.UpdatedAt,
+		}
+		if err := tx.Create(&search).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复保存的搜索失败", err.Error()))
+			return
+		}
+		restoredCounts["saved_searches"] = restoredCounts["saved_searches"].(int) + 1
+	}
+
+	// 恢复笔记数据
+	for _, noteBackup := range backupData.Notes {
+		note := models.Note{
+			ID:         noteBackup.ID,
+			DeckID:     noteBackup.DeckID,
+			Type:       noteBackup.Type,
+			NoteTypeID: noteBackup.NoteTypeID,
+			Fields:     noteBackup.Fields,
+			Content:    noteBackup.Content,
+			Extra:      noteBackup.Extra,
+			MediaID:    noteBackup.MediaID,
+			Masks:      noteBackup.Masks,
+			CreatedAt:  noteBackup.CreatedAt,
+			UpdatedAt:  noteBackup.UpdatedAt,
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复笔记失败", err.Error()))
+			return
+		}
+		for _, tagID := range mergeTagIDs(noteBackup.TagID, noteBackup.TagIDs) {
+			if err := tx.Exec("INSERT OR IGNORE INTO note_tags (note_id, tag_id) VALUES (?, ?)", note.ID, tagID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复笔记标签失败", err.Error()))
+				return
+			}
+		}
+		restoredCounts["notes"] = restoredCounts["notes"].(int) + 1
+	}
+
 	// 恢复卡片数据
 	for _, cardBackup := range backupData.Cards {
 		card := models.Card{
 			ID:        cardBackup.ID,
 			DeckID:    cardBackup.DeckID,
-			TagID:     cardBackup.TagID,
+			NoteID:    cardBackup.NoteID,
+			Type:      cardBackup.Type,
+			Ord:       cardBackup.Ord,
 			Question:  cardBackup.Question,
 			Answer:    cardBackup.Answer,
+			Format:    cardBackup.Format,
+			Masks:     cardBackup.Masks,
+			Suspended: cardBackup.Suspended,
+			Flag:      cardBackup.Flag,
+			Position:  cardBackup.Position,
 			CreatedAt: cardBackup.CreatedAt,
 			UpdatedAt: cardBackup.UpdatedAt,
 		}
@@ -271,9 +665,33 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复卡片失败", err.Error()))
 			return
 		}
+		for _, tagID := range mergeTagIDs(cardBackup.TagID, cardBackup.TagIDs) {
+			if err := tx.Exec("INSERT OR IGNORE INTO card_tags (card_id, tag_id) VALUES (?, ?)", card.ID, tagID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复卡片标签失败", err.Error()))
+				return
+			}
+		}
 		restoredCounts["cards"] = restoredCounts["cards"].(int) + 1
 	}
 
+	// 恢复选择题选项数据
+	for _, optionBackup := range backupData.Options {
+		option := models.CardOption{
+			ID:       optionBackup.ID,
+			CardID:   optionBackup.CardID,
+			Position: optionBackup.Position,
+			Content:  optionBackup.Content,
+			Correct:  optionBackup.Correct,
+		}
+		if err := tx.Create(&option).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复选择题选项失败", err.Error()))
+			return
+		}
+		restoredCounts["options"] = restoredCounts["options"].(int) + 1
+	}
+
 	// 恢复复习记录数据
 	for _, reviewBackup := range backupData.Reviews {
 		review := models.Review{
@@ -282,6 +700,7 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 			EFactor:     reviewBackup.EFactor,
 			Interval:    reviewBackup.Interval,
 			Repetitions: reviewBackup.Repetitions,
+			Lapses:      reviewBackup.Lapses,
 			NextReview:  reviewBackup.NextReview,
 			CreatedAt:   reviewBackup.CreatedAt,
 			UpdatedAt:   reviewBackup.UpdatedAt,
@@ -294,6 +713,62 @@ func (h *SystemHandler) RestoreData(c *gin.Context) {
 		restoredCounts["reviews"] = restoredCounts["reviews"].(int) + 1
 	}
 
+	// 恢复复习日志数据
+	for _, reviewLogBackup := range backupData.ReviewLogs {
+		reviewLog := models.ReviewLog{
+			ID:           reviewLogBackup.ID,
+			CardID:       reviewLogBackup.CardID,
+			Result:       models.ReviewResult(reviewLogBackup.Result),
+			LastInterval: reviewLogBackup.LastInterval,
+			Interval:     reviewLogBackup.Interval,
+			EFactor:      reviewLogBackup.EFactor,
+			ReviewedAt:   reviewLogBackup.ReviewedAt,
+		}
+		if err := tx.Create(&reviewLog).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复复习日志失败", err.Error()))
+			return
+		}
+		restoredCounts["review_logs"] = restoredCounts["review_logs"].(int) + 1
+	}
+
+	// 恢复卡片修订记录
+	for _, revisionBackup := range backupData.Revisions {
+		revision := models.CardRevision{
+			ID:            revisionBackup.ID,
+			CardID:        revisionBackup.CardID,
+			DeckID:        revisionBackup.DeckID,
+			TagIDs:        revisionBackup.TagIDs,
+			Question:      revisionBackup.Question,
+			Answer:        revisionBackup.Answer,
+			Format:        revisionBackup.Format,
+			ChangedFields: revisionBackup.ChangedFields,
+			CreatedAt:     revisionBackup.CreatedAt,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复卡片修订记录失败", err.Error()))
+			return
+		}
+		restoredCounts["revisions"] = restoredCounts["revisions"].(int) + 1
+	}
+
+	// 恢复卡片与媒体的关联
+	for _, cardMedia := range backupData.CardMedia {
+		if err := tx.Exec("INSERT INTO card_media (card_id, media_id) VALUES (?, ?)", cardMedia.CardID, cardMedia.MediaID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复卡片媒体关联失败", err.Error()))
+			return
+		}
+	}
+
+	// 数据全部恢复后再写入媒体文件，写入失败时回滚
+	if err := restoreBackupMedia(mediaFiles, backupData.Media); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "恢复媒体文件失败", err.Error()))
+		return
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复数据失败", err.Error()))
@@ -333,32 +808,84 @@ func (h *SystemHandler) clearAllDataInternal() error {
 	}()
 
 	// 清空所有表的数据，按照外键依赖顺序
-	// 1. 先删除复习记录
+	// 1. 先删除复习日志和复习记录
+	if err := tx.Exec("DELETE FROM review_logs").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空复习日志失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM card_revisions").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空卡片修订记录失败: %v", err)
+	}
+
 	if err := tx.Exec("DELETE FROM reviews").Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("清空复习记录失败: %v", err)
 	}
 
-	// 2. 删除卡片
+	// 2. 删除卡片和笔记的标签关联
+	if err := tx.Exec("DELETE FROM card_tags").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空卡片标签关联失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM note_tags").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空笔记标签关联失败: %v", err)
+	}
+
+	// 3. 删除卡片媒体关联、媒体记录（文件由媒体清理接口删除）、选择题选项和卡片
+	if err := tx.Exec("DELETE FROM card_media").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空卡片媒体关联失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM media").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空媒体失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM card_options").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空选择题选项失败: %v", err)
+	}
+
 	if err := tx.Exec("DELETE FROM cards").Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("清空卡片失败: %v", err)
 	}
 
-	// 3. 删除标签
+	// 4. 删除笔记
+	if err := tx.Exec("DELETE FROM notes").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空笔记失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM note_types").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空笔记类型失败: %v", err)
+	}
+
+	if err := tx.Exec("DELETE FROM saved_searches").Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("清空保存的搜索失败: %v", err)
+	}
+
+	// 5. 删除标签
 	if err := tx.Exec("DELETE FROM tags").Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("清空标签失败: %v", err)
 	}
 
-	// 4. 删除卡包
+	// 6. 删除卡包
 	if err := tx.Exec("DELETE FROM decks").Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("清空卡包失败: %v", err)
 	}
 
 	// 重置自增ID（SQLite语法）
-	tables := []string{"decks", "tags", "cards", "reviews"}
+	tables := []string{"decks", "tags", "note_types", "notes", "cards", "card_options", "media", "reviews", "review_logs", "card_revisions", "saved_searches"}
 	for _, table := range tables {
 		if err := tx.Exec(fmt.Sprintf("DELETE FROM sqlite_sequence WHERE name='%s'", table)).Error; err != nil {
 			// 忽略错误，因为表可能没有自增字段
@@ -373,6 +900,15 @@ func (h *SystemHandler) clearAllDataInternal() error {
 	return nil
 }
 
+// backupTagIDs 获取备份中记录的标签ID列表
+func backupTagIDs(tags []models.Tag) []uint {
+	var ids []uint
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
+
 // GetSystemStats 获取系统统计信息
 func (h *SystemHandler) GetSystemStats(c *gin.Context) {
 	// 获取统计信息
@@ -382,7 +918,7 @@ func (h *SystemHandler) GetSystemStats(c *gin.Context) {
 	totalCards := int64(0)
 	totalTags := 0
 	for _, deck := range decks {
-		cardsResponse, _ := h.cardService.GetCardsByDeckID(deck.ID, 1, 1000) // 假设每个卡包不超过1000张卡片
+		cardsResponse, _ := h.cardService.GetCardsByDeckID(deck.ID, "", models.CardPageRequest{Page: 1, PageSize: 1000}) // 假设每个卡包不超过1000张卡片
 		if cardsResponse != nil {
 			totalCards += cardsResponse.Total
 		}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/internal/services"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // TagHandler 标签处理器
@@ -24,8 +26,9 @@ func NewTagHandler() *TagHandler {
 // CreateTag 创建标签
 func (h *TagHandler) CreateTag(c *gin.Context) {
 	var req struct {
-		Name   string `json:"name" binding:"required,min=1,max=50"`
-		DeckID *uint  `json:"deck_id,omitempty"`
+		Name     string `json:"name" binding:"required,min=1,max=50"`
+		DeckID   *uint  `json:"deck_id,omitempty"`
+		ParentID *uint  `json:"parent_id,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -33,9 +36,9 @@ func (h *TagHandler) CreateTag(c *gin.Context) {
 		return
 	}
 
-	tag, err := h.tagService.CreateTag(req.DeckID, req.Name)
+	tag, err := h.tagService.CreateTag(req.DeckID, req.ParentID, req.Name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "创建标签失败", err.Error()))
+		respondTagError(c, err, "创建标签失败")
 		return
 	}
 
@@ -101,25 +104,95 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 		return
 	}
 
+	var req models.UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "标签名称长度应在1-50之间，描述、图标和元数据不能过长"))
+		return
+	}
+
+	if req.Name == nil && req.DetailsUpdate.IsEmpty() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "至少需要提供一个更新字段"))
+		return
+	}
+
+	tag, err := h.tagService.UpdateTagWithDeck(uint(id), req)
+	if err != nil {
+		respondTagError(c, err, "更新标签失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(tag))
+}
+
+// MoveTag 将标签及其子标签移动到新的上级标签下
+func (h *TagHandler) MoveTag(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的标签ID"))
+		return
+	}
+
 	var req struct {
-		Name   string `json:"name" binding:"required,min=1,max=50"`
-		DeckID *uint  `json:"deck_id,omitempty"`
+		ParentID *uint `json:"parent_id"` // 为空表示移动到顶层
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "标签名称不能为空且长度应在1-50之间"))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "请求参数格式错误"))
 		return
 	}
 
-	tag, err := h.tagService.UpdateTagWithDeck(uint(id), req.Name, req.DeckID)
+	tag, err := h.tagService.MoveTag(uint(id), req.ParentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "更新标签失败", err.Error()))
+		respondTagError(c, err, "移动标签失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(tag))
 }
 
+// RenameTagPath 按完整路径重命名标签
+func (h *TagHandler) RenameTagPath(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的标签ID"))
+		return
+	}
+
+	var req struct {
+		Path string `json:"path" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "标签路径不能为空"))
+		return
+	}
+
+	tag, err := h.tagService.RenameTagPath(uint(id), req.Path)
+	if err != nil {
+		respondTagError(c, err, "重命名标签失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(tag))
+}
+
+// respondTagError 根据标签服务返回的错误写入响应
+func respondTagError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, "标签不存在"))
+	case errors.Is(err, services.ErrTagPathConflict):
+		c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error()))
+	case errors.Is(err, services.ErrInvalidTagName), errors.Is(err, services.ErrInvalidTagParent),
+		errors.Is(err, services.ErrTagCycle), errors.Is(err, services.ErrInvalidColor):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, message, err.Error()))
+	}
+}
+
 // DeleteTag 删除标签
 func (h *TagHandler) DeleteTag(c *gin.Context) {
 	idStr := c.Param("id")
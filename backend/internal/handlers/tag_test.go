@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"flashcard/internal/models"
+	"flashcard/internal/services"
 )
 
 // TestListTags 测试获取标签列表
@@ -22,8 +23,8 @@ func TestListTags(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag1 := models.Tag{DeckID: &deck.ID, Name: "测试标签1"}
-	tag2 := models.Tag{DeckID: &deck.ID, Name: "测试标签2"}
+	tag1 := models.Tag{DeckID: &deck.ID, Name: "测试标签1", Path: "测试标签1"}
+	tag2 := models.Tag{DeckID: &deck.ID, Name: "测试标签2", Path: "测试标签2"}
 	db.Create(&tag1)
 	db.Create(&tag2)
 
@@ -111,7 +112,7 @@ func TestGetTag(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	w := httptest.NewRecorder()
@@ -143,7 +144,7 @@ func TestUpdateTag(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	updateData := map[string]string{
@@ -181,7 +182,7 @@ func TestDeleteTag(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	w := httptest.NewRecorder()
@@ -213,12 +214,12 @@ func TestGetTagStats(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签"}
+	tag := models.Tag{DeckID: &deck.ID, Name: "测试标签", Path: "测试标签"}
 	db.Create(&tag)
 
 	// 创建卡片
-	card1 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "问题1", Answer: "答案1"}
-	card2 := models.Card{DeckID: deck.ID, TagID: &tag.ID, Question: "问题2", Answer: "答案2"}
+	card1 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题1", Answer: "答案1"}
+	card2 := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题2", Answer: "答案2"}
 	db.Create(&card1)
 	db.Create(&card2)
 
@@ -251,8 +252,8 @@ func TestGetTagsByDeck(t *testing.T) {
 	deck := models.Deck{Name: "测试卡包"}
 	db.Create(&deck)
 
-	tag1 := models.Tag{DeckID: &deck.ID, Name: "测试标签1"}
-	tag2 := models.Tag{DeckID: &deck.ID, Name: "测试标签2"}
+	tag1 := models.Tag{DeckID: &deck.ID, Name: "测试标签1", Path: "测试标签1"}
+	tag2 := models.Tag{DeckID: &deck.ID, Name: "测试标签2", Path: "测试标签2"}
 	db.Create(&tag1)
 	db.Create(&tag2)
 
@@ -274,4 +275,64 @@ func TestGetTagsByDeck(t *testing.T) {
 	data := response["data"].([]interface{})
 	tags := data // 整个data就是标签列表
 	assert.Equal(t, 2, len(tags))
-}
\ No newline at end of file
+}
+
+// TestListTagsWithStats 测试带统计信息的标签列表与单个标签统计一致
+func TestListTagsWithStats(t *testing.T) {
+	db := setupTestDB()
+	seedStatsData(db, 2, 3, 4)
+
+	var deck models.Deck
+	db.First(&deck)
+
+	tagService := services.NewTagService()
+	tags, err := tagService.GetTagsByDeckIDWithStats(deck.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(tags))
+
+	allTags, err := tagService.GetAllTagsWithStats()
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(allTags))
+
+	for _, tag := range allTags {
+		stats, err := tagService.GetTagStats(tag.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, *stats, tag.Stats)
+		assert.Equal(t, 4, tag.Stats.TotalCards)
+		assert.Equal(t, 2, tag.Stats.DueCards)
+	}
+}
+
+// BenchmarkGetAllTagsWithStats 分组聚合查询获取标签统计
+func BenchmarkGetAllTagsWithStats(b *testing.B) {
+	db := setupTestDB()
+	seedStatsData(db, 20, 50, 5)
+	tagService := services.NewTagService()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tagService.GetAllTagsWithStats(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetTagStatsPerTag 逐个标签查询统计（对照组）
+func BenchmarkGetTagStatsPerTag(b *testing.B) {
+	db := setupTestDB()
+	seedStatsData(db, 20, 50, 5)
+	tagService := services.NewTagService()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tags, err := tagService.GetAllTags()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, tag := range tags {
+			if _, err := tagService.GetTagStats(tag.ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// createChildTag 通过接口创建标签，parentID为0时创建顶层标签
+func createChildTag(t *testing.T, router http.Handler, deckID, parentID uint, name string) models.Tag {
+	body := map[string]interface{}{"name": name, "deck_id": deckID}
+	if parentID != 0 {
+		body["parent_id"] = parentID
+	}
+	var tag models.Tag
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deckID), body, &tag)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return tag
+}
+
+// TestTagHierarchy 测试创建层级标签，按上级标签搜索、学习和统计时包含子标签的卡片
+func TestTagHierarchy(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	grammar := createChildTag(t, router, deck.ID, 0, "Grammar")
+	verbs := createChildTag(t, router, deck.ID, grammar.ID, "Verbs")
+	irregular := createChildTag(t, router, deck.ID, verbs.ID, "Irregular")
+	assert.Equal(t, "Grammar::Verbs::Irregular", irregular.Path)
+	assert.Equal(t, verbs.ID, *irregular.ParentID)
+
+	// 同一上级标签下不能重名，标签名不能包含分隔符
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID),
+		map[string]interface{}{"name": "Verbs", "deck_id": deck.ID, "parent_id": grammar.ID}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID),
+		map[string]interface{}{"name": "A::B", "deck_id": deck.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	createChildTag(t, router, deck.ID, 0, "Verbs")
+
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{grammar, verbs}, Question: "语法", Answer: "答案"})
+	db.Create(&models.Card{DeckID: deck.ID, Tags: []models.Tag{irregular}, Question: "不规则动词", Answer: "答案"})
+	db.Create(&models.Card{DeckID: deck.ID, Question: "没有标签", Answer: "答案"})
+
+	var cards struct {
+		Data models.CardListResponse `json:"data"`
+	}
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/cards?tag_ids=%d&page=1&page_size=20", grammar.ID), nil)
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &cards)
+	assert.Equal(t, int64(2), cards.Data.Total)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/tags/%d/cards", verbs.ID), nil)
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &cards)
+	assert.Equal(t, int64(2), cards.Data.Total)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/study/tag/%d", grammar.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var session struct {
+		Data models.StudySession `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &session)
+	assert.Equal(t, 2, session.Data.Total)
+
+	// 同时带有上级和子标签的卡片只统计一次
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/tags/deck/%d?include_stats=true", deck.ID), nil)
+	router.ServeHTTP(w, req)
+	var stats struct {
+		Data []models.TagWithStats `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	totals := make(map[string]int)
+	for _, tag := range stats.Data {
+		totals[tag.Path] = tag.Stats.TotalCards
+	}
+	assert.Equal(t, 2, totals["Grammar"])
+	assert.Equal(t, 2, totals["Grammar::Verbs"])
+	assert.Equal(t, 1, totals["Grammar::Verbs::Irregular"])
+	assert.Equal(t, 0, totals["Verbs"])
+}
+
+// TestMoveAndRenameTag 测试移动标签子树和按路径重命名
+func TestMoveAndRenameTag(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	grammar := createChildTag(t, router, deck.ID, 0, "Grammar")
+	verbs := createChildTag(t, router, deck.ID, grammar.ID, "Verbs")
+	irregular := createChildTag(t, router, deck.ID, verbs.ID, "Irregular")
+	vocabulary := createChildTag(t, router, deck.ID, 0, "Vocabulary")
+
+	// 不能移动到自身的子标签下
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/move", grammar.ID), map[string]interface{}{"parent_id": irregular.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var moved models.Tag
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/move", verbs.ID), map[string]interface{}{"parent_id": vocabulary.ID}, &moved)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Vocabulary::Verbs", moved.Path)
+
+	var child models.Tag
+	db.First(&child, irregular.ID)
+	assert.Equal(t, "Vocabulary::Verbs::Irregular", child.Path)
+
+	// 移动到顶层
+	moved = models.Tag{}
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/move", irregular.ID), map[string]interface{}{"parent_id": nil}, &moved)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Irregular", moved.Path)
+	assert.Nil(t, moved.ParentID)
+
+	// 按路径重命名时自动创建不存在的上级标签，子标签随之更新
+	sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/move", irregular.ID), map[string]interface{}{"parent_id": verbs.ID}, nil)
+	var renamed models.Tag
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/rename", verbs.ID), map[string]interface{}{"path": "Language :: Verb Forms"}, &renamed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Verb Forms", renamed.Name)
+	assert.Equal(t, "Language::Verb Forms", renamed.Path)
+
+	var language models.Tag
+	assert.NoError(t, db.Where("deck_id = ? AND path = ?", deck.ID, "Language").First(&language).Error)
+	assert.Equal(t, language.ID, *renamed.ParentID)
+	db.First(&child, irregular.ID)
+	assert.Equal(t, "Language::Verb Forms::Irregular", child.Path)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/rename", verbs.ID), map[string]interface{}{"path": "Language::Verb Forms::Irregular::X"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/rename", verbs.ID), map[string]interface{}{"path": "Grammar"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/%d/rename", verbs.ID), map[string]interface{}{"path": "A::::B"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestDeleteAndRestoreTagTree 测试删除上级标签时子标签一起进入回收站并一起恢复
+func TestDeleteAndRestoreTagTree(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	grammar := createChildTag(t, router, deck.ID, 0, "Grammar")
+	verbs := createChildTag(t, router, deck.ID, grammar.ID, "Verbs")
+	card := models.Card{DeckID: deck.ID, Tags: []models.Tag{verbs}, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/tags/%d?delete_cards=true", grammar.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	db.Model(&models.Tag{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.Card{}).Where("id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	items := listTrash(t, router, "")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "Grammar", items[0].Name)
+
+	// 子标签不能单独恢复
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/tag/%d/restore", verbs.ID), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/tag/%d/restore", grammar.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Tag{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Model(&models.Card{}).Where("id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// 彻底删除时子标签一起删除
+	sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/tags/%d", grammar.ID), nil, nil)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/trash/tag/%d", grammar.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Unscoped().Model(&models.Tag{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Table("card_tags").Where("card_id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// TestImportTagPaths 测试导入时按::分隔的标签名创建层级标签
+func TestImportTagPaths(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	csvData := "ID,Question,Answer,Tag\n1,go,went,Grammar::Verbs::Irregular\n2,run,ran,Grammar::Verbs;Vocabulary\n"
+	deckID := importDeckFile(t, router, "deck.csv", "层级标签", []byte(csvData))
+
+	var tags []models.Tag
+	db.Where("deck_id = ?", deckID).Order("path").Find(&tags)
+	paths := make(map[string]models.Tag)
+	for _, tag := range tags {
+		paths[tag.Path] = tag
+	}
+	assert.Equal(t, 4, len(tags))
+	assert.Equal(t, paths["Grammar"].ID, *paths["Grammar::Verbs"].ParentID)
+	assert.Equal(t, paths["Grammar::Verbs"].ID, *paths["Grammar::Verbs::Irregular"].ParentID)
+	assert.Nil(t, paths["Vocabulary"].ParentID)
+
+	// 导出时使用完整路径
+	content := exportDeckFile(t, router, deckID, "txt")
+	assert.Contains(t, string(content), "# Grammar::Verbs::Irregular\n")
+}
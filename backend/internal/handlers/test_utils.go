@@ -1,6 +1,14 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
 	"github.com/gin-gonic/gin"
@@ -14,24 +22,45 @@ var testDB *gorm.DB
 func setupTestDB() *gorm.DB {
 	if testDB != nil {
 		// 清理数据库
+		testDB.Exec("DELETE FROM card_revisions")
+		testDB.Exec("DELETE FROM review_logs")
+		testDB.Exec("DELETE FROM reviews")
+		testDB.Exec("DELETE FROM card_options")
+		testDB.Exec("DELETE FROM card_media")
+		testDB.Exec("DELETE FROM card_tags")
+		testDB.Exec("DELETE FROM note_tags")
+		testDB.Exec("DELETE FROM media")
 		testDB.Exec("DELETE FROM cards")
+		testDB.Exec("DELETE FROM notes")
+		testDB.Exec("DELETE FROM note_types")
+		testDB.Exec("DELETE FROM saved_searches")
 		testDB.Exec("DELETE FROM tags")
 		testDB.Exec("DELETE FROM decks")
 		return testDB
 	}
 
 	var err error
-	testDB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	testDB, err = gorm.Open(sqlite.Dialector{DriverName: database.DriverName, DSN: "file::memory:?cache=shared"}, &gorm.Config{})
 	if err != nil {
 		panic("failed to connect database")
 	}
 
 	// 自动迁移
-	err = testDB.AutoMigrate(&models.Deck{}, &models.Tag{}, &models.Card{}, &models.Review{})
+	err = testDB.AutoMigrate(&models.Deck{}, &models.Tag{}, &models.NoteType{}, &models.Note{}, &models.Media{}, &models.Card{}, &models.CardOption{}, &models.Review{}, &models.ReviewLog{}, &models.CardRevision{}, &models.SavedSearch{})
 	if err != nil {
 		panic("failed to migrate database")
 	}
 
+	// 创建名称唯一索引等自定义索引
+	if err := database.CreateIndexes(testDB); err != nil {
+		panic("failed to create indexes")
+	}
+
+	// 创建卡片全文索引，未使用 sqlite_fts5 构建标签时退回LIKE查询
+	if err := database.SetupFullTextSearch(testDB); err != nil {
+		panic("failed to setup full-text search")
+	}
+
 	// 临时替换全局数据库连接
 	database.DB = testDB
 	
@@ -39,6 +68,26 @@ func setupTestDB() *gorm.DB {
 	return testDB
 }
 
+// sendJSON 发送JSON请求并返回响应，body为nil时不携带请求体，data不为nil时将响应的data解析到data中
+func sendJSON(router http.Handler, method, url string, body interface{}, data interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(jsonData)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, reader)
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if data != nil {
+		response := struct {
+			Data interface{} `json:"data"`
+		}{Data: data}
+		json.Unmarshal(w.Body.Bytes(), &response)
+	}
+	return w
+}
+
 // setupRouter 设置测试路由
 func setupRouter(_ *gorm.DB) *gin.Engine {
 	gin.SetMode(gin.TestMode)
@@ -49,6 +98,14 @@ func setupRouter(_ *gorm.DB) *gin.Engine {
 	tagHandler := NewTagHandler()
 	cardHandler := NewCardHandler()
 	importExportHandler := NewImportExportHandler()
+	studyHandler := NewStudyHandler()
+	analyticsHandler := NewAnalyticsHandler()
+	noteHandler := NewNoteHandler()
+	noteTypeHandler := NewNoteTypeHandler()
+	savedSearchHandler := NewSavedSearchHandler()
+	mediaHandler := NewMediaHandler()
+	systemHandler := NewSystemHandler()
+	trashHandler := NewTrashHandler()
 
 	// 注册路由
 	api := r.Group("/api/v1")
@@ -58,10 +115,18 @@ func setupRouter(_ *gorm.DB) *gin.Engine {
 		{
 			decks.GET("", deckHandler.GetDecks)
 			decks.POST("", deckHandler.CreateDeck)
+			decks.GET("/tree", deckHandler.GetDeckTree)
 			decks.GET("/:id", deckHandler.GetDeck)
 			decks.PATCH("/:id", deckHandler.UpdateDeck)
 			decks.DELETE("/:id", deckHandler.DeleteDeck)
+			decks.POST("/:id/move", deckHandler.MoveDeck)
+			decks.POST("/:id/clone", deckHandler.CloneDeck)
+			decks.POST("/:id/merge", deckHandler.MergeDeck)
 			decks.GET("/:id/stats", deckHandler.GetDeckStats)
+			decks.GET("/:id/duplicates", deckHandler.GetDeckDuplicates)
+			decks.GET("/:id/cards", cardHandler.GetCardsByDeck)
+			decks.POST("/:id/cards/reorder", cardHandler.ReorderCards)
+			decks.GET("/:id/analytics", analyticsHandler.GetDeckAnalytics)
 		}
 
 		// 标签路由
@@ -71,9 +136,12 @@ func setupRouter(_ *gorm.DB) *gin.Engine {
 			tags.POST("/deck/:deckId", tagHandler.CreateTag)         // 创建标签
 			tags.GET("/:id", tagHandler.GetTag)         // 获取单个标签
 			tags.PATCH("/:id", tagHandler.UpdateTag)    // 更新标签
+			tags.POST("/:id/move", tagHandler.MoveTag) // 移动标签及其子标签
+			tags.POST("/:id/rename", tagHandler.RenameTagPath) // 按完整路径重命名标签
 			tags.DELETE("/:id", tagHandler.DeleteTag)   // 删除标签
 			tags.GET("/:id/stats", tagHandler.GetTagStats) // 获取标签统计
 			tags.GET("/:id/cards", cardHandler.GetCardsByTag)      // 获取标签下的所有卡片
+			tags.GET("/:id/analytics", analyticsHandler.GetTagAnalytics) // 获取标签记忆分析
 		}
 
 		// 卡片路由
@@ -81,9 +149,63 @@ func setupRouter(_ *gorm.DB) *gin.Engine {
 		{
 			cards.GET("", cardHandler.SearchCards)
 			cards.POST("", cardHandler.CreateCard)
+			cards.POST("/bulk", cardHandler.BulkUpdateCards)
+			cards.POST("/replace", cardHandler.ReplaceCards)
 			cards.GET("/:id", cardHandler.GetCard)
 			cards.PATCH("/:id", cardHandler.UpdateCard)
 			cards.DELETE("/:id", cardHandler.DeleteCard)
+			cards.PUT("/:id/media", mediaHandler.SetCardMedia)
+			cards.PUT("/:id/flag", cardHandler.SetCardFlag)
+			cards.GET("/:id/revisions", cardHandler.GetCardRevisions)
+			cards.GET("/:id/revisions/diff", cardHandler.DiffCardRevisions)
+			cards.POST("/:id/revisions/:revisionId/revert", cardHandler.RevertCard)
+		}
+
+		// 媒体路由
+		media := api.Group("/media")
+		{
+			media.POST("", mediaHandler.UploadMedia)
+			media.GET("/:id", mediaHandler.GetMedia)
+			media.POST("/gc", mediaHandler.CollectGarbage)
+		}
+
+		// 笔记路由
+		notes := api.Group("/notes")
+		{
+			notes.POST("", noteHandler.CreateNote)
+			notes.GET("/:id", noteHandler.GetNote)
+			notes.PATCH("/:id", noteHandler.UpdateNote)
+			notes.DELETE("/:id", noteHandler.DeleteNote)
+		}
+
+		// 笔记类型路由
+		noteTypes := api.Group("/note-types")
+		{
+			noteTypes.GET("", noteTypeHandler.GetNoteTypes)
+			noteTypes.POST("", noteTypeHandler.CreateNoteType)
+			noteTypes.GET("/:id", noteTypeHandler.GetNoteType)
+			noteTypes.PATCH("/:id", noteTypeHandler.UpdateNoteType)
+			noteTypes.DELETE("/:id", noteTypeHandler.DeleteNoteType)
+		}
+
+		// 保存搜索路由
+		savedSearches := api.Group("/saved-searches")
+		{
+			savedSearches.GET("", savedSearchHandler.GetSavedSearches)
+			savedSearches.POST("", savedSearchHandler.CreateSavedSearch)
+			savedSearches.GET("/:id", savedSearchHandler.GetSavedSearch)
+			savedSearches.PATCH("/:id", savedSearchHandler.UpdateSavedSearch)
+			savedSearches.DELETE("/:id", savedSearchHandler.DeleteSavedSearch)
+			savedSearches.GET("/:id/cards", savedSearchHandler.GetSavedSearchCards)
+		}
+
+		// 回收站路由
+		trash := api.Group("/trash")
+		{
+			trash.GET("", trashHandler.ListTrash)
+			trash.DELETE("", trashHandler.EmptyTrash)
+			trash.POST("/:type/:id/restore", trashHandler.RestoreItem)
+			trash.DELETE("/:type/:id", trashHandler.PurgeItem)
 		}
 
 		// 导入导出路由
@@ -92,7 +214,50 @@ func setupRouter(_ *gorm.DB) *gin.Engine {
 			importExport.POST("/decks", importExportHandler.ImportDeck)
 			importExport.GET("/decks/:deckId", importExportHandler.ExportDeck)
 		}
+
+		// 学习路由
+		study := api.Group("/study")
+		{
+			study.POST("/deck/:deckId", studyHandler.StartDeckStudy)
+			study.POST("/tag/:tagId", studyHandler.StartTagStudy)
+			study.POST("/filtered", studyHandler.StartFilteredStudy)
+			study.GET("/due", studyHandler.GetDueCards)
+			study.POST("/review/:cardId", studyHandler.SubmitReview)
+		}
+
+		// 系统路由
+		system := api.Group("/system")
+		{
+			system.GET("/analytics", analyticsHandler.GetCollectionAnalytics)
+			system.GET("/backup", systemHandler.BackupData)
+			system.POST("/restore", systemHandler.RestoreData)
+		}
 	}
 
 	return r
-}
\ No newline at end of file
+}
+
+// seedStatsData 批量生成统计测试数据：每个卡包若干标签，每个标签若干卡片，半数卡片带复习记录
+func seedStatsData(db *gorm.DB, deckCount, tagsPerDeck, cardsPerTag int) {
+	for d := 0; d < deckCount; d++ {
+		deck := models.Deck{Name: fmt.Sprintf("卡包%d", d)}
+		db.Create(&deck)
+
+		for t := 0; t < tagsPerDeck; t++ {
+			tag := models.Tag{DeckID: &deck.ID, Name: fmt.Sprintf("标签%d", t), Path: fmt.Sprintf("标签%d", t)}
+			db.Create(&tag)
+
+			cards := make([]models.Card, cardsPerTag)
+			for i := range cards {
+				cards[i] = models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: fmt.Sprintf("问题%d-%d-%d", d, t, i), Answer: "答案"}
+			}
+			db.CreateInBatches(&cards, 100)
+
+			var reviews []models.Review
+			for i := 0; i < len(cards); i += 2 {
+				reviews = append(reviews, models.Review{CardID: cards[i].ID, EFactor: 2.5, Interval: 3, NextReview: time.Now().AddDate(0, 0, 3)})
+			}
+			db.CreateInBatches(&reviews, 100)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler 回收站处理器
+type TrashHandler struct {
+	trashService *services.TrashService
+}
+
+// NewTrashHandler 创建回收站处理器实例
+func NewTrashHandler() *TrashHandler {
+	return &TrashHandler{
+		trashService: services.NewTrashService(),
+	}
+}
+
+// ListTrash 获取回收站中的项目
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	itemType := c.Query("type")
+	if itemType != "" && !isTrashType(itemType) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrInvalidTrashType.Error()))
+		return
+	}
+
+	items, err := h.trashService.ListTrash(itemType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "获取回收站失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(items))
+}
+
+// RestoreItem 恢复回收站中的项目
+func (h *TrashHandler) RestoreItem(c *gin.Context) {
+	itemType, id, ok := parseTrashItem(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Restore(itemType, id); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotInTrash):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, err.Error()))
+		case errors.Is(err, services.ErrTrashNameConflict):
+			c.JSON(http.StatusConflict, models.ErrorResponse(models.CodeConflict, err.Error()))
+		case errors.Is(err, services.ErrTrashParentDeleted):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "恢复失败", err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// PurgeItem 彻底删除回收站中的项目
+func (h *TrashHandler) PurgeItem(c *gin.Context) {
+	itemType, id, ok := parseTrashItem(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Purge(itemType, id); err != nil {
+		if errors.Is(err, services.ErrNotInTrash) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(models.CodeNotFound, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "彻底删除失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// EmptyTrash 清空回收站
+func (h *TrashHandler) EmptyTrash(c *gin.Context) {
+	result, err := h.trashService.PurgeExpired(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(models.CodeInternal, "清空回收站失败", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
+// parseTrashItem 解析回收站项目的类型和ID，失败时写入错误响应
+func parseTrashItem(c *gin.Context) (string, uint, bool) {
+	itemType := c.Param("type")
+	if !isTrashType(itemType) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, services.ErrInvalidTrashType.Error()))
+		return "", 0, false
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(models.CodeInvalidParam, "无效的ID"))
+		return "", 0, false
+	}
+
+	return itemType, uint(id), true
+}
+
+// isTrashType 判断是否为回收站支持的项目类型
+func isTrashType(itemType string) bool {
+	return itemType == models.TrashTypeDeck || itemType == models.TrashTypeTag || itemType == models.TrashTypeCard
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// listTrash 通过接口获取回收站中的项目
+func listTrash(t *testing.T, router http.Handler, query string) []models.TrashItem {
+	w := sendJSON(router, "GET", "/api/v1/trash"+query, nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.TrashItem `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	return response.Data
+}
+
+// TestDeleteAndRestoreDeck 测试删除卡包后连同标签、卡片和复习记录一起恢复
+func TestDeleteAndRestoreDeck(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	tag := models.Tag{DeckID: &deck.ID, Name: "标签", Path: "标签"}
+	db.Create(&tag)
+	card := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+	db.Create(&models.Review{CardID: card.ID, EFactor: 2.5, Interval: 6, NextReview: time.Now()})
+
+	// 提前单独删除的卡片不随卡包恢复
+	deletedCard := models.Card{DeckID: deck.ID, Question: "已删除", Answer: "答案"}
+	db.Create(&deletedCard)
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", deletedCard.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	time.Sleep(10 * time.Millisecond)
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	db.Model(&models.Card{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 回收站只列出卡包，子项目随卡包处理
+	items := listTrash(t, router, "")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, models.TrashTypeDeck, items[0].Type)
+	assert.Equal(t, "测试卡包", items[0].Name)
+	assert.NotNil(t, items[0].PurgeAt)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.Model(&models.Tag{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	var restored models.Card
+	err := db.Preload("Review").First(&restored, card.ID).Error
+	assert.NoError(t, err)
+	assert.NotNil(t, restored.Review)
+	assert.Equal(t, 6, restored.Review.Interval)
+
+	// 单独删除的卡片仍在回收站中
+	items = listTrash(t, router, "?type=card")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, deletedCard.ID, items[0].ID)
+
+	// 恢复不在回收站中的项目
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = sendJSON(router, "POST", "/api/v1/trash/unknown/1/restore", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestRestoreDeckNameConflict 测试删除的卡包不占用名称，同名卡包存在时无法恢复
+func TestRestoreDeckNameConflict(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "重名卡包"}
+	db.Create(&deck)
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	newDeck := models.Deck{Name: "重名卡包"}
+	assert.NoError(t, db.Create(&newDeck).Error)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestRestoreTagAndCard 测试恢复标签和卡片
+func TestRestoreTagAndCard(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	tag := models.Tag{DeckID: &deck.ID, Name: "标签", Path: "标签"}
+	db.Create(&tag)
+	card := models.Card{DeckID: deck.ID, Tags: []models.Tag{tag}, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/tags/%d?delete_cards=true", tag.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	items := listTrash(t, router, "")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, models.TrashTypeTag, items[0].Type)
+
+	// 所属标签已删除的卡片不能单独恢复
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/card/%d/restore", card.ID), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/tag/%d/restore", tag.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var restored models.Card
+	assert.NoError(t, db.Preload("Tags").First(&restored, card.ID).Error)
+	assert.Equal(t, 1, len(restored.Tags))
+	assert.Equal(t, tag.ID, restored.Tags[0].ID)
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/card/%d/restore", card.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, db.First(&restored, card.ID).Error)
+}
+
+// TestPurgeTrash 测试彻底删除和清空回收站
+func TestPurgeTrash(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "测试卡包"}
+	db.Create(&deck)
+	card := models.Card{DeckID: deck.ID, Question: "问题", Answer: "答案"}
+	db.Create(&card)
+	db.Create(&models.Review{CardID: card.ID, EFactor: 2.5, NextReview: time.Now()})
+	db.Create(&models.ReviewLog{CardID: card.ID, Result: models.Good, ReviewedAt: time.Now()})
+	otherCard := models.Card{DeckID: deck.ID, Question: "另一个问题", Answer: "答案"}
+	db.Create(&otherCard)
+
+	// 未删除的卡片不能彻底删除
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/trash/card/%d", card.ID), nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/cards/%d", card.ID), nil, nil)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/trash/card/%d", card.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	db.Unscoped().Model(&models.Card{}).Where("id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.Review{}).Where("card_id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.ReviewLog{}).Where("card_id = ?", card.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 清空回收站时卡包下的卡片一起彻底删除
+	sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d", deck.ID), nil, nil)
+	w = sendJSON(router, "DELETE", "/api/v1/trash", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.TrashPurgeResult `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response.Data.Decks)
+	assert.Equal(t, 0, response.Data.Cards)
+
+	db.Unscoped().Model(&models.Card{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&models.Deck{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Empty(t, listTrash(t, router, ""))
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flashcard/internal/models"
+)
+
+// TestReuseNamesAfterDelete 测试名称唯一索引：未删除的卡包、标签和笔记类型不能重名，删除后可以重新使用名称
+func TestReuseNamesAfterDelete(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	var deck models.Deck
+	w := sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "日语"}, &deck)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "日语"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var other models.Deck
+	sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "韩语"}, &other)
+	w = sendJSON(router, "PATCH", fmt.Sprintf("/api/v1/decks/%d", other.ID), map[string]interface{}{"name": "日语"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	// 绕过接口直接插入时由索引拒绝
+	assert.Error(t, db.Create(&models.Deck{Name: "日语"}).Error)
+
+	var tag models.Tag
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID), map[string]interface{}{"name": "动词", "deck_id": deck.ID}, &tag)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID), map[string]interface{}{"name": "动词", "deck_id": deck.ID}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/tags/%d", tag.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID), map[string]interface{}{"name": "动词", "deck_id": deck.ID}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/decks/%d", deck.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/decks", map[string]interface{}{"name": "日语"}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	noteType := createVocabularyType(t, router)
+	w = sendJSON(router, "POST", "/api/v1/note-types", map[string]interface{}{
+		"name": noteType.Name, "fields": []string{"Word"}, "templates": []map[string]string{{"name": "认读", "front": "{{Word}}", "back": "{{Word}}"}},
+	}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/note-types/%d", noteType.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	createVocabularyType(t, router)
+
+	w = sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{"name": "全部"}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(router, "POST", "/api/v1/saved-searches", map[string]interface{}{"name": "全部"}, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestRestoreTagNameConflict 测试同一卡包中已有相同路径的标签时无法恢复标签
+func TestRestoreTagNameConflict(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	deck := models.Deck{Name: "日语"}
+	db.Create(&deck)
+	verbs := createDeckTag(db, deck.ID, nil, "动词")
+	irregular := createDeckTag(db, deck.ID, &verbs, "不规则")
+
+	w := sendJSON(router, "DELETE", fmt.Sprintf("/api/v1/tags/%d", irregular.ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/tags/deck/%d", deck.ID), map[string]interface{}{"name": "不规则", "deck_id": deck.ID, "parent_id": verbs.ID}, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/tag/%d/restore", irregular.ID), nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var count int64
+	db.Model(&models.Tag{}).Where("deck_id = ? AND path = ?", deck.ID, "动词::不规则").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestMergeDecksSharingTags 测试依次合并多个有相同路径标签的卡包，以及恢复合并后删除的源卡包
+func TestMergeDecksSharingTags(t *testing.T) {
+	db := setupTestDB()
+	router := setupRouter(db)
+
+	target := models.Deck{Name: "日语"}
+	db.Create(&target)
+	var sources []models.Deck
+	for _, name := range []string{"N5", "N4"} {
+		source := models.Deck{Name: name}
+		db.Create(&source)
+		verbs := createDeckTag(db, source.ID, nil, "动词")
+		createDeckTag(db, source.ID, &verbs, "不规则")
+		sources = append(sources, source)
+	}
+
+	// 第一次合并移动标签，第二次合并到已移动的同路径标签
+	var result models.DeckMergeResult
+	w := sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", sources[0].ID), map[string]interface{}{"target_id": target.ID}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.MovedTags)
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/decks/%d/merge", sources[1].ID), map[string]interface{}{"target_id": target.ID}, &result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.MergedTags)
+
+	var paths []string
+	db.Model(&models.Tag{}).Where("deck_id = ?", target.ID).Order("path").Pluck("path", &paths)
+	assert.Equal(t, []string{"动词", "动词::不规则"}, paths)
+
+	// 源卡包的名称已释放，恢复时连同合并时删除的标签一起恢复
+	w = sendJSON(router, "POST", fmt.Sprintf("/api/v1/trash/deck/%d/restore", sources[1].ID), nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Tag{}).Where("deck_id = ?", sources[1].ID).Order("path").Pluck("path", &paths)
+	assert.Equal(t, []string{"动词", "动词::不规则"}, paths)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// RegisterCardGauges 注册卡片总数和待复习卡片数指标，在每次抓取时查询数据库
+func RegisterCardGauges(db *gorm.DB) error {
+	totalCards := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cards",
+		Help:      "卡片总数",
+	}, func() float64 {
+		var count int64
+		if err := db.Table("cards").Where("deleted_at IS NULL").Count(&count).Error; err != nil {
+			log.Printf("metrics: 统计卡片总数失败: %v", err)
+		}
+		return float64(count)
+	})
+
+	dueCards := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cards_due",
+		Help:      "当前待复习的卡片数（包括新卡片）",
+	}, func() float64 {
+		var count int64
+		if err := db.Table("cards").
+			Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
+			Where("cards.deleted_at IS NULL AND cards.suspended = ?", false).
+			Where("reviews.next_review <= ? OR reviews.id IS NULL", time.Now()).
+			Count(&count).Error; err != nil {
+			log.Printf("metrics: 统计待复习卡片数失败: %v", err)
+		}
+		return float64(count)
+	})
+
+	if err := prometheus.Register(totalCards); err != nil {
+		return err
+	}
+	return prometheus.Register(dueCards)
+}
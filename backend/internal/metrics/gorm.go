@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// RegisterDBCallbacks 注册GORM回调，记录每类数据库操作的耗时
+func RegisterDBCallbacks(db *gorm.DB) error {
+	callback := db.Callback()
+	errs := []error{
+		callback.Create().Before("gorm:create").Register("metrics:before_create", startTimer),
+		callback.Create().After("gorm:create").Register("metrics:after_create", observer("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", startTimer),
+		callback.Query().After("gorm:query").Register("metrics:after_query", observer("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", startTimer),
+		callback.Update().After("gorm:update").Register("metrics:after_update", observer("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", startTimer),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", observer("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", startTimer),
+		callback.Row().After("gorm:row").Register("metrics:after_row", observer("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", startTimer),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", observer("raw")),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startTimer 记录操作开始时间
+func startTimer(tx *gorm.DB) {
+	tx.InstanceSet(startTimeKey, time.Now())
+}
+
+// observer 返回记录指定操作耗时的回调
+func observer(operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		observeDuration(tx, operation)
+	}
+}
+
+// observeDuration 记录操作耗时
+func observeDuration(tx *gorm.DB, operation string) {
+	value, ok := tx.InstanceGet(startTimeKey)
+	if !ok {
+		return
+	}
+	start, ok := value.(time.Time)
+	if !ok {
+		log.Printf("metrics: 无效的开始时间: %v", value)
+		return
+	}
+
+	table := tx.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+
+	DBQueryDuration.With(prometheus.Labels{
+		"operation": operation,
+		"table":     table,
+	}).Observe(time.Since(start).Seconds())
+}
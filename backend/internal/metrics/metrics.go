@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "flashcard"
+
+var (
+	// HTTPRequestsTotal HTTP请求计数（按方法、路由、状态码）
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求总数",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration HTTP请求耗时（按方法、路由）
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时（秒）",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// DBQueryDuration 数据库操作耗时（按操作类型、表名）
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "数据库操作耗时（秒）",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	// ReviewsSubmitted 提交的复习结果计数（按评分）
+	ReviewsSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_submitted_total",
+		Help:      "提交的复习结果总数",
+	}, []string{"result"})
+
+	// ImportsTotal 导入次数（按格式、结果）
+	ImportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_total",
+		Help:      "卡包导入总次数",
+	}, []string{"format", "status"})
+
+	// ExportsTotal 导出次数（按格式、结果）
+	ExportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exports_total",
+		Help:      "卡包导出总次数",
+	}, []string{"format", "status"})
+)
+
+// Status 根据错误返回结果标签值
+func Status(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -0,0 +1,55 @@
+package models
+
+// 卡片成熟度划分：间隔达到该天数即视为成熟卡片
+const MatureInterval = 21
+
+// RetentionRate 记忆保持率
+type RetentionRate struct {
+	Passed int     `json:"passed"`
+	Failed int     `json:"failed"`
+	Rate   float64 `json:"rate"` // 0-1之间，没有复习记录时为0
+}
+
+// RetentionStats 真实保持率（仅统计复习阶段卡片的复习结果）
+type RetentionStats struct {
+	Days   int           `json:"days"` // 统计的时间窗口（天）
+	Young  RetentionRate `json:"young"`
+	Mature RetentionRate `json:"mature"`
+	Total  RetentionRate `json:"total"`
+}
+
+// CardStateCounts 各状态卡片数量
+type CardStateCounts struct {
+	New       int `json:"new"`
+	Learning  int `json:"learning"`
+	Young     int `json:"young"`
+	Mature    int `json:"mature"`
+	Suspended int `json:"suspended"`
+}
+
+// HistogramBucket 直方图区间，包含Min，不包含Max（最后一个区间Max为空表示无上限）
+type HistogramBucket struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// HardCard 难记卡片
+type HardCard struct {
+	CardID   uint    `json:"card_id"`
+	DeckID   uint    `json:"deck_id"`
+	Question string  `json:"question"`
+	Lapses   int     `json:"lapses"`
+	EFactor  float64 `json:"efactor"`
+	Interval int     `json:"interval"`
+}
+
+// AnalyticsStats 记忆分析统计
+type AnalyticsStats struct {
+	Retention         RetentionStats    `json:"retention"`
+	CardStates        CardStateCounts   `json:"card_states"`
+	IntervalHistogram []HistogramBucket `json:"interval_histogram"`
+	EaseHistogram     []HistogramBucket `json:"ease_histogram"`
+	HardestCards      []HardCard        `json:"hardest_cards"`
+}
@@ -6,44 +6,234 @@ import (
 	"gorm.io/gorm"
 )
 
+// 卡片内容格式
+const (
+	ContentFormatPlain    = "plain"    // 纯文本
+	ContentFormatMarkdown = "markdown" // Markdown，支持代码块、表格和LaTeX公式
+)
+
+// 卡片标记颜色，0表示未标记
+const (
+	FlagNone      = 0
+	FlagRed       = 1
+	FlagOrange    = 2
+	FlagGreen     = 3
+	FlagBlue      = 4
+	FlagPink      = 5
+	FlagTurquoise = 6
+	FlagPurple    = 7
+	FlagMax       = FlagPurple
+)
+
 // Card 卡片模型
 type Card struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	DeckID    uint           `json:"deck_id" gorm:"not null;index"`
-	TagID     *uint          `json:"tag_id,omitempty" gorm:"index"` // 可为空，表示未分组
+	NoteID    *uint          `json:"note_id,omitempty" gorm:"index"` // 由笔记生成的卡片所属笔记
+	Type      string         `json:"type" gorm:"not null;default:basic"`
+	Ord       int            `json:"ord" gorm:"default:0"` // 在笔记中的序号，填空卡片为填空编号
 	Question  string         `json:"question" gorm:"not null;type:text"`
 	Answer    string         `json:"answer" gorm:"not null;type:text"`
+	Format    string         `json:"format" gorm:"not null;default:plain"` // 问题和答案的内容格式
+	Masks     OcclusionMasks `json:"masks,omitempty" gorm:"type:text"`     // 图片遮挡卡片的全部遮挡区域，Ord为当前卡片遮挡的区域编号
+	Suspended bool           `json:"suspended" gorm:"default:false;index"` // 暂停的卡片不进入学习队列
+	Flag      int            `json:"flag" gorm:"not null;default:0;index"` // 标记颜色，用于标记需要跟进的卡片
+	Position  int            `json:"position" gorm:"not null;default:0"`   // 在卡包中的顺序，新卡片排在最后，见 idx_cards_deck_position
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联
-	Deck   Deck    `json:"deck,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
-	Tag    *Tag    `json:"tag,omitempty" gorm:"constraint:OnDelete:SET NULL;"`
-	Review *Review `json:"review,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Deck    Deck         `json:"deck,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Tags    []Tag        `json:"tags,omitempty" gorm:"many2many:card_tags;"` // 为空表示未分组
+	Review  *Review      `json:"review,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Note    *Note        `json:"note,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Options []CardOption `json:"options,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Media   []Media      `json:"media,omitempty" gorm:"many2many:card_media;"`
+}
+
+// CardOption 选择题选项
+type CardOption struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	CardID   uint   `json:"card_id" gorm:"not null;index"`
+	Position int    `json:"position" gorm:"default:0"` // 选项在卡片中的原始顺序
+	Content  string `json:"content" gorm:"not null;type:text"`
+	Correct  bool   `json:"correct" gorm:"default:false"`
+}
+
+// CardFilter 卡片筛选条件
+type CardFilter struct {
+	DeckID  *uint  `form:"deck_id" json:"deck_id"`
+	TagID   *uint  `form:"tag_id" json:"tag_id"`
+	TagIDs  []uint `form:"tag_ids" json:"tag_ids"` // 同时拥有全部指定标签的卡片
+	Keyword string `form:"keyword" json:"keyword"`
+	Flag    *int   `form:"flag" json:"flag"`       // 指定颜色标记的卡片，0为未标记的卡片
+	Flagged *bool  `form:"flagged" json:"flagged"` // true为有任意标记的卡片，false为未标记的卡片
+	Query   string `form:"q" json:"q"`             // 搜索语句，如 deck:"日语" tag:verbs is:due -is:new prop:ivl>30
+}
+
+// 卡片列表的排序方式
+const (
+	CardSortCreated   = "created"   // 创建时间
+	CardSortUpdated   = "updated"   // 修改时间
+	CardSortDue       = "due"       // 到期时间，新卡片按创建时间计
+	CardSortInterval  = "interval"  // 复习间隔
+	CardSortEase      = "ease"      // 记忆强度因子
+	CardSortLapses    = "lapses"    // 遗忘次数
+	CardSortQuestion  = "question"  // 问题文本
+	CardSortPosition  = "position"  // 在卡包中的顺序
+	CardSortRelevance = "relevance" // 与关键词的相关度，仅在启用全文搜索且有关键词时可用
+)
+
+// CardPageRequest 卡片列表的排序和分页参数，指定cursor时从游标位置继续，忽略page
+type CardPageRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Sort     string `form:"sort"`   // 排序方式，见CardSort*常量
+	Order    string `form:"order"`  // 排序方向 asc 或 desc，为空时使用排序方式的默认方向
+	Cursor   string `form:"cursor"` // 上一页响应中的next_cursor
 }
 
 // CardSearchRequest 卡片搜索请求
 type CardSearchRequest struct {
-	DeckID   *uint  `form:"deck_id"`
-	TagID    *uint  `form:"tag_id"`
-	Keyword  string `form:"keyword"`
-	Page     int    `form:"page" binding:"min=1"`
-	PageSize int    `form:"page_size" binding:"min=1,max=100"`
+	CardFilter
+	CardPageRequest
 }
 
 // CardResponse 卡片响应（包含关联数据）
 type CardResponse struct {
 	Card
-	DeckName string `json:"deck_name,omitempty"`
-	TagName  string `json:"tag_name,omitempty"`
+	DeckName     string   `json:"deck_name,omitempty"`
+	TagNames     []string `json:"tag_names,omitempty"`
+	TagID        *uint    `json:"tag_id,omitempty"`   // 已废弃，兼容旧版本客户端：第一个标签的ID，请使用tags
+	TagName      string   `json:"tag_name,omitempty"` // 已废弃，兼容旧版本客户端：第一个标签的完整路径，请使用tag_names
+	QuestionHTML string   `json:"question_html"`      // 按内容格式渲染并清洗后的HTML
+	AnswerHTML   string   `json:"answer_html"`
+	Snippet      string   `json:"snippet,omitempty"` // 搜索关键词所在位置的摘要，匹配部分用<mark>标记
 }
 
 // CardListResponse 卡片列表响应
 type CardListResponse struct {
 	Cards      []CardResponse `json:"cards"`
 	Total      int64          `json:"total"`
-	Page       int            `json:"page"`
+	Page       int            `json:"page,omitempty"` // 按游标分页时为空
 	PageSize   int            `json:"page_size"`
 	TotalPages int            `json:"total_pages"`
+	NextCursor string         `json:"next_cursor,omitempty"` // 还有后续卡片时，用于获取下一页的游标
+}
+
+// 批量操作类型
+const (
+	BulkActionMove      = "move"       // 移动到卡包
+	BulkActionAddTag    = "add_tag"    // 添加标签
+	BulkActionRemoveTag = "remove_tag" // 移除标签，未指定标签时清除全部标签
+	BulkActionDelete    = "delete"     // 删除（移入回收站）
+	BulkActionSuspend   = "suspend"    // 暂停
+	BulkActionUnsuspend = "unsuspend"  // 取消暂停
+	BulkActionReset     = "reset"      // 重置复习进度，卡片重新作为新卡片学习
+	BulkActionFlag      = "flag"       // 设置标记颜色，0为清除标记
+)
+
+// CardBulkRequest 卡片批量操作请求，CardIDs和Filter至少指定一个，同时指定时取并集
+type CardBulkRequest struct {
+	CardIDs []uint      `json:"card_ids"`
+	Filter  *CardFilter `json:"filter"`
+	Action  string      `json:"action" binding:"required"`
+	DeckID  *uint       `json:"deck_id"` // move 的目标卡包
+	TagID   *uint       `json:"tag_id"`  // add_tag、remove_tag 的标签
+	Flag    *int        `json:"flag"`    // flag 的标记颜色
+}
+
+// CardReorderRequest 调整卡包中卡片顺序的请求，CardIDs和Start、End二选一
+type CardReorderRequest struct {
+	CardIDs  []uint `json:"card_ids"` // 移动的卡片，按给出的顺序连续排列
+	Start    int    `json:"start"`    // 移动第start到第end张卡片（从1开始，包括两端）
+	End      int    `json:"end"`
+	Position int    `json:"position" binding:"required,min=1"` // 移动后第一张卡片的位置，从1开始，超出卡片数时移到最后
+}
+
+// CardReorderResponse 调整卡片顺序的结果
+type CardReorderResponse struct {
+	CardIDs  []uint `json:"card_ids"` // 移动的卡片，按移动后的顺序排列
+	Position int    `json:"position"` // 移动后第一张卡片的位置
+	Total    int    `json:"total"`    // 卡包中的卡片数
+}
+
+// CardBulkResult 单张卡片的批量操作结果
+type CardBulkResult struct {
+	CardID  uint   `json:"card_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CardBulkResponse 批量操作结果，失败的卡片不影响其他卡片
+type CardBulkResponse struct {
+	Action    string           `json:"action"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []CardBulkResult `json:"results"`
+}
+
+// 查找替换的字段
+const (
+	ReplaceFieldQuestion = "question" // 只替换问题
+	ReplaceFieldAnswer   = "answer"   // 只替换答案
+	ReplaceFieldBoth     = "both"     // 问题和答案都替换
+)
+
+// CardReplaceRequest 卡片查找替换请求，DeckID、TagID和Filter至少指定一个，同时指定时需要同时满足
+type CardReplaceRequest struct {
+	Find       string      `json:"find" binding:"required"`
+	Replace    string      `json:"replace"`
+	Regex      bool        `json:"regex"`       // find为正则表达式，replace中可以用$1、${name}引用分组
+	IgnoreCase bool        `json:"ignore_case"` // 忽略大小写
+	Field      string      `json:"field"`       // question、answer或both，默认both
+	DeckID     *uint       `json:"deck_id"`
+	TagID      *uint       `json:"tag_id"`
+	Filter     *CardFilter `json:"filter"`
+	DryRun     bool        `json:"dry_run"` // 只预览替换结果，不修改卡片
+}
+
+// CardReplaceChange 卡片中一个字段的替换结果
+type CardReplaceChange struct {
+	Field  string `json:"field"` // question或answer
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Count  int    `json:"count"` // 替换次数
+}
+
+// CardReplaceResult 单张卡片的替换结果
+type CardReplaceResult struct {
+	CardID  uint                `json:"card_id"`
+	Changes []CardReplaceChange `json:"changes"`
+}
+
+// CardReplaceResponse 查找替换结果，由笔记生成的卡片需要通过笔记修改，不做替换
+type CardReplaceResponse struct {
+	DryRun         bool                `json:"dry_run"`
+	Total          int                 `json:"total"`            // 范围内的卡片数
+	Changed        int                 `json:"changed"`          // 内容有变化的卡片数
+	Replacements   int                 `json:"replacements"`     // 替换的总次数
+	SkippedCardIDs []uint              `json:"skipped_card_ids"` // 有匹配但由笔记生成的卡片
+	Cards          []CardReplaceResult `json:"cards"`
+}
+
+// 创建卡片时卡包中已有相同问题的处理方式
+const (
+	DuplicateWarn   = "warn"   // 照常创建，响应中列出重复的卡片
+	DuplicateReject = "reject" // 拒绝创建
+)
+
+// 导入时卡包中已有相同问题的处理方式
+const (
+	DuplicateKeep   = "keep"   // 保留两者
+	DuplicateSkip   = "skip"   // 跳过导入的卡片
+	DuplicateUpdate = "update" // 用导入的内容更新已有卡片或笔记
+)
+
+// DuplicateGroup 卡包中问题相同（规范化后）的一组卡片
+type DuplicateGroup struct {
+	Question string         `json:"question"` // 规范化后的问题
+	Cards    []CardResponse `json:"cards"`
 }
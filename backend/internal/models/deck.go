@@ -8,12 +8,18 @@ import (
 
 // Deck 卡包模型
 type Deck struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"unique;not null"`
-	Archived  bool           `json:"archived" gorm:"default:false"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Name         string         `json:"name" gorm:"not null"`             // 同一上级卡包下未删除的卡包名称唯一，见 idx_decks_parent_name
+	ParentID     *uint          `json:"parent_id,omitempty" gorm:"index"` // 为空表示顶层卡包
+	Archived     bool           `json:"archived" gorm:"default:false"`
+	StudyLimit   int            `json:"study_limit" gorm:"default:0"`                  // 每次学习最多抽取的卡片数（包括子卡包），0表示不限制
+	NewCardOrder string         `json:"new_card_order" gorm:"not null;default:random"` // 新卡片的学习顺序，见NewCardOrder*常量
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 描述、颜色、图标和元数据
+	Details
 
 	// 关联
 	Tags  []Tag  `json:"tags,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
@@ -34,3 +40,71 @@ type DeckWithStats struct {
 	Deck
 	Stats DeckStats `json:"stats"`
 }
+
+// DeckTreeNode 卡包树中的节点，统计信息包括所有子卡包
+type DeckTreeNode struct {
+	DeckWithStats
+	Children []DeckTreeNode `json:"children"`
+}
+
+// 卡包中新卡片的学习顺序
+const (
+	NewCardOrderRandom     = "random"     // 随机
+	NewCardOrderSequential = "sequential" // 按卡片在卡包中的顺序
+)
+
+// 删除包含子卡包的卡包时子卡包的处理方式
+const (
+	DeckChildrenDelete   = "delete"   // 子卡包一起删除
+	DeckChildrenReparent = "reparent" // 子卡包移动到被删除卡包的上级
+)
+
+// UpdateDeckRequest 更新卡包请求，未提供的字段保持不变
+type UpdateDeckRequest struct {
+	Name         *string `json:"name"`
+	Archived     *bool   `json:"archived"`
+	StudyLimit   *int    `json:"study_limit" binding:"omitempty,min=0"`
+	NewCardOrder *string `json:"new_card_order" binding:"omitempty,oneof=random sequential"`
+	DetailsUpdate
+}
+
+// IsEmpty 是否没有提供任何字段
+func (r UpdateDeckRequest) IsEmpty() bool {
+	return r.Name == nil && r.Archived == nil && r.StudyLimit == nil && r.NewCardOrder == nil && r.DetailsUpdate.IsEmpty()
+}
+
+// DeckCloneRequest 复制卡包请求
+type DeckCloneRequest struct {
+	Name              string `json:"name" binding:"max=100"` // 为空时使用“原名称 (副本)”
+	IncludeScheduling bool   `json:"include_scheduling"`     // 是否复制复习进度、复习日志和暂停状态
+}
+
+// DeckCloneResult 复制卡包结果
+type DeckCloneResult struct {
+	Deck              *Deck `json:"deck"` // 新建的卡包
+	SourceID          uint  `json:"source_id"`
+	Cards             int   `json:"cards"` // 复制的卡片数（包括笔记生成的卡片）
+	Notes             int   `json:"notes"`
+	Tags              int   `json:"tags"`
+	IncludeScheduling bool  `json:"include_scheduling"`
+}
+
+// DeckMergeRequest 合并卡包请求
+type DeckMergeRequest struct {
+	TargetID    uint   `json:"target_id" binding:"required"`
+	OnDuplicate string `json:"on_duplicate" binding:"omitempty,oneof=keep skip update"` // 与目标卡包中问题重复的卡片的处理方式，见Duplicate*常量
+}
+
+// DeckMergeResult 合并卡包结果
+type DeckMergeResult struct {
+	Deck             *Deck  `json:"deck"`               // 合并后的目标卡包
+	SourceID         uint   `json:"source_id"`          // 已移入回收站的源卡包
+	MovedCards       int    `json:"moved_cards"`        // 移动的卡片数（包括笔记生成的卡片）
+	MovedNotes       int    `json:"moved_notes"`        // 移动的笔记数
+	Updated          int    `json:"updated"`            // 用源卡包中的内容更新的重复卡片和笔记数
+	Skipped          int    `json:"skipped"`            // 跳过的重复卡片和笔记数
+	MergedTags       int    `json:"merged_tags"`        // 与目标卡包中相同路径的标签合并的标签数
+	MovedTags        int    `json:"moved_tags"`         // 移动到目标卡包的标签数
+	MovedDecks       int    `json:"moved_decks"`        // 移动到目标卡包下的子卡包数
+	DuplicateCardIDs []uint `json:"duplicate_card_ids"` // 重复而未移动的卡片，随源卡包移入回收站
+}
@@ -0,0 +1,22 @@
+package models
+
+// Details 卡包和标签的描述、颜色、图标和元数据
+type Details struct {
+	Description string            `json:"description" gorm:"type:text;not null;default:''"`
+	Color       string            `json:"color" gorm:"not null;default:''"`          // 十六进制颜色，如 #4A90E2，为空表示默认颜色
+	Icon        string            `json:"icon" gorm:"not null;default:''"`           // 图标名称或emoji
+	Metadata    map[string]string `json:"metadata,omitempty" gorm:"serializer:json"` // 自由格式的元数据，如 author、source、languages
+}
+
+// DetailsUpdate 更新卡包或标签的描述、颜色、图标和元数据，未提供的字段保持不变，元数据整体替换
+type DetailsUpdate struct {
+	Description *string           `json:"description" binding:"omitempty,max=2000"`
+	Color       *string           `json:"color"`
+	Icon        *string           `json:"icon" binding:"omitempty,max=50"`
+	Metadata    map[string]string `json:"metadata" binding:"omitempty,max=20,dive,keys,min=1,max=50,endkeys,max=500"`
+}
+
+// IsEmpty 是否没有提供任何字段
+func (u DetailsUpdate) IsEmpty() bool {
+	return u.Description == nil && u.Color == nil && u.Icon == nil && u.Metadata == nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Media 媒体文件模型（图片、音频），文件按内容的SHA-256哈希存储，相同内容只保存一份
+type Media struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Hash      string    `json:"hash" gorm:"not null;uniqueIndex;size:64"`
+	Filename  string    `json:"filename"` // 首次上传时的原始文件名
+	MimeType  string    `json:"mime_type" gorm:"not null"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MediaGCResult 媒体垃圾回收结果
+type MediaGCResult struct {
+	DeletedMedia int   `json:"deleted_media"` // 删除的未引用媒体记录数
+	DeletedFiles int   `json:"deleted_files"` // 删除的文件数（包括没有对应记录的孤立文件）
+	FreedBytes   int64 `json:"freed_bytes"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 卡片类型
+const (
+	CardTypeBasic     = "basic"     // 普通问答卡片
+	CardTypeCloze     = "cloze"     // 填空卡片，由笔记按填空编号生成
+	CardTypeChoice    = "choice"    // 选择题卡片，选项保存在card_options表中
+	CardTypeOcclusion = "occlusion" // 图片遮挡卡片，由笔记按遮挡区域生成
+	CardTypeTemplate  = "template"  // 模板卡片，由自定义笔记类型的笔记按卡片模板生成
+)
+
+// Note 笔记模型，一条笔记可以生成多张卡片（如填空笔记的每个填空编号生成一张卡片）
+type Note struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	DeckID     uint           `json:"deck_id" gorm:"not null;index"`
+	Type       string         `json:"type" gorm:"not null;default:cloze"`
+	NoteTypeID *uint          `json:"note_type_id,omitempty" gorm:"index"` // 模板笔记使用的笔记类型
+	Fields     NoteFields     `json:"fields,omitempty" gorm:"type:text"`   // 模板笔记的字段内容
+	Content    string         `json:"content" gorm:"not null;type:text"`   // 源文本，如 {{c1::答案::提示}}；模板笔记为第一个字段的内容
+	Extra      string         `json:"extra" gorm:"type:text"`              // 背面附加内容
+	MediaID    *uint          `json:"media_id,omitempty" gorm:"index"`     // 图片遮挡笔记使用的图片
+	Masks      OcclusionMasks `json:"masks,omitempty" gorm:"type:text"`    // 图片遮挡区域
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联
+	Cards    []Card    `json:"cards,omitempty"`
+	Tags     []Tag     `json:"tags,omitempty" gorm:"many2many:note_tags;"` // 生成的卡片使用笔记的标签
+	Media    *Media    `json:"media,omitempty"`
+	NoteType *NoteType `json:"note_type,omitempty"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NoteType 自定义笔记类型，定义笔记的字段和生成卡片的模板
+type NoteType struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null"`                 // 未删除的笔记类型名称唯一
+	Fields    NoteFieldNames `json:"fields" gorm:"type:text"`              // 字段名称，第一个字段作为笔记的排序和查重字段
+	Templates CardTemplates  `json:"templates" gorm:"type:text"`           // 每个模板生成一张卡片，卡片的Ord为模板序号（从1开始）
+	Format    string         `json:"format" gorm:"not null;default:plain"` // 生成卡片的内容格式
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CardTemplate 卡片模板，{{字段名}} 替换为字段内容，{{#字段名}}...{{/字段名}} 只在字段不为空时显示，
+// {{^字段名}}...{{/字段名}} 只在字段为空时显示，背面模板中的 {{FrontSide}} 替换为正面内容
+type CardTemplate struct {
+	Name  string `json:"name"`
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+// TemplateFrontSide 背面模板中表示正面内容的特殊字段
+const TemplateFrontSide = "FrontSide"
+
+// NoteFieldNames 笔记类型的字段名称列表，以JSON文本存储
+type NoteFieldNames []string
+
+// Value 实现driver.Valuer接口
+func (f NoteFieldNames) Value() (driver.Value, error) {
+	return jsonValue(f)
+}
+
+// Scan 实现sql.Scanner接口
+func (f *NoteFieldNames) Scan(value interface{}) error {
+	return scanJSON(value, f)
+}
+
+// CardTemplates 卡片模板列表，以JSON文本存储
+type CardTemplates []CardTemplate
+
+// Value 实现driver.Valuer接口
+func (t CardTemplates) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+// Scan 实现sql.Scanner接口
+func (t *CardTemplates) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+// NoteFields 笔记的字段内容（字段名到内容），以JSON文本存储
+type NoteFields map[string]string
+
+// Value 实现driver.Valuer接口
+func (f NoteFields) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return jsonValue(f)
+}
+
+// Scan 实现sql.Scanner接口
+func (f *NoteFields) Scan(value interface{}) error {
+	return scanJSON(value, f)
+}
+
+// jsonValue 将值序列化为JSON文本
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanJSON 从数据库的JSON文本解析值，空值保持零值
+func scanJSON(value interface{}, dest interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("无法解析JSON数据")
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
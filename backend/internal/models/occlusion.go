@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// 遮挡区域形状
+const (
+	MaskShapeRect    = "rect"    // 矩形，使用X、Y、Width、Height
+	MaskShapePolygon = "polygon" // 多边形，使用Points
+)
+
+// OcclusionMask 图片遮挡区域，坐标单位由前端决定（建议使用相对图片尺寸的0-1比例）
+type OcclusionMask struct {
+	ID     int          `json:"id"` // 区域编号，对应卡片的Ord，由服务端分配
+	Shape  string       `json:"shape"`
+	X      float64      `json:"x,omitempty"`
+	Y      float64      `json:"y,omitempty"`
+	Width  float64      `json:"width,omitempty"`
+	Height float64      `json:"height,omitempty"`
+	Points [][2]float64 `json:"points,omitempty"`
+	Label  string       `json:"label,omitempty"` // 区域答案，可为空
+}
+
+// OcclusionMasks 遮挡区域列表，以JSON文本存储
+type OcclusionMasks []OcclusionMask
+
+// Value 实现driver.Valuer接口
+func (m OcclusionMasks) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (m *OcclusionMasks) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("无法解析遮挡区域数据")
+	}
+	if len(data) == 0 {
+		*m = nil
+		return nil
+	}
+	return json.Unmarshal(data, m)
+}
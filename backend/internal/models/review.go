@@ -11,6 +11,7 @@ type Review struct {
 	EFactor     float64   `json:"efactor" gorm:"default:2.5"`           // 记忆强度因子，默认2.5
 	Interval    int       `json:"interval" gorm:"default:0"`            // 间隔天数
 	Repetitions int       `json:"repetitions" gorm:"default:0"`         // 连续复习次数
+	Lapses      int       `json:"lapses" gorm:"default:0"`              // 复习阶段遗忘次数
 	NextReview  time.Time `json:"next_review" gorm:"index"`             // 下次复习时间
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -19,6 +20,17 @@ type Review struct {
 	Card Card `json:"card,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
 }
 
+// ReviewLog 复习日志，每次提交复习结果记录一条
+type ReviewLog struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	CardID       uint         `json:"card_id" gorm:"not null;index"`
+	Result       ReviewResult `json:"result"`
+	LastInterval int          `json:"last_interval"` // 复习前的间隔天数，0表示新卡片或学习中
+	Interval     int          `json:"interval"`      // 复习后的间隔天数
+	EFactor      float64      `json:"efactor"`       // 复习后的记忆强度因子
+	ReviewedAt   time.Time    `json:"reviewed_at" gorm:"index"`
+}
+
 // ReviewResult 复习结果枚举
 type ReviewResult int
 
@@ -28,13 +40,46 @@ const (
 	Good                      // 记得，正常难度
 )
 
+// String 返回复习结果名称
+func (r ReviewResult) String() string {
+	switch r {
+	case Again:
+		return "again"
+	case Hard:
+		return "hard"
+	case Good:
+		return "good"
+	default:
+		return "unknown"
+	}
+}
+
 // StudyQueue 学习队列项
 type StudyQueue struct {
-	CardID   uint   `json:"card_id"`
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
-	DeckName string `json:"deck_name"`
-	TagName  string `json:"tag_name,omitempty"`
+	CardID       uint            `json:"card_id"`
+	Type         string          `json:"type"`
+	Question     string          `json:"question"`
+	Answer       string          `json:"answer"`
+	Format       string          `json:"format"`
+	QuestionHTML string          `json:"question_html"` // 按内容格式渲染并清洗后的HTML
+	AnswerHTML   string          `json:"answer_html"`
+	Options      []StudyOption   `json:"options,omitempty"`  // 选择题选项，已打乱顺序
+	Multiple     bool            `json:"multiple,omitempty"` // 选择题是否有多个正确选项
+	Media        []Media         `json:"media,omitempty"`
+	HideMask     *OcclusionMask  `json:"hide_mask,omitempty"`  // 图片遮挡卡片需要遮挡并回答的区域
+	ShowMasks    []OcclusionMask `json:"show_masks,omitempty"` // 图片遮挡卡片的其他区域
+	DeckName     string          `json:"deck_name"`
+	TagIDs       []uint          `json:"tag_ids,omitempty"`
+	TagNames     []string        `json:"tag_names,omitempty"` // 与tag_ids一一对应
+	TagID        *uint           `json:"tag_id,omitempty"`    // 已废弃，兼容旧版本客户端：第一个标签的ID
+	TagName      string          `json:"tag_name,omitempty"`  // 已废弃，兼容旧版本客户端：第一个标签的完整路径
+	Flag         int             `json:"flag"`
+}
+
+// StudyOption 学习队列中的选择题选项（不包含正确与否）
+type StudyOption struct {
+	ID      uint   `json:"id"`
+	Content string `json:"content"`
 }
 
 // StudySession 学习会话
@@ -48,13 +93,24 @@ type StudySession struct {
 
 // ReviewRequest 复习请求
 type ReviewRequest struct {
-	Result ReviewResult `json:"result"`
+	Result  ReviewResult `json:"result"`
+	Choices []uint       `json:"choices,omitempty"` // 选择题提交的选项ID，提供时由服务端评分
+	Flag    *int         `json:"flag,omitempty"`    // 同时设置卡片的标记颜色，0为清除标记
+}
+
+// FilteredStudyRequest 按筛选条件学习的请求
+type FilteredStudyRequest struct {
+	CardFilter
+	DueOnly bool `json:"due_only"` // 只学习到期的卡片（包括新卡片）
 }
 
 // ReviewResponse 复习响应
 type ReviewResponse struct {
-	Success    bool      `json:"success"`
-	NextReview time.Time `json:"next_review"`
-	Interval   int       `json:"interval"`
-	Message    string    `json:"message"`
+	Success        bool         `json:"success"`
+	Result         ReviewResult `json:"result"`
+	NextReview     time.Time    `json:"next_review"`
+	Interval       int          `json:"interval"`
+	Message        string       `json:"message"`
+	Correct        *bool        `json:"correct,omitempty"`         // 选择题是否完全答对
+	CorrectOptions []uint       `json:"correct_options,omitempty"` // 选择题的正确选项ID
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// 修订记录跟踪的卡片字段
+const (
+	FieldDeckID   = "deck_id"
+	FieldTagIDs   = "tag_ids"
+	FieldQuestion = "question"
+	FieldAnswer   = "answer"
+	FieldFormat   = "format"
+	FieldOptions  = "options"
+)
+
+// CardRevision 卡片修订记录，保存每次编辑后的卡片内容快照
+type CardRevision struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	CardID        uint           `json:"card_id" gorm:"not null;index"`
+	DeckID        uint           `json:"deck_id"`
+	TagIDs        []uint         `json:"tag_ids" gorm:"serializer:json"`
+	Question      string         `json:"question" gorm:"type:text"`
+	Answer        string         `json:"answer" gorm:"type:text"`
+	Format        string         `json:"format"`
+	Options       []OptionExport `json:"options" gorm:"serializer:json"`        // 选择题选项，为null表示旧版本没有记录选项
+	ChangedFields []string       `json:"changed_fields" gorm:"serializer:json"` // 相对上一个修订变化的字段，为空表示编辑前的原始内容
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// DiffLine 文本差异中的一行
+type DiffLine struct {
+	Op   string `json:"op"` // equal、insert或delete
+	Text string `json:"text"`
+}
+
+// FieldDiff 单个字段的差异
+type FieldDiff struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Lines []DiffLine  `json:"lines,omitempty"` // 问题和答案的逐行差异
+}
+
+// RevisionDiff 两个修订之间的差异
+type RevisionDiff struct {
+	CardID uint        `json:"card_id"`
+	FromID uint        `json:"from_id"`
+	ToID   uint        `json:"to_id"`
+	Fields []FieldDiff `json:"fields"`
+}
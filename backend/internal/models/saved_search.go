@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// SavedSearch 保存的搜索，记录搜索卡片的筛选条件以便再次执行
+type SavedSearch struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name" gorm:"not null"`                 // 名称唯一，见 idx_saved_searches_name
+	Filter    SavedFilter `json:"filter" gorm:"type:text"`              // 与搜索卡片的筛选和排序参数相同
+	SortOrder int         `json:"sort_order" gorm:"not null;default:0"` // 在列表中的顺序，从小到大排列
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// SavedFilter 保存的卡片筛选条件和排序方式，以JSON文本存储
+type SavedFilter struct {
+	CardFilter
+	Sort  string `json:"sort,omitempty"`  // 排序方式，见CardSort*常量，为空时与搜索卡片的默认排序相同
+	Order string `json:"order,omitempty"` // 排序方向 asc 或 desc，为空时使用排序方式的默认方向
+}
+
+// Value 实现driver.Valuer接口
+func (f SavedFilter) Value() (driver.Value, error) {
+	return jsonValue(f)
+}
+
+// Scan 实现sql.Scanner接口
+func (f *SavedFilter) Scan(value interface{}) error {
+	return scanJSON(value, f)
+}
@@ -4,39 +4,106 @@ import "time"
 
 // BackupData 完整备份数据结构
 type BackupData struct {
-	Version    string         `json:"version"`
-	ExportDate time.Time      `json:"export_date"`
-	Decks      []DeckBackup   `json:"decks"`
-	Tags       []TagBackup    `json:"tags"`
-	Cards      []CardBackup   `json:"cards"`
-	Reviews    []ReviewBackup `json:"reviews"`
+	Version       string              `json:"version"`
+	ExportDate    time.Time           `json:"export_date"`
+	Decks         []DeckBackup        `json:"decks"`
+	Tags          []TagBackup         `json:"tags"`
+	NoteTypes     []NoteTypeBackup    `json:"note_types"`
+	Notes         []NoteBackup        `json:"notes"`
+	Cards         []CardBackup        `json:"cards"`
+	Options       []OptionBackup      `json:"card_options"`
+	Reviews       []ReviewBackup      `json:"reviews"`
+	ReviewLogs    []ReviewLogBackup   `json:"review_logs"`
+	Media         []MediaBackup       `json:"media"` // 媒体文件内容保存在备份压缩包的media目录中
+	CardMedia     []CardMediaBackup   `json:"card_media"`
+	Revisions     []RevisionBackup    `json:"card_revisions"`
+	SavedSearches []SavedSearchBackup `json:"saved_searches"`
 }
 
 // 完整表备份结构
 type DeckBackup struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Archived  bool      `json:"archived"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint      `json:"id"`
+	ParentID     *uint     `json:"parent_id,omitempty"`
+	Name         string    `json:"name"`
+	Archived     bool      `json:"archived"`
+	StudyLimit   int       `json:"study_limit,omitempty"`
+	NewCardOrder string    `json:"new_card_order,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Details                // 旧版本备份没有描述等信息
 }
 
 type TagBackup struct {
 	ID        uint      `json:"id"`
 	DeckID    *uint     `json:"deck_id"`
+	ParentID  *uint     `json:"parent_id,omitempty"`
 	Name      string    `json:"name"`
+	Path      string    `json:"path,omitempty"` // 旧版本备份没有路径，恢复时使用标签名
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Details             // 旧版本备份没有描述等信息
+}
+
+type NoteTypeBackup struct {
+	ID        uint           `json:"id"`
+	Name      string         `json:"name"`
+	Fields    NoteFieldNames `json:"fields"`
+	Templates CardTemplates  `json:"templates"`
+	Format    string         `json:"format"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type SavedSearchBackup struct {
+	ID        uint        `json:"id"`
+	Name      string      `json:"name"`
+	Filter    SavedFilter `json:"filter"`
+	SortOrder int         `json:"sort_order"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type NoteBackup struct {
+	ID         uint           `json:"id"`
+	DeckID     uint           `json:"deck_id"`
+	TagID      *uint          `json:"tag_id,omitempty"` // 旧版本备份的单个标签
+	TagIDs     []uint         `json:"tag_ids,omitempty"`
+	Type       string         `json:"type"`
+	NoteTypeID *uint          `json:"note_type_id,omitempty"`
+	Fields     NoteFields     `json:"fields,omitempty"`
+	Content    string         `json:"content"`
+	Extra      string         `json:"extra"`
+	MediaID    *uint          `json:"media_id,omitempty"`
+	Masks      OcclusionMasks `json:"masks,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
 }
 
 type CardBackup struct {
-	ID        uint      `json:"id"`
-	DeckID    uint      `json:"deck_id"`
-	TagID     *uint     `json:"tag_id"`
-	Question  string    `json:"question"`
-	Answer    string    `json:"answer"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint           `json:"id"`
+	DeckID    uint           `json:"deck_id"`
+	TagID     *uint          `json:"tag_id,omitempty"` // 旧版本备份的单个标签
+	TagIDs    []uint         `json:"tag_ids,omitempty"`
+	NoteID    *uint          `json:"note_id,omitempty"`
+	Type      string         `json:"type,omitempty"`
+	Ord       int            `json:"ord,omitempty"`
+	Question  string         `json:"question"`
+	Answer    string         `json:"answer"`
+	Format    string         `json:"format,omitempty"`
+	Masks     OcclusionMasks `json:"masks,omitempty"`
+	Suspended bool           `json:"suspended"`
+	Flag      int            `json:"flag,omitempty"`
+	Position  int            `json:"position,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type OptionBackup struct {
+	ID       uint   `json:"id"`
+	CardID   uint   `json:"card_id"`
+	Position int    `json:"position"`
+	Content  string `json:"content"`
+	Correct  bool   `json:"correct"`
 }
 
 type ReviewBackup struct {
@@ -45,31 +112,101 @@ type ReviewBackup struct {
 	EFactor     float64   `json:"efactor"`
 	Interval    int       `json:"interval"`
 	Repetitions int       `json:"repetitions"`
+	Lapses      int       `json:"lapses"`
 	NextReview  time.Time `json:"next_review"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type ReviewLogBackup struct {
+	ID           uint      `json:"id"`
+	CardID       uint      `json:"card_id"`
+	Result       int       `json:"result"`
+	LastInterval int       `json:"last_interval"`
+	Interval     int       `json:"interval"`
+	EFactor      float64   `json:"efactor"`
+	ReviewedAt   time.Time `json:"reviewed_at"`
+}
+
+// 导出内容类型
+const (
+	ExportContentSource   = "source"   // 导出源文本
+	ExportContentRendered = "rendered" // TXT/CSV导出渲染后的HTML
+)
+
+type MediaBackup struct {
+	ID        uint      `json:"id"`
+	Hash      string    `json:"hash"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CardMediaBackup struct {
+	CardID  uint `json:"card_id"`
+	MediaID uint `json:"media_id"`
+}
+
+type RevisionBackup struct {
+	ID            uint      `json:"id"`
+	CardID        uint      `json:"card_id"`
+	DeckID        uint      `json:"deck_id"`
+	TagIDs        []uint    `json:"tag_ids,omitempty"`
+	Question      string    `json:"question"`
+	Answer        string    `json:"answer"`
+	Format        string    `json:"format"`
+	ChangedFields []string  `json:"changed_fields"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DeckName    string            // 新建卡包的名称，为空时使用文件中的名称
+	DeckID      *uint             // 导入到已有卡包，指定时不新建卡包
+	OnDuplicate string            // 卡包中已有相同问题时的处理方式，默认保留两者
+	NoteTypeID  *uint             // CSV按该笔记类型导入，每行生成一条模板笔记
+	FieldMap    map[string]string // CSV列名到笔记类型字段名的映射，未映射的列按同名字段匹配（不区分大小写）
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Deck    *Deck
+	Created int // 新建的卡片或笔记数
+	Updated int // 更新的已有卡片或笔记数
+	Skipped int // 因重复跳过的卡片或笔记数
+}
+
 // 为了兼容性，保留原有导出结构
 type DeckExport struct {
-	Name        string       `json:"name"`
-	Description string       `json:"description"`
-	Cards       []CardExport `json:"cards"`
-	Tags        []TagExport  `json:"tags"`
-	CreatedAt   time.Time    `json:"created_at"`
+	Name      string       `json:"name"`
+	Cards     []CardExport `json:"cards"`
+	Tags      []TagExport  `json:"tags"`
+	CreatedAt time.Time    `json:"created_at"`
+	Details                // 描述、颜色、图标和元数据，只在JSON格式中导出
 }
 
 type CardExport struct {
-	Question  string    `json:"question"`
-	Answer    string    `json:"answer"`
-	TagName   string    `json:"tag_name,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	Type      string         `json:"type,omitempty"` // 为空表示普通卡片；cloze时Question为填空内容，Answer为背面附加内容
+	Question  string         `json:"question"`
+	Answer    string         `json:"answer"`
+	Format    string         `json:"format,omitempty"`   // 为空表示纯文本
+	Options   []OptionExport `json:"options,omitempty"`  // choice时的选项
+	TagName   string         `json:"tag_name,omitempty"` // 旧版本导出的单个标签
+	TagNames  []string       `json:"tag_names,omitempty"`
+	Flag      int            `json:"flag,omitempty"` // 标记颜色，只在JSON格式中导出
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type OptionExport struct {
+	Content string `json:"content"`
+	Correct bool   `json:"correct"`
 }
 
 type TagExport struct {
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Details             // 描述、颜色、图标和元数据
 }
 
 // SystemStats 系统统计信息
@@ -10,14 +10,27 @@ import (
 type Tag struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	DeckID    *uint          `json:"deck_id,omitempty" gorm:"index"`
+	ParentID  *uint          `json:"parent_id,omitempty" gorm:"index"` // 为空表示顶层标签
 	Name      string         `json:"name" gorm:"not null"`
+	Path      string         `json:"path" gorm:"not null;default:''"` // 从顶层标签开始的完整路径，如 Grammar::Verbs::Irregular
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// 描述、颜色、图标和元数据
+	Details
+
 	// 关联
 	Deck  *Deck  `json:"deck,omitempty" gorm:"constraint:OnDelete:SET NULL;"`
-	Cards []Card `json:"cards,omitempty"`
+	Cards []Card `json:"cards,omitempty" gorm:"many2many:card_tags;"`
+}
+
+// UpdateTagRequest 更新标签请求：提供名称时同时按deck_id设置标签所属的卡包（为空表示不属于任何卡包），
+// 只提供描述等信息时不修改名称和卡包
+type UpdateTagRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=1,max=50"`
+	DeckID *uint   `json:"deck_id,omitempty"`
+	DetailsUpdate
 }
 
 // TagStats 标签统计信息
@@ -33,6 +46,9 @@ type TagWithStats struct {
 	Stats TagStats `json:"stats"`
 }
 
+// TagPathSeparator 标签路径中上下级标签名的分隔符
+const TagPathSeparator = "::"
+
 // 确保同一卡包内标签名唯一的索引
 func (Tag) TableName() string {
 	return "tags"
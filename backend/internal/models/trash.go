@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 回收站项目类型
+const (
+	TrashTypeDeck = "deck"
+	TrashTypeTag  = "tag"
+	TrashTypeCard = "card"
+)
+
+// TrashItem 回收站中的项目
+type TrashItem struct {
+	Type      string     `json:"type"`
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"` // 卡包名、标签名或卡片问题
+	DeckID    *uint      `json:"deck_id,omitempty"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"` // 自动彻底删除的时间，未开启自动清理时为空
+}
+
+// TrashPurgeResult 彻底删除的项目数量
+type TrashPurgeResult struct {
+	Decks int `json:"decks"`
+	Tags  int `json:"tags"`
+	Cards int `json:"cards"`
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsService 记忆分析服务
+type AnalyticsService struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsService 创建记忆分析服务实例
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{
+		db: database.GetDB(),
+	}
+}
+
+// cardScope 限定统计范围的卡片查询条件
+type cardScope func(query *gorm.DB) *gorm.DB
+
+// bucketRange 直方图区间定义，max为0表示无上限
+type bucketRange struct {
+	label    string
+	min, max float64
+}
+
+// 间隔直方图区间（天）
+var intervalBuckets = []bucketRange{
+	{"0", 0, 1},
+	{"1", 1, 2},
+	{"2-3", 2, 4},
+	{"4-7", 4, 8},
+	{"8-14", 8, 15},
+	{"15-30", 15, 31},
+	{"31-90", 31, 91},
+	{"91-180", 91, 181},
+	{"181-365", 181, 366},
+	{"365+", 366, 0},
+}
+
+// 记忆强度因子直方图区间
+var easeBuckets = []bucketRange{
+	{"1.3-1.5", 1.3, 1.5},
+	{"1.5-1.7", 1.5, 1.7},
+	{"1.7-1.9", 1.7, 1.9},
+	{"1.9-2.1", 1.9, 2.1},
+	{"2.1-2.3", 2.1, 2.3},
+	{"2.3+", 2.3, 0},
+}
+
+// GetDeckAnalytics 获取卡包（包括所有子卡包）的记忆分析统计
+func (s *AnalyticsService) GetDeckAnalytics(deckID uint, days, limit int) (*models.AnalyticsStats, error) {
+	if err := s.db.Select("id").First(&models.Deck{}, deckID).Error; err != nil {
+		return nil, err
+	}
+
+	deckIDs, err := deckSubtreeIDs(s.db, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getAnalytics(func(query *gorm.DB) *gorm.DB {
+		return query.Where("cards.deck_id IN ?", deckIDs)
+	}, days, limit)
+}
+
+// GetTagAnalytics 获取标签的记忆分析统计
+func (s *AnalyticsService) GetTagAnalytics(tagID uint, days, limit int) (*models.AnalyticsStats, error) {
+	if err := s.db.Select("id").First(&models.Tag{}, tagID).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getAnalytics(func(query *gorm.DB) *gorm.DB {
+		return cardsWithTag(query, tagID)
+	}, days, limit)
+}
+
+// GetCollectionAnalytics 获取全部卡片的记忆分析统计
+func (s *AnalyticsService) GetCollectionAnalytics(days, limit int) (*models.AnalyticsStats, error) {
+	return s.getAnalytics(func(query *gorm.DB) *gorm.DB {
+		return query
+	}, days, limit)
+}
+
+// getAnalytics 按范围计算记忆分析统计
+func (s *AnalyticsService) getAnalytics(scope cardScope, days, limit int) (*models.AnalyticsStats, error) {
+	stats := &models.AnalyticsStats{}
+
+	retention, err := s.getRetention(scope, days)
+	if err != nil {
+		return nil, err
+	}
+	stats.Retention = *retention
+
+	states, err := s.getCardStates(scope)
+	if err != nil {
+		return nil, err
+	}
+	stats.CardStates = *states
+
+	intervalHistogram, err := s.getHistogram(scope, "reviews.interval", intervalBuckets)
+	if err != nil {
+		return nil, err
+	}
+	stats.IntervalHistogram = intervalHistogram
+
+	easeHistogram, err := s.getHistogram(scope, "ROUND(reviews.e_factor, 2)", easeBuckets)
+	if err != nil {
+		return nil, err
+	}
+	stats.EaseHistogram = easeHistogram
+
+	hardest, err := s.getHardestCards(scope, limit)
+	if err != nil {
+		return nil, err
+	}
+	stats.HardestCards = hardest
+
+	return stats, nil
+}
+
+// getRetention 计算真实保持率：复习前已处于复习阶段（间隔>=1天）的复习中，未遗忘的比例
+func (s *AnalyticsService) getRetention(scope cardScope, days int) (*models.RetentionStats, error) {
+	var rows []struct {
+		Mature bool
+		Passed int
+		Failed int
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	query := s.db.
+		Table("review_logs").
+		Select("review_logs.last_interval >= ? AS mature, "+
+			"SUM(CASE WHEN review_logs.result <> ? THEN 1 ELSE 0 END) AS passed, "+
+			"SUM(CASE WHEN review_logs.result = ? THEN 1 ELSE 0 END) AS failed",
+			models.MatureInterval, models.Again, models.Again).
+		Joins("JOIN cards ON cards.id = review_logs.card_id AND cards.deleted_at IS NULL").
+		Where("review_logs.last_interval >= 1").
+		Where("review_logs.reviewed_at >= ?", since).
+		Group("mature")
+
+	if err := scope(query).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	retention := &models.RetentionStats{Days: days}
+	for _, row := range rows {
+		if row.Mature {
+			retention.Mature = newRetentionRate(row.Passed, row.Failed)
+		} else {
+			retention.Young = newRetentionRate(row.Passed, row.Failed)
+		}
+	}
+	retention.Total = newRetentionRate(
+		retention.Young.Passed+retention.Mature.Passed,
+		retention.Young.Failed+retention.Mature.Failed,
+	)
+
+	return retention, nil
+}
+
+// newRetentionRate 根据通过和遗忘次数计算保持率
+func newRetentionRate(passed, failed int) models.RetentionRate {
+	rate := models.RetentionRate{Passed: passed, Failed: failed}
+	if passed+failed > 0 {
+		rate.Rate = float64(passed) / float64(passed+failed)
+	}
+	return rate
+}
+
+// getCardStates 统计各状态的卡片数量
+func (s *AnalyticsService) getCardStates(scope cardScope) (*models.CardStateCounts, error) {
+	var counts models.CardStateCounts
+
+	query := s.db.
+		Table("cards").
+		Select("COALESCE(SUM(CASE WHEN cards.suspended THEN 1 ELSE 0 END), 0) AS suspended, "+
+			"COALESCE(SUM(CASE WHEN NOT cards.suspended AND reviews.id IS NULL THEN 1 ELSE 0 END), 0) AS new, "+
+			"COALESCE(SUM(CASE WHEN NOT cards.suspended AND reviews.interval < 1 THEN 1 ELSE 0 END), 0) AS learning, "+
+			"COALESCE(SUM(CASE WHEN NOT cards.suspended AND reviews.interval >= 1 AND reviews.interval < ? THEN 1 ELSE 0 END), 0) AS young, "+
+			"COALESCE(SUM(CASE WHEN NOT cards.suspended AND reviews.interval >= ? THEN 1 ELSE 0 END), 0) AS mature",
+			models.MatureInterval, models.MatureInterval).
+		Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
+		Where("cards.deleted_at IS NULL")
+
+	if err := scope(query).Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return &counts, nil
+}
+
+// getHistogram 按区间统计已复习卡片的某一列取值分布
+func (s *AnalyticsService) getHistogram(scope cardScope, column string, buckets []bucketRange) ([]models.HistogramBucket, error) {
+	var rows []struct {
+		Value float64
+		Count int
+	}
+
+	query := s.db.
+		Table("cards").
+		Select(column+" AS value, COUNT(*) AS count").
+		Joins("JOIN reviews ON cards.id = reviews.card_id").
+		Where("cards.deleted_at IS NULL").
+		Where("cards.suspended = ?", false).
+		Group("value")
+
+	if err := scope(query).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	histogram := make([]models.HistogramBucket, len(buckets))
+	for i, bucket := range buckets {
+		histogram[i] = models.HistogramBucket{Label: bucket.label, Min: bucket.min}
+		if bucket.max > 0 {
+			max := bucket.max
+			histogram[i].Max = &max
+		}
+	}
+
+	for _, row := range rows {
+		// 低于第一个区间的值计入第一个区间
+		index := 0
+		for i, bucket := range buckets {
+			if row.Value >= bucket.min {
+				index = i
+			}
+		}
+		histogram[index].Count += row.Count
+	}
+
+	return histogram, nil
+}
+
+// getHardestCards 按遗忘次数获取最难记的卡片
+func (s *AnalyticsService) getHardestCards(scope cardScope, limit int) ([]models.HardCard, error) {
+	hardest := []models.HardCard{}
+
+	query := s.db.
+		Table("cards").
+		Select("cards.id AS card_id, cards.deck_id, cards.question, reviews.lapses, reviews.e_factor, reviews.interval").
+		Joins("JOIN reviews ON cards.id = reviews.card_id").
+		Where("cards.deleted_at IS NULL").
+		Where("reviews.lapses > 0").
+		Order("reviews.lapses DESC, reviews.e_factor ASC").
+		Limit(limit)
+
+	if err := scope(query).Scan(&hardest).Error; err != nil {
+		return nil, err
+	}
+
+	return hardest, nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 批量操作相关错误
+var (
+	ErrInvalidBulkAction = errors.New("无效的批量操作类型")
+	ErrBulkNoCards       = errors.New("请指定卡片ID或非空的筛选条件")
+	ErrBulkDeckRequired  = errors.New("移动卡片需要指定存在的目标卡包")
+	ErrBulkTagRequired   = errors.New("添加标签需要指定存在的标签")
+	ErrBulkCardNotFound  = errors.New("卡片不存在")
+	ErrBulkFlagRequired  = errors.New("设置标记需要指定标记颜色")
+)
+
+// BulkUpdateCards 在一个事务中对多张卡片执行批量操作，
+// 每张卡片单独回滚，失败的卡片记录在结果中，不影响其他卡片；
+// 没有任何条件的筛选会选中全部卡片，视为误用而拒绝
+func (s *CardService) BulkUpdateCards(req models.CardBulkRequest) (*models.CardBulkResponse, error) {
+	if len(req.CardIDs) == 0 && req.Filter == nil {
+		return nil, ErrBulkNoCards
+	}
+	if req.Filter != nil && emptyCardFilter(*req.Filter) {
+		return nil, ErrBulkNoCards
+	}
+	if req.Filter != nil {
+		if err := validateCardFilter(*req.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	apply, err := s.bulkAction(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CardBulkResponse{
+		Action:  req.Action,
+		Results: []models.CardBulkResult{},
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		cardIDs, err := bulkCardIDs(tx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, cardID := range cardIDs {
+			result := models.CardBulkResult{CardID: cardID, Success: true}
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				var card models.Card
+				if err := tx.Preload("Tags").First(&card, cardID).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return ErrBulkCardNotFound
+					}
+					return err
+				}
+				return apply(tx, &card)
+			})
+			if err != nil {
+				result.Success = false
+				result.Error = err.Error()
+				response.Failed++
+			} else {
+				response.Succeeded++
+			}
+			response.Results = append(response.Results, result)
+		}
+
+		response.Total = len(cardIDs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// bulkAction 检查批量操作参数，返回对单张卡片执行的操作
+func (s *CardService) bulkAction(req models.CardBulkRequest) (func(tx *gorm.DB, card *models.Card) error, error) {
+	switch req.Action {
+	case models.BulkActionMove:
+		if req.DeckID == nil || !recordExists(s.db, &models.Deck{}, *req.DeckID) {
+			return nil, ErrBulkDeckRequired
+		}
+		return func(tx *gorm.DB, card *models.Card) error {
+			if card.NoteID != nil {
+				return ErrCardManagedByNote
+			}
+			return saveCardWithRevision(tx, card, tagIDsOf(card.Tags), func(card *models.Card) {
+				card.DeckID = *req.DeckID
+			})
+		}, nil
+
+	case models.BulkActionAddTag:
+		if req.TagID == nil || !recordExists(s.db, &models.Tag{}, *req.TagID) {
+			return nil, ErrBulkTagRequired
+		}
+		return func(tx *gorm.DB, card *models.Card) error {
+			if card.NoteID != nil {
+				return ErrCardManagedByNote
+			}
+			tagIDs := append(tagIDsOf(card.Tags), *req.TagID)
+			return saveCardWithRevision(tx, card, tagIDs, func(card *models.Card) {})
+		}, nil
+
+	case models.BulkActionRemoveTag:
+		return func(tx *gorm.DB, card *models.Card) error {
+			if card.NoteID != nil {
+				return ErrCardManagedByNote
+			}
+			tagIDs := []uint{}
+			for _, tagID := range tagIDsOf(card.Tags) {
+				if req.TagID != nil && tagID != *req.TagID {
+					tagIDs = append(tagIDs, tagID)
+				}
+			}
+			return saveCardWithRevision(tx, card, tagIDs, func(card *models.Card) {})
+		}, nil
+
+	case models.BulkActionDelete:
+		return func(tx *gorm.DB, card *models.Card) error {
+			return tx.Delete(card).Error
+		}, nil
+
+	case models.BulkActionSuspend, models.BulkActionUnsuspend:
+		suspended := req.Action == models.BulkActionSuspend
+		return func(tx *gorm.DB, card *models.Card) error {
+			return tx.Model(card).Update("suspended", suspended).Error
+		}, nil
+
+	case models.BulkActionReset:
+		return func(tx *gorm.DB, card *models.Card) error {
+			return tx.Where("card_id = ?", card.ID).Delete(&models.Review{}).Error
+		}, nil
+
+	case models.BulkActionFlag:
+		if req.Flag == nil {
+			return nil, ErrBulkFlagRequired
+		}
+		if !validFlag(*req.Flag) {
+			return nil, ErrInvalidFlag
+		}
+		return func(tx *gorm.DB, card *models.Card) error {
+			return setCardFlag(tx, card.ID, *req.Flag)
+		}, nil
+
+	default:
+		return nil, ErrInvalidBulkAction
+	}
+}
+
+// bulkCardIDs 获取批量操作的卡片ID：指定的ID（保持顺序）加上符合筛选条件的卡片
+func bulkCardIDs(tx *gorm.DB, req models.CardBulkRequest) ([]uint, error) {
+	cardIDs := uniqueIDs(req.CardIDs)
+	if req.Filter == nil {
+		return cardIDs, nil
+	}
+
+	var filtered []uint
+	if err := applyCardFilter(tx.Model(&models.Card{}), *req.Filter).Order("id ASC").Pluck("id", &filtered).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		seen[id] = true
+	}
+	for _, id := range filtered {
+		if !seen[id] {
+			cardIDs = append(cardIDs, id)
+		}
+	}
+
+	return cardIDs, nil
+}
+
+// recordExists 检查记录是否存在且未删除
+func recordExists(db *gorm.DB, model interface{}, id uint) bool {
+	var count int64
+	db.Model(model).Where("id = ?", id).Count(&count)
+	return count > 0
+}
@@ -1,12 +1,21 @@
 package services
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
+	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+// ErrCardManagedByNote 由笔记生成的卡片需要通过笔记修改
+var ErrCardManagedByNote = errors.New("该卡片由笔记生成，请通过笔记接口修改")
+
+// ErrInvalidFlag 标记颜色超出范围
+var ErrInvalidFlag = fmt.Errorf("标记颜色必须在%d-%d之间", models.FlagNone, models.FlagMax)
+
 // CardService 卡片服务
 type CardService struct {
 	db *gorm.DB
@@ -24,197 +33,332 @@ func (s *CardService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// CreateCard 创建卡片
-func (s *CardService) CreateCard(deckID uint, tagID *uint, question, answer string) (*models.Card, error) {
+// CreateCard 创建卡片，同时返回卡包中问题重复的卡片ID；onDuplicate为reject且有重复时不创建并返回ErrDuplicateCard
+func (s *CardService) CreateCard(deckID uint, tagIDs []uint, question, answer, format, onDuplicate string) (*models.Card, []uint, error) {
 	card := &models.Card{
 		DeckID:   deckID,
-		TagID:    tagID,
 		Question: question,
 		Answer:   answer,
+		Format:   normalizeFormat(format),
 	}
 
-	if err := s.db.Create(card).Error; err != nil {
-		return nil, err
+	duplicateIDs, err := s.createCardWithTags(card, tagIDs, onDuplicate)
+	if err != nil {
+		return nil, duplicateIDs, err
+	}
+
+	return card, duplicateIDs, nil
+}
+
+// CreateChoiceCard 创建选择题卡片，answer为可选的解析内容，重复问题的处理与CreateCard相同
+func (s *CardService) CreateChoiceCard(deckID uint, tagIDs []uint, question, answer, format, onDuplicate string, options []models.CardOption) (*models.Card, []uint, error) {
+	options, err := validateOptions(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	card := &models.Card{
+		DeckID:   deckID,
+		Type:     models.CardTypeChoice,
+		Question: question,
+		Answer:   answer,
+		Format:   normalizeFormat(format),
+		Options:  options,
+	}
+
+	duplicateIDs, err := s.createCardWithTags(card, tagIDs, onDuplicate)
+	if err != nil {
+		return nil, duplicateIDs, err
 	}
 
-	return card, nil
+	return card, duplicateIDs, nil
+}
+
+// createCardWithTags 在事务中查重、创建卡片并设置标签，返回卡包中问题重复的卡片ID
+func (s *CardService) createCardWithTags(card *models.Card, tagIDs []uint, onDuplicate string) ([]uint, error) {
+	var duplicateIDs []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if duplicateIDs, err = findDuplicateCards(tx, card.DeckID, card.Question); err != nil {
+			return err
+		}
+		if len(duplicateIDs) > 0 && onDuplicate == models.DuplicateReject {
+			return ErrDuplicateCard
+		}
+
+		tags, err := findTags(tx, tagIDs)
+		if err != nil {
+			return err
+		}
+
+		card.Tags = tags
+		if card.Position, err = nextCardPosition(tx, card.DeckID); err != nil {
+			return err
+		}
+		return tx.Create(card).Error
+	})
+	return duplicateIDs, err
+}
+
+// nextCardPosition 获取卡包中排在最后的位置，新加入卡包的卡片排在已有卡片之后
+func nextCardPosition(tx *gorm.DB, deckID uint) (int, error) {
+	var last int
+	if err := tx.Model(&models.Card{}).Where("deck_id = ?", deckID).Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+	return last + 1, nil
 }
 
 // GetCardByID 根据ID获取卡片
 func (s *CardService) GetCardByID(id uint) (*models.Card, error) {
 	var card models.Card
-	if err := s.db.Preload("Deck").Preload("Tag").First(&card, id).Error; err != nil {
+	if err := s.db.Preload("Deck").Preload("Tags").Preload("Media").Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&card, id).Error; err != nil {
 		return nil, err
 	}
 
 	return &card, nil
 }
 
-// GetCardsByDeckID 根据卡包ID获取卡片列表
-func (s *CardService) GetCardsByDeckID(deckID uint, page, pageSize int) (*models.CardListResponse, error) {
+// GetCardsByDeckID 根据卡包ID获取卡片列表，可以用搜索语句进一步筛选
+func (s *CardService) GetCardsByDeckID(deckID uint, query string, page models.CardPageRequest) (*models.CardListResponse, error) {
+	return s.SearchCards(models.CardSearchRequest{
+		CardFilter:      models.CardFilter{DeckID: &deckID, Query: query},
+		CardPageRequest: page,
+	})
+}
+
+// GetCardsByTagID 根据标签ID获取卡片列表（包括子标签的卡片），可以用搜索语句进一步筛选
+func (s *CardService) GetCardsByTagID(tagID uint, query string, page models.CardPageRequest) (*models.CardListResponse, error) {
+	return s.SearchCards(models.CardSearchRequest{
+		CardFilter:      models.CardFilter{TagID: &tagID, Query: query},
+		CardPageRequest: page,
+	})
+}
+
+// SearchCards 搜索卡片，按指定方式排序，可以按页码或游标分页
+func (s *CardService) SearchCards(req models.CardSearchRequest) (*models.CardListResponse, error) {
 	var cards []models.Card
 	var total int64
 
+	if err := validateCardFilter(req.CardFilter); err != nil {
+		return nil, err
+	}
+
+	// 构建查询条件，启用全文搜索时关键词通过cards_fts匹配并可以按相关度排序
+	filter := req.CardFilter
+	query, ranked := rankByKeyword(s.db.Model(&models.Card{}), filter.Keyword)
+	if ranked {
+		filter.Keyword = ""
+	}
+	sort, err := resolveCardSort(req.CardPageRequest, ranked)
+	if err != nil {
+		return nil, err
+	}
+	query = sort.join(applyCardFilter(query, filter)).Session(&gorm.Session{})
+
 	// 获取总数
-	if err := s.db.Model(&models.Card{}).Where("deck_id = ?", deckID).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, err
 	}
 
-	// 获取分页数据
-	offset := (page - 1) * pageSize
-	if err := s.db.Where("deck_id = ?", deckID).
+	// 获取分页数据，多取一张卡片以判断是否还有下一页
+	page := query
+	if req.Cursor != "" {
+		cursor, err := sort.decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		page = sort.after(page, cursor)
+		req.Page = 0
+	} else {
+		page = page.Offset((req.Page - 1) * req.PageSize)
+	}
+	if err := sort.apply(page).Select("cards.*").
 		Preload("Deck").
-		Preload("Tag").
-		Offset(offset).
-		Limit(pageSize).
-		Order("created_at DESC").
+		Preload("Tags").
+		Limit(req.PageSize + 1).
 		Find(&cards).Error; err != nil {
 		return nil, err
 	}
 
-	// 转换为响应格式
+	var nextCursor string
+	if len(cards) > req.PageSize {
+		cards = cards[:req.PageSize]
+		if nextCursor, err = sort.encodeCursor(query, cards[len(cards)-1].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 转换为响应格式，有关键词时附带高亮摘要
+	terms := highlightTerms(req.CardFilter)
 	var cardResponses []models.CardResponse
 	for _, card := range cards {
-		cardResponse := models.CardResponse{
-			Card: card,
-		}
-		if card.Tag != nil {
-			cardResponse.TagName = card.Tag.Name
-		}
-		cardResponses = append(cardResponses, cardResponse)
+		response := NewCardResponse(card)
+		response.Snippet = cardSnippet(card, terms)
+		cardResponses = append(cardResponses, response)
 	}
 
 	// 计算总页数
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	totalPages := int((total + int64(req.PageSize) - 1) / int64(req.PageSize))
 
 	return &models.CardListResponse{
 		Cards:      cardResponses,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
 		TotalPages: totalPages,
+		NextCursor: nextCursor,
 	}, nil
 }
 
-// GetCardsByTagID 根据标签ID获取卡片列表
-func (s *CardService) GetCardsByTagID(tagID uint, page, pageSize int) (*models.CardListResponse, error) {
-	var cards []models.Card
-	var total int64
+// applyCardFilter 按筛选条件过滤卡片
+func applyCardFilter(query *gorm.DB, filter models.CardFilter) *gorm.DB {
+	if filter.DeckID != nil {
+		query = query.Where("deck_id = ?", *filter.DeckID)
+	}
 
-	// 获取总数
-	if err := s.db.Model(&models.Card{}).Where("tag_id = ?", tagID).Count(&total).Error; err != nil {
-		return nil, err
+	if filter.TagID != nil {
+		query = cardsWithTag(query, *filter.TagID)
 	}
 
-	// 获取分页数据
-	offset := (page - 1) * pageSize
-	if err := s.db.Where("tag_id = ?", tagID).
-		Preload("Deck").
-		Preload("Tag").
-		Offset(offset).
-		Limit(pageSize).
-		Order("created_at DESC").
-		Find(&cards).Error; err != nil {
-		return nil, err
+	for _, tagID := range uniqueIDs(filter.TagIDs) {
+		query = cardsWithTag(query, tagID)
 	}
 
-	// 转换为响应格式
-	var cardResponses []models.CardResponse
-	for _, card := range cards {
-		cardResponse := models.CardResponse{
-			Card: card,
-		}
-		if card.Tag != nil {
-			cardResponse.TagName = card.Tag.Name
+	if filter.Keyword != "" {
+		query = applyKeywordFilter(query, filter.Keyword)
+	}
+
+	if filter.Flag != nil {
+		query = query.Where("flag = ?", *filter.Flag)
+	}
+
+	if filter.Flagged != nil {
+		if *filter.Flagged {
+			query = query.Where("flag <> ?", models.FlagNone)
+		} else {
+			query = query.Where("flag = ?", models.FlagNone)
 		}
-		cardResponses = append(cardResponses, cardResponse)
 	}
 
-	// 计算总页数
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if filter.Query != "" {
+		query = applyCardQuery(query, filter.Query)
+	}
 
-	return &models.CardListResponse{
-		Cards:      cardResponses,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-	}, nil
+	return query
 }
 
-// SearchCards 搜索卡片
-func (s *CardService) SearchCards(req models.CardSearchRequest) (*models.CardListResponse, error) {
-	var cards []models.Card
-	var total int64
+// validateCardFilter 检查筛选条件中的标记颜色和搜索语句
+func validateCardFilter(filter models.CardFilter) error {
+	if filter.Flag != nil && !validFlag(*filter.Flag) {
+		return ErrInvalidFlag
+	}
+	if _, err := parseCardQuery(filter.Query); err != nil {
+		return err
+	}
+	return nil
+}
 
-	// 构建查询条件
-	query := s.db.Model(&models.Card{})
+// emptyCardFilter 判断筛选条件是否没有指定卡包、标签、关键词、标记或搜索语句，即匹配全部卡片
+func emptyCardFilter(filter models.CardFilter) bool {
+	return filter.DeckID == nil && filter.TagID == nil && len(filter.TagIDs) == 0 &&
+		strings.TrimSpace(filter.Keyword) == "" && filter.Flag == nil && filter.Flagged == nil &&
+		strings.TrimSpace(filter.Query) == ""
+}
 
-	if req.DeckID != nil {
-		query = query.Where("deck_id = ?", *req.DeckID)
+// highlightTerms 获取筛选条件中需要在摘要中高亮的关键词和搜索语句中的文本
+func highlightTerms(filter models.CardFilter) []string {
+	terms := strings.Fields(filter.Keyword)
+	if parsed, err := parseCardQuery(filter.Query); err == nil && parsed != nil {
+		terms = append(terms, parsed.terms...)
 	}
+	return terms
+}
 
-	if req.TagID != nil {
-		query = query.Where("tag_id = ?", *req.TagID)
-	}
+// validFlag 判断标记颜色是否在有效范围内
+func validFlag(flag int) bool {
+	return flag >= models.FlagNone && flag <= models.FlagMax
+}
 
-	if req.Keyword != "" {
-		query = query.Where("question LIKE ? OR answer LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+// SetCardFlag 设置卡片的标记颜色，0为清除标记；由笔记生成的卡片同样可以标记
+func (s *CardService) SetCardFlag(id uint, flag int) (*models.Card, error) {
+	if !validFlag(flag) {
+		return nil, ErrInvalidFlag
 	}
 
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
+	if err := setCardFlag(s.db, id, flag); err != nil {
 		return nil, err
 	}
 
-	// 获取分页数据
-	offset := (req.Page - 1) * req.PageSize
-	if err := query.Preload("Deck").
-		Preload("Tag").
-		Offset(offset).
-		Limit(req.PageSize).
-		Order("created_at DESC").
-		Find(&cards).Error; err != nil {
-		return nil, err
+	return s.GetCardByID(id)
+}
+
+// setCardFlag 更新卡片的标记颜色，不记录修订
+func setCardFlag(db *gorm.DB, id uint, flag int) error {
+	result := db.Model(&models.Card{}).Where("id = ?", id).Update("flag", flag)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
+	return nil
+}
 
-	// 转换为响应格式
-	var cardResponses []models.CardResponse
-	for _, card := range cards {
-		cardResponse := models.CardResponse{
-			Card: card,
+// UpdateCard 更新卡片，内容有变化时记录修订；tagIDs为nil时保留原有标签
+func (s *CardService) UpdateCard(id uint, deckID uint, tagIDs *[]uint, question, answer, format string) (*models.Card, error) {
+	var card models.Card
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Tags").First(&card, id).Error; err != nil {
+			return err
 		}
-		if card.Tag != nil {
-			cardResponse.TagName = card.Tag.Name
+
+		if card.NoteID != nil {
+			return ErrCardManagedByNote
 		}
-		cardResponses = append(cardResponses, cardResponse)
-	}
 
-	// 计算总页数
-	totalPages := int((total + int64(req.PageSize) - 1) / int64(req.PageSize))
+		return saveCardWithRevision(tx, &card, tagIDsOrCurrent(tagIDs, card.Tags), func(card *models.Card) {
+			card.DeckID = deckID
+			card.Question = question
+			card.Answer = answer
+			card.Format = normalizeFormat(format)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return &models.CardListResponse{
-		Cards:      cardResponses,
-		Total:      total,
-		Page:       req.Page,
-		PageSize:   req.PageSize,
-		TotalPages: totalPages,
-	}, nil
+	return &card, nil
 }
 
-// UpdateCard 更新卡片
-func (s *CardService) UpdateCard(id uint, deckID uint, tagID *uint, question, answer string) (*models.Card, error) {
-	var card models.Card
-	if err := s.db.First(&card, id).Error; err != nil {
+// UpdateChoiceCard 更新选择题卡片并替换全部选项，tagIDs为nil时保留原有标签
+func (s *CardService) UpdateChoiceCard(id uint, deckID uint, tagIDs *[]uint, question, answer, format string, options []models.CardOption) (*models.Card, error) {
+	options, err := validateOptions(options)
+	if err != nil {
 		return nil, err
 	}
 
-	card.DeckID = deckID
-	card.TagID = tagID
-	card.Question = question
-	card.Answer = answer
+	var card models.Card
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Tags").First(&card, id).Error; err != nil {
+			return err
+		}
+
+		if card.NoteID != nil {
+			return ErrCardManagedByNote
+		}
 
-	if err := s.db.Save(&card).Error; err != nil {
+		return saveCardWithOptions(tx, &card, tagIDsOrCurrent(tagIDs, card.Tags), options, func(card *models.Card) {
+			card.DeckID = deckID
+			card.Type = models.CardTypeChoice
+			card.Question = question
+			card.Answer = answer
+			card.Format = normalizeFormat(format)
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
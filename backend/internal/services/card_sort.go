@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flashcard/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCardSort 排序方式或排序方向无效
+var ErrInvalidCardSort = errors.New("无效的排序方式")
+
+// ErrInvalidCursor 分页游标无效
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// cardSortColumns 各排序方式对应的排序表达式；复习相关的排序通过sort_reviews连接reviews表，
+// 新卡片没有复习记录，按默认值参与排序
+var cardSortColumns = map[string]string{
+	models.CardSortCreated:   "cards.created_at",
+	models.CardSortUpdated:   "cards.updated_at",
+	models.CardSortDue:       "COALESCE(sort_reviews.next_review, cards.created_at)",
+	models.CardSortInterval:  "COALESCE(sort_reviews.interval, 0)",
+	models.CardSortEase:      "COALESCE(sort_reviews.e_factor, 2.5)",
+	models.CardSortLapses:    "COALESCE(sort_reviews.lapses, 0)",
+	models.CardSortQuestion:  "cards.question COLLATE NOCASE",
+	models.CardSortPosition:  "cards.position",
+	models.CardSortRelevance: "-fts.rank", // bm25越小越相关，取负数后越大越相关
+}
+
+// cardSortDescending 未指定排序方向时默认降序的排序方式，其余默认升序
+var cardSortDescending = map[string]bool{
+	models.CardSortCreated:   true,
+	models.CardSortUpdated:   true,
+	models.CardSortRelevance: true,
+}
+
+// cardSort 卡片列表的排序方式，相同排序值的卡片按ID以相同方向排列，保证顺序稳定
+type cardSort struct {
+	key        string
+	descending bool
+}
+
+// cardCursor 分页游标的内容：排序方式、方向和上一页最后一张卡片的排序值及ID
+type cardCursor struct {
+	Sort  string      `json:"s"`
+	Order string      `json:"o"`
+	Value interface{} `json:"v,omitempty"`
+	Time  *time.Time  `json:"t,omitempty"` // 排序值为时间时保存在这里，以便按原类型比较
+	ID    uint        `json:"id"`
+}
+
+// resolveCardSort 确定卡片列表的排序方式：未指定时有关键词相关度则按相关度，否则按创建时间；
+// 没有相关度时按相关度排序同样改为按创建时间
+func resolveCardSort(page models.CardPageRequest, ranked bool) (cardSort, error) {
+	key := page.Sort
+	if key == "" || (key == models.CardSortRelevance && !ranked) {
+		key = models.CardSortCreated
+		if ranked && page.Sort == "" {
+			key = models.CardSortRelevance
+		}
+	}
+	if _, ok := cardSortColumns[key]; !ok {
+		return cardSort{}, fmt.Errorf("%w: 排序方式必须是 created、updated、due、interval、ease、lapses、question、position、relevance 之一", ErrInvalidCardSort)
+	}
+
+	sort := cardSort{key: key, descending: cardSortDescending[key]}
+	switch page.Order {
+	case "":
+	case "asc":
+		sort.descending = false
+	case "desc":
+		sort.descending = true
+	default:
+		return cardSort{}, fmt.Errorf("%w: 排序方向必须是 asc 或 desc", ErrInvalidCardSort)
+	}
+	return sort, nil
+}
+
+// order 排序方向的SQL关键字
+func (s cardSort) order() string {
+	if s.descending {
+		return "desc"
+	}
+	return "asc"
+}
+
+// join 按复习属性排序时连接卡片的复习记录
+func (s cardSort) join(query *gorm.DB) *gorm.DB {
+	switch s.key {
+	case models.CardSortDue, models.CardSortInterval, models.CardSortEase, models.CardSortLapses:
+		return query.Joins("LEFT JOIN reviews AS sort_reviews ON sort_reviews.card_id = cards.id")
+	}
+	return query
+}
+
+// apply 按排序方式排列查询结果
+func (s cardSort) apply(query *gorm.DB) *gorm.DB {
+	direction := " " + s.order()
+	return query.Order(cardSortColumns[s.key] + direction).Order("cards.id" + direction)
+}
+
+// after 只保留排在游标之后的卡片
+func (s cardSort) after(query *gorm.DB, cursor cardCursor) *gorm.DB {
+	column := cardSortColumns[s.key]
+	operator := ">"
+	if s.descending {
+		operator = "<"
+	}
+
+	var value interface{} = cursor.Value
+	if cursor.Time != nil {
+		value = *cursor.Time
+	}
+	return query.Where(
+		fmt.Sprintf("(%s %s ? OR (%s = ? AND cards.id %s ?))", column, operator, column, operator),
+		value, value, cursor.ID,
+	)
+}
+
+// encodeCursor 生成指向指定卡片之后位置的游标，query为连接了排序所需表的筛选查询
+func (s cardSort) encodeCursor(query *gorm.DB, cardID uint) (string, error) {
+	var value interface{}
+	row := query.Where("cards.id = ?", cardID).Select(cardSortColumns[s.key]).Row()
+	if err := row.Scan(&value); err != nil {
+		return "", err
+	}
+
+	cursor := cardCursor{Sort: s.key, Order: s.order(), ID: cardID}
+	switch v := value.(type) {
+	case time.Time:
+		cursor.Time = &v
+	case []byte:
+		cursor.Value = string(v)
+	default:
+		cursor.Value = v
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解析游标，游标的排序方式和方向必须与本次请求一致
+func (s cardSort) decodeCursor(encoded string) (cardCursor, error) {
+	var cursor cardCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &cursor) != nil || cursor.ID == 0 {
+		return cardCursor{}, ErrInvalidCursor
+	}
+	if cursor.Sort != s.key || cursor.Order != s.order() {
+		return cardCursor{}, fmt.Errorf("%w: 游标的排序方式与请求不一致", ErrInvalidCursor)
+	}
+	return cursor, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"math/rand"
+	"strings"
+)
+
+var (
+	// ErrInvalidOptions 选择题选项不合法
+	ErrInvalidOptions = errors.New("选择题至少需要两个非空选项，且至少有一个正确选项")
+	// ErrNotChoiceCard 卡片不是选择题
+	ErrNotChoiceCard = errors.New("该卡片不是选择题")
+	// ErrInvalidChoice 提交的选项不属于该卡片
+	ErrInvalidChoice = errors.New("提交的选项不属于该卡片")
+	// ErrChoicesRequired 选择题复习未提交选项
+	ErrChoicesRequired = errors.New("选择题需要提交所选选项，由服务端评分")
+)
+
+// validateOptions 校验选择题选项并按提交顺序设置位置
+func validateOptions(options []models.CardOption) ([]models.CardOption, error) {
+	if len(options) < 2 {
+		return nil, ErrInvalidOptions
+	}
+
+	hasCorrect := false
+	result := make([]models.CardOption, len(options))
+	for i, option := range options {
+		content := strings.TrimSpace(option.Content)
+		if content == "" {
+			return nil, ErrInvalidOptions
+		}
+		if option.Correct {
+			hasCorrect = true
+		}
+		result[i] = models.CardOption{
+			Position: i,
+			Content:  content,
+			Correct:  option.Correct,
+		}
+	}
+
+	if !hasCorrect {
+		return nil, ErrInvalidOptions
+	}
+
+	return result, nil
+}
+
+// shuffleOptions 打乱选项顺序，返回不含正确答案的学习选项以及是否多选
+func shuffleOptions(options []models.CardOption) ([]models.StudyOption, bool) {
+	studyOptions := make([]models.StudyOption, len(options))
+	correctCount := 0
+	for i, option := range options {
+		studyOptions[i] = models.StudyOption{ID: option.ID, Content: option.Content}
+		if option.Correct {
+			correctCount++
+		}
+	}
+
+	rand.Shuffle(len(studyOptions), func(i, j int) {
+		studyOptions[i], studyOptions[j] = studyOptions[j], studyOptions[i]
+	})
+
+	return studyOptions, correctCount > 1
+}
+
+// gradeChoices 根据提交的选项评分：完全正确为Good，只漏选正确选项为Hard，选错或未选为Again
+func gradeChoices(options []models.CardOption, choices []uint) (models.ReviewResult, []uint, error) {
+	correctByID := make(map[uint]bool, len(options))
+	var correctIDs []uint
+	for _, option := range options {
+		correctByID[option.ID] = option.Correct
+		if option.Correct {
+			correctIDs = append(correctIDs, option.ID)
+		}
+	}
+
+	selected := make(map[uint]bool, len(choices))
+	wrong := false
+	for _, id := range choices {
+		correct, exists := correctByID[id]
+		if !exists {
+			return models.Again, nil, ErrInvalidChoice
+		}
+		if !correct {
+			wrong = true
+		}
+		selected[id] = true
+	}
+
+	switch {
+	case wrong:
+		return models.Again, correctIDs, nil
+	case len(selected) == len(correctIDs):
+		return models.Good, correctIDs, nil
+	case len(selected) > 0:
+		return models.Hard, correctIDs, nil
+	default:
+		return models.Again, correctIDs, nil
+	}
+}
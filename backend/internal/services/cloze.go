@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCloze 填空笔记内容中没有填空标记
+var ErrNoCloze = errors.New("填空内容中没有找到 {{c1::答案}} 格式的填空标记")
+
+// clozePattern 匹配 {{c1::答案}} 或 {{c1::答案::提示}}
+var clozePattern = regexp.MustCompile(`\{\{c(\d+)::(.+?)(?:::([^{}]*?))?\}\}`)
+
+// hasCloze 判断文本中是否包含填空标记
+func hasCloze(text string) bool {
+	return clozePattern.MatchString(text)
+}
+
+// parseClozeOrds 解析文本中出现的所有填空编号（升序、去重）
+func parseClozeOrds(text string) []int {
+	seen := make(map[int]bool)
+	var ords []int
+	for _, match := range clozePattern.FindAllStringSubmatch(text, -1) {
+		ord, err := strconv.Atoi(match[1])
+		if err != nil || ord < 1 || seen[ord] {
+			continue
+		}
+		seen[ord] = true
+		ords = append(ords, ord)
+	}
+	sort.Ints(ords)
+	return ords
+}
+
+// renderClozeFront 渲染填空卡片正面：当前编号的填空替换为 [...] 或 [提示]，其余填空显示答案
+func renderClozeFront(text string, ord int) string {
+	return clozePattern.ReplaceAllStringFunc(text, func(m string) string {
+		match := clozePattern.FindStringSubmatch(m)
+		if match[1] != strconv.Itoa(ord) {
+			return match[2]
+		}
+		if hint := strings.TrimSpace(match[3]); hint != "" {
+			return "[" + hint + "]"
+		}
+		return "[...]"
+	})
+}
+
+// renderClozeBack 渲染填空卡片背面：显示全部答案，并附加背面内容
+func renderClozeBack(text, extra string) string {
+	back := clozePattern.ReplaceAllString(text, "$2")
+	if extra = strings.TrimSpace(extra); extra != "" {
+		back += "\n\n" + extra
+	}
+	return back
+}
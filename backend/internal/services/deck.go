@@ -1,12 +1,28 @@
 package services
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// 卡包层级相关错误
+var (
+	ErrInvalidDeckParent = errors.New("上级卡包不存在")
+	ErrDeckCycle         = errors.New("不能将卡包移动到自身或其子卡包下")
+	ErrDeckHasChildren   = errors.New("卡包包含子卡包，请指定删除子卡包（delete）还是将其移动到上级（reparent）")
+	ErrDeckNameExists    = errors.New("卡包名称已存在")
+)
+
+// activeDeckSubtree 查询卡包及其所有未删除子孙卡包的ID，参数为卡包ID
+const activeDeckSubtree = "WITH RECURSIVE subtree(id) AS (" +
+	"SELECT id FROM decks WHERE id = ? AND deleted_at IS NULL " +
+	"UNION SELECT decks.id FROM decks JOIN subtree ON decks.parent_id = subtree.id WHERE decks.deleted_at IS NULL) " +
+	"SELECT id FROM subtree"
+
 // DeckService 卡包服务
 type DeckService struct {
 	db *gorm.DB
@@ -24,10 +40,23 @@ func (s *DeckService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// CreateDeck 创建卡包
-func (s *DeckService) CreateDeck(name string) (*models.Deck, error) {
+// CreateDeck 创建卡包，parentID为空时创建顶层卡包
+func (s *DeckService) CreateDeck(name string, parentID *uint) (*models.Deck, error) {
 	deck := &models.Deck{
-		Name: name,
+		Name:     name,
+		ParentID: parentID,
+	}
+
+	if err := checkDeckParent(s.db, deck); err != nil {
+		return nil, err
+	}
+
+	exists, err := deckNameExists(s.db, parentID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrDeckNameExists
 	}
 
 	if err := s.db.Create(deck).Error; err != nil {
@@ -57,19 +86,38 @@ func (s *DeckService) GetAllDecks() ([]models.Deck, error) {
 	return decks, nil
 }
 
-// UpdateDeck 更新卡包
-func (s *DeckService) UpdateDeck(id uint, name string, archived *bool) (*models.Deck, error) {
+// UpdateDeck 更新卡包，未提供的字段保持不变
+func (s *DeckService) UpdateDeck(id uint, req models.UpdateDeckRequest) (*models.Deck, error) {
 	var deck models.Deck
 	if err := s.db.First(&deck, id).Error; err != nil {
 		return nil, err
 	}
 
-	if name != "" {
-		deck.Name = name
+	if req.Name != nil && *req.Name != "" && *req.Name != deck.Name {
+		exists, err := deckNameExists(s.db, deck.ParentID, *req.Name, deck.ID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrDeckNameExists
+		}
+		deck.Name = *req.Name
+	}
+
+	if req.Archived != nil {
+		deck.Archived = *req.Archived
+	}
+
+	if req.StudyLimit != nil {
+		deck.StudyLimit = *req.StudyLimit
 	}
 
-	if archived != nil {
-		deck.Archived = *archived
+	if req.NewCardOrder != nil {
+		deck.NewCardOrder = *req.NewCardOrder
+	}
+
+	if err := applyDetails(&deck.Details, req.DetailsUpdate); err != nil {
+		return nil, err
 	}
 
 	if err := s.db.Save(&deck).Error; err != nil {
@@ -79,22 +127,110 @@ func (s *DeckService) UpdateDeck(id uint, name string, archived *bool) (*models.
 	return &deck, nil
 }
 
-// DeleteDeck 删除卡包
-func (s *DeckService) DeleteDeck(id uint) error {
-	if err := s.db.Delete(&models.Deck{}, id).Error; err != nil {
-		return err
+// MoveDeck 将卡包及其子卡包移动到新的上级卡包下，parentID为空时移动到顶层
+func (s *DeckService) MoveDeck(id uint, parentID *uint) (*models.Deck, error) {
+	var deck models.Deck
+	if err := s.db.First(&deck, id).Error; err != nil {
+		return nil, err
 	}
 
-	return nil
+	deck.ParentID = parentID
+	if err := checkDeckParent(s.db, &deck); err != nil {
+		return nil, err
+	}
+
+	exists, err := deckNameExists(s.db, parentID, deck.Name, deck.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrDeckNameExists
+	}
+
+	if err := s.db.Model(&deck).Update("parent_id", parentID).Error; err != nil {
+		return nil, err
+	}
+
+	return &deck, nil
+}
+
+// DeleteDeck 删除卡包，卡包下的标签、笔记和卡片一起移入回收站；
+// 包含子卡包时children指定子卡包一起删除还是移动到被删除卡包的上级
+func (s *DeckService) DeleteDeck(id uint, children string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var childCount int64
+		if err := tx.Model(&models.Deck{}).Where("parent_id = ?", id).Count(&childCount).Error; err != nil {
+			return err
+		}
+
+		ids := []uint{id}
+		if childCount > 0 {
+			switch children {
+			case models.DeckChildrenDelete:
+				subtree, err := deckSubtreeIDs(tx, id)
+				if err != nil {
+					return err
+				}
+				ids = subtree
+			case models.DeckChildrenReparent:
+				var deck models.Deck
+				if err := tx.First(&deck, id).Error; err != nil {
+					return err
+				}
+				// 先删除卡包再移动子卡包，子卡包可以与被删除的卡包同名
+				if err := softDeleteDecks(tx, ids); err != nil {
+					return err
+				}
+				_, err := reparentDecks(tx, id, deck.ParentID)
+				return err
+			default:
+				return ErrDeckHasChildren
+			}
+		}
+
+		return softDeleteDecks(tx, ids)
+	})
+}
+
+// reparentDecks 将卡包的子卡包移动到新的上级卡包下，返回移动的卡包数；与新的上级卡包下的卡包同名时返回ErrDeckNameExists
+func reparentDecks(tx *gorm.DB, fromID uint, toID *uint) (int64, error) {
+	var count int64
+	siblings := whereParentID(tx.Model(&models.Deck{}), toID).Select("name")
+	if err := tx.Model(&models.Deck{}).Where("parent_id = ? AND name IN (?)", fromID, siblings).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrDeckNameExists
+	}
+
+	moved := tx.Model(&models.Deck{}).Where("parent_id = ?", fromID).Update("parent_id", toID)
+	return moved.RowsAffected, moved.Error
 }
 
-// GetDeckStats 获取卡包统计信息
+// softDeleteDecks 将卡包及其标签、笔记和卡片以相同的删除时间移入回收站
+func softDeleteDecks(tx *gorm.DB, ids []uint) error {
+	now := time.Now()
+	for _, model := range []interface{}{&models.Card{}, &models.Note{}, &models.Tag{}} {
+		if err := softDeleteWith(tx, now, model, "deck_id IN ?", ids); err != nil {
+			return err
+		}
+	}
+
+	return softDeleteWith(tx, now, &models.Deck{}, "id IN ?", ids)
+}
+
+// GetDeckStats 获取卡包统计信息，包括所有子卡包
 func (s *DeckService) GetDeckStats(deckID uint) (*models.DeckStats, error) {
 	stats := &models.DeckStats{}
 	var count int64
 
+	deckIDs, err := deckSubtreeIDs(s.db, deckID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 获取总卡片数
-	if err := s.db.Model(&models.Card{}).Where("deck_id = ?", deckID).Count(&count).Error; err != nil {
+	if err := s.db.Model(&models.Card{}).Where("deck_id IN ?", deckIDs).Count(&count).Error; err != nil {
 		return nil, err
 	}
 	stats.TotalCards = int(count)
@@ -104,7 +240,8 @@ func (s *DeckService) GetDeckStats(deckID uint) (*models.DeckStats, error) {
 		Table("cards").
 		Select("COUNT(cards.id)").
 		Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
-		Where("cards.deck_id = ?", deckID).
+		Where("cards.deck_id IN ?", deckIDs).
+		Where("cards.deleted_at IS NULL").
 		Where("(reviews.next_review <= date('now') OR reviews.id IS NULL)")
 
 	if err := query.Scan(&count).Error; err != nil {
@@ -113,7 +250,7 @@ func (s *DeckService) GetDeckStats(deckID uint) (*models.DeckStats, error) {
 	stats.DueCards = int(count)
 
 	// 获取标签数量
-	if err := s.db.Model(&models.Tag{}).Where("deck_id = ?", deckID).Count(&count).Error; err != nil {
+	if err := s.db.Model(&models.Tag{}).Where("deck_id IN ?", deckIDs).Count(&count).Error; err != nil {
 		return nil, err
 	}
 	stats.TagCount = int(count)
@@ -125,25 +262,138 @@ func (s *DeckService) GetDeckStats(deckID uint) (*models.DeckStats, error) {
 	return stats, nil
 }
 
-// GetAllDecksWithStats 获取所有卡包及其统计信息
+// GetAllDecksWithStats 获取所有卡包及其统计信息，上级卡包的统计包括所有子卡包
 func (s *DeckService) GetAllDecksWithStats() ([]models.DeckWithStats, error) {
 	var decks []models.Deck
 	if err := s.db.Find(&decks).Error; err != nil {
 		return nil, err
 	}
 
-	var result []models.DeckWithStats
-	for _, deck := range decks {
-		stats, err := s.GetDeckStats(deck.ID)
-		if err != nil {
-			return nil, err
+	// 使用分组聚合查询一次性统计所有卡包，避免逐个卡包查询
+	cardCountsByDeck, err := countCardsBy(s.db, "cards.deck_id", func(query *gorm.DB) *gorm.DB {
+		return query
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagCountsByDeck, err := countTagsByDeck(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	children := deckChildren(decks)
+	var sumStats func(deckID uint) models.DeckStats
+	sumStats = func(deckID uint) models.DeckStats {
+		counts := cardCountsByDeck[deckID]
+		stats := models.DeckStats{
+			TotalCards: counts.Total,
+			DueCards:   counts.Due,
+			TagCount:   tagCountsByDeck[deckID],
 		}
+		for _, child := range children[deckID] {
+			childStats := sumStats(child.ID)
+			stats.TotalCards += childStats.TotalCards
+			stats.DueCards += childStats.DueCards
+			stats.TagCount += childStats.TagCount
+		}
+		return stats
+	}
 
+	var result []models.DeckWithStats
+	for _, deck := range decks {
 		result = append(result, models.DeckWithStats{
 			Deck:  deck,
-			Stats: *stats,
+			Stats: sumStats(deck.ID),
 		})
 	}
 
 	return result, nil
 }
+
+// GetDeckTree 获取卡包树，上级卡包已删除的卡包作为顶层卡包
+func (s *DeckService) GetDeckTree() ([]models.DeckTreeNode, error) {
+	decks, err := s.GetAllDecksWithStats()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]bool, len(decks))
+	for _, deck := range decks {
+		byID[deck.ID] = true
+	}
+
+	childrenOf := make(map[uint][]models.DeckWithStats)
+	var roots []models.DeckWithStats
+	for _, deck := range decks {
+		if deck.ParentID != nil && byID[*deck.ParentID] {
+			childrenOf[*deck.ParentID] = append(childrenOf[*deck.ParentID], deck)
+		} else {
+			roots = append(roots, deck)
+		}
+	}
+
+	var build func(decks []models.DeckWithStats) []models.DeckTreeNode
+	build = func(decks []models.DeckWithStats) []models.DeckTreeNode {
+		nodes := []models.DeckTreeNode{}
+		for _, deck := range decks {
+			nodes = append(nodes, models.DeckTreeNode{
+				DeckWithStats: deck,
+				Children:      build(childrenOf[deck.ID]),
+			})
+		}
+		return nodes
+	}
+
+	return build(roots), nil
+}
+
+// deckSubtreeIDs 获取卡包及其所有未删除子孙卡包的ID
+func deckSubtreeIDs(tx *gorm.DB, deckID uint) ([]uint, error) {
+	var ids []uint
+	if err := tx.Raw(activeDeckSubtree, deckID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// deckChildren 按上级卡包分组卡包列表
+func deckChildren(decks []models.Deck) map[uint][]models.Deck {
+	children := make(map[uint][]models.Deck)
+	for _, deck := range decks {
+		if deck.ParentID != nil {
+			children[*deck.ParentID] = append(children[*deck.ParentID], deck)
+		}
+	}
+	return children
+}
+
+// checkDeckParent 检查上级卡包存在，且不是卡包自身或其子孙卡包
+func checkDeckParent(tx *gorm.DB, deck *models.Deck) error {
+	if deck.ParentID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.Deck{}).Where("id = ?", *deck.ParentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInvalidDeckParent
+	}
+
+	if deck.ID == 0 {
+		return nil
+	}
+	ids, err := deckSubtreeIDs(tx, deck.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id == *deck.ParentID {
+			return ErrDeckCycle
+		}
+	}
+
+	return nil
+}
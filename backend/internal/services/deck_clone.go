@@ -0,0 +1,319 @@
+package services
+
+import (
+	"flashcard/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CloneDeck 复制卡包的设置、描述等信息、标签、笔记和卡片（不包括子卡包），复制的卡片保持原来的顺序；
+// 指定复制复习进度时同时复制复习记录、复习日志和暂停状态，否则复制的卡片都是新卡片
+func (s *DeckService) CloneDeck(id uint, req models.DeckCloneRequest) (*models.DeckCloneResult, error) {
+	result := &models.DeckCloneResult{SourceID: id, IncludeScheduling: req.IncludeScheduling}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var source models.Deck
+		if err := tx.First(&source, id).Error; err != nil {
+			return err
+		}
+
+		name, err := cloneDeckName(tx, source.ParentID, source.Name, req.Name)
+		if err != nil {
+			return err
+		}
+
+		deck := models.Deck{
+			Name:         name,
+			ParentID:     source.ParentID,
+			StudyLimit:   source.StudyLimit,
+			NewCardOrder: source.NewCardOrder,
+			Details:      source.Details,
+		}
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+		result.Deck = &deck
+
+		cloner := &deckCloner{
+			tx:         tx,
+			deckID:     deck.ID,
+			scheduling: req.IncludeScheduling,
+			tags:       make(map[uint]uint),
+			result:     result,
+		}
+		if err := cloner.cloneTags(source.ID); err != nil {
+			return err
+		}
+		return cloner.cloneCards(source.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// cloneDeckName 确定复制的卡包名称：指定的名称在同一上级卡包下已存在时返回 ErrDeckNameExists；
+// 未指定时使用“原名称 (副本)”，已存在时依次添加编号
+func cloneDeckName(tx *gorm.DB, parentID *uint, sourceName, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name != "" {
+		exists, err := deckNameExists(tx, parentID, name, 0)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", ErrDeckNameExists
+		}
+		return name, nil
+	}
+
+	name = sourceName + " (副本)"
+	for i := 2; ; i++ {
+		exists, err := deckNameExists(tx, parentID, name, 0)
+		if err != nil || !exists {
+			return name, err
+		}
+		name = fmt.Sprintf("%s (副本 %d)", sourceName, i)
+	}
+}
+
+// deckNameExists 检查同一上级卡包下（parentID为空时为顶层）是否已有同名的未删除卡包，excludeID为要排除的卡包
+func deckNameExists(tx *gorm.DB, parentID *uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	query := whereParentID(tx.Model(&models.Deck{}), parentID).Where("name = ? AND id <> ?", name, excludeID)
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// whereParentID 按上级卡包筛选卡包，parentID为空时筛选顶层卡包
+func whereParentID(query *gorm.DB, parentID *uint) *gorm.DB {
+	if parentID == nil {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", *parentID)
+}
+
+// deckCloner 将卡包的标签、笔记和卡片复制到新卡包
+type deckCloner struct {
+	tx         *gorm.DB
+	deckID     uint
+	scheduling bool
+	tags       map[uint]uint // 源卡包的标签ID到复制的标签ID
+	result     *models.DeckCloneResult
+}
+
+// cloneTags 复制卡包的标签树，上级标签先于子标签复制
+func (c *deckCloner) cloneTags(sourceID uint) error {
+	var tags []models.Tag
+	if err := c.tx.Where("deck_id = ?", sourceID).Find(&tags).Error; err != nil {
+		return err
+	}
+	sortTagsByPath(tags)
+
+	for _, tag := range tags {
+		clone := models.Tag{DeckID: &c.deckID, Name: tag.Name, Path: tag.Path, Details: tag.Details}
+		if tag.ParentID != nil {
+			if parentID, ok := c.tags[*tag.ParentID]; ok {
+				clone.ParentID = &parentID
+			}
+		}
+		if err := c.tx.Create(&clone).Error; err != nil {
+			return err
+		}
+		c.tags[tag.ID] = clone.ID
+	}
+	c.result.Tags = len(tags)
+
+	return nil
+}
+
+// tagIDs 获取复制后的标签ID，不属于源卡包的标签保持不变
+func (c *deckCloner) tagIDs(tags []models.Tag) []uint {
+	ids := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		if id, ok := c.tags[tag.ID]; ok {
+			ids = append(ids, id)
+		} else {
+			ids = append(ids, tag.ID)
+		}
+	}
+	return ids
+}
+
+// cloneCards 按顺序复制卡片，笔记生成的卡片通过复制笔记重新生成，再设置为原卡片的位置和标记；
+// 原卡片已删除的编号重新生成的卡片随后彻底删除
+func (c *deckCloner) cloneCards(sourceID uint) error {
+	var cards []models.Card
+	if err := c.tx.Preload("Tags").Preload("Options").Preload("Media").
+		Where("deck_id = ?", sourceID).Order(cardPositionOrder).Find(&cards).Error; err != nil {
+		return err
+	}
+
+	noteCards := make(map[uint]map[int]uint) // 源笔记ID到复制的笔记生成的卡片ID（按编号）
+	used := make(map[uint]bool)              // 对应源卡包中未删除卡片的生成卡片ID
+	for _, card := range cards {
+		if card.NoteID == nil {
+			clone, err := c.cloneCard(card)
+			if err != nil {
+				return err
+			}
+			if err := c.cloneScheduling(card.ID, clone.ID); err != nil {
+				return err
+			}
+			c.result.Cards++
+			continue
+		}
+
+		generated, ok := noteCards[*card.NoteID]
+		if !ok {
+			var err error
+			if generated, err = c.cloneNote(*card.NoteID); err != nil {
+				return err
+			}
+			noteCards[*card.NoteID] = generated
+		}
+		cloneID, ok := generated[card.Ord]
+		if !ok {
+			continue
+		}
+		used[cloneID] = true
+
+		updates := map[string]interface{}{"position": card.Position, "flag": card.Flag}
+		if c.scheduling {
+			updates["suspended"] = card.Suspended
+		}
+		if err := c.tx.Model(&models.Card{}).Where("id = ?", cloneID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		if err := c.cloneScheduling(card.ID, cloneID); err != nil {
+			return err
+		}
+		c.result.Cards++
+	}
+
+	var unused []uint
+	for _, generated := range noteCards {
+		for _, id := range generated {
+			if !used[id] {
+				unused = append(unused, id)
+			}
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	_, err := purgeCards(c.tx, "id IN ?", unused)
+	return err
+}
+
+// cloneCard 复制不属于笔记的卡片及其选项、标签和引用的媒体
+func (c *deckCloner) cloneCard(card models.Card) (*models.Card, error) {
+	tags, err := findTags(c.tx, c.tagIDs(card.Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	clone := &models.Card{
+		DeckID:   c.deckID,
+		Type:     card.Type,
+		Ord:      card.Ord,
+		Question: card.Question,
+		Answer:   card.Answer,
+		Format:   card.Format,
+		Masks:    card.Masks,
+		Flag:     card.Flag,
+		Position: card.Position,
+		Tags:     tags,
+		Media:    card.Media,
+	}
+	if c.scheduling {
+		clone.Suspended = card.Suspended
+	}
+	for _, option := range card.Options {
+		clone.Options = append(clone.Options, models.CardOption{
+			Position: option.Position,
+			Content:  option.Content,
+			Correct:  option.Correct,
+		})
+	}
+
+	if err := c.tx.Create(clone).Error; err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// cloneNote 复制笔记并生成卡片，返回生成的卡片ID（按编号）
+func (c *deckCloner) cloneNote(noteID uint) (map[int]uint, error) {
+	var note models.Note
+	if err := c.tx.Preload("Tags").First(&note, noteID).Error; err != nil {
+		return nil, err
+	}
+
+	tags, err := findTags(c.tx, c.tagIDs(note.Tags))
+	if err != nil {
+		return nil, err
+	}
+	clone := &models.Note{
+		DeckID:     c.deckID,
+		Type:       note.Type,
+		NoteTypeID: note.NoteTypeID,
+		Fields:     note.Fields,
+		Content:    note.Content,
+		Extra:      note.Extra,
+		MediaID:    note.MediaID,
+		Masks:      note.Masks,
+		Tags:       tags,
+	}
+	if err := createNoteWithCards(c.tx, clone); err != nil {
+		return nil, err
+	}
+
+	var cards []models.Card
+	if err := c.tx.Select("id", "ord").Where("note_id = ?", clone.ID).Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	generated := make(map[int]uint, len(cards))
+	for _, card := range cards {
+		generated[card.Ord] = card.ID
+	}
+	c.result.Notes++
+
+	return generated, nil
+}
+
+// cloneScheduling 复制卡片的复习记录和复习日志，不复制复习进度时什么也不做
+func (c *deckCloner) cloneScheduling(sourceID, cloneID uint) error {
+	if !c.scheduling {
+		return nil
+	}
+
+	var reviews []models.Review
+	if err := c.tx.Where("card_id = ?", sourceID).Find(&reviews).Error; err != nil {
+		return err
+	}
+	for _, review := range reviews {
+		review.ID = 0
+		review.CardID = cloneID
+		if err := c.tx.Create(&review).Error; err != nil {
+			return err
+		}
+	}
+
+	var logs []models.ReviewLog
+	if err := c.tx.Where("card_id = ?", sourceID).Order("id ASC").Find(&logs).Error; err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	for i := range logs {
+		logs[i].ID = 0
+		logs[i].CardID = cloneID
+	}
+	return c.tx.Create(&logs).Error
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrDeckMergeCycle 不能将卡包合并到自身或其子卡包
+var ErrDeckMergeCycle = errors.New("不能将卡包合并到自身或其子卡包")
+
+// MergeDeck 将源卡包合并到目标卡包后删除源卡包：标签按路径与目标卡包的标签合并，卡片和笔记按顺序
+// 排在目标卡包的最后，问题重复的卡片按导入选项处理，子卡包移动到目标卡包下，源卡包和未移动的内容移入回收站
+func (s *DeckService) MergeDeck(sourceID uint, req models.DeckMergeRequest) (*models.DeckMergeResult, error) {
+	result := &models.DeckMergeResult{SourceID: sourceID, DuplicateCardIDs: []uint{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.Deck{}, sourceID).Error; err != nil {
+			return err
+		}
+		var target models.Deck
+		if err := tx.First(&target, req.TargetID).Error; err != nil {
+			return err
+		}
+
+		subtree, err := deckSubtreeIDs(tx, sourceID)
+		if err != nil {
+			return err
+		}
+		for _, id := range subtree {
+			if id == target.ID {
+				return ErrDeckMergeCycle
+			}
+		}
+
+		if err := mergeDeckTags(tx, sourceID, target.ID, result); err != nil {
+			return err
+		}
+
+		im, err := newCardImporter(tx, &target, req.OnDuplicate)
+		if err != nil {
+			return err
+		}
+		if err := mergeDeckCards(im, sourceID, target.ID, result); err != nil {
+			return err
+		}
+
+		// 先删除源卡包再移动子卡包，子卡包可以与源卡包同名
+		if err := softDeleteDecks(tx, []uint{sourceID}); err != nil {
+			return err
+		}
+		moved, err := reparentDecks(tx, sourceID, &target.ID)
+		if err != nil {
+			return err
+		}
+		result.MovedDecks = int(moved)
+
+		if err := tx.First(&target, target.ID).Error; err != nil {
+			return err
+		}
+		result.Deck = &target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// mergeDeckTags 将源卡包的标签合并到目标卡包：目标卡包中已有相同路径的标签时，卡片和笔记改用该标签，
+// 源标签随源卡包删除；否则将标签（保留ID）移动到目标卡包中对应的上级标签下
+func mergeDeckTags(tx *gorm.DB, sourceID, targetID uint, result *models.DeckMergeResult) error {
+	var existing []models.Tag
+	if err := tx.Where("deck_id = ?", targetID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byPath := make(map[string]uint, len(existing))
+	for _, tag := range existing {
+		byPath[tagPathOf(tag)] = tag.ID
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("deck_id = ?", sourceID).Find(&tags).Error; err != nil {
+		return err
+	}
+	sortTagsByPath(tags)
+
+	merged := make(map[uint]uint, len(tags)) // 源标签ID到合并后的标签ID
+	for _, tag := range tags {
+		path := tagPathOf(tag)
+		if id, ok := byPath[path]; ok {
+			if err := replaceTagReferences(tx, tag.ID, id); err != nil {
+				return err
+			}
+			merged[tag.ID] = id
+			result.MergedTags++
+			continue
+		}
+
+		parentID := tag.ParentID
+		if parentID != nil {
+			if id, ok := merged[*parentID]; ok {
+				parentID = &id
+			}
+		}
+		err := tx.Model(&tag).Updates(map[string]interface{}{"deck_id": targetID, "parent_id": parentID}).Error
+		if err != nil {
+			return err
+		}
+		merged[tag.ID] = tag.ID
+		byPath[path] = tag.ID
+		result.MovedTags++
+	}
+
+	return nil
+}
+
+// replaceTagReferences 将卡片和笔记的标签从一个标签替换为另一个标签，已有该标签的不重复添加
+func replaceTagReferences(tx *gorm.DB, fromID, toID uint) error {
+	for _, table := range []struct{ name, column string }{{"card_tags", "card_id"}, {"note_tags", "note_id"}} {
+		if err := tx.Exec("INSERT OR IGNORE INTO "+table.name+" ("+table.column+", tag_id) "+
+			"SELECT "+table.column+", ? FROM "+table.name+" WHERE tag_id = ?", toID, fromID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM "+table.name+" WHERE tag_id = ?", fromID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeDeckCards 按源卡包中的顺序将卡片和笔记移动到目标卡包，与目标卡包（或之前移动的卡片）问题重复的卡片
+// 和内容重复的笔记按导入选项跳过、更新已有卡片或保留两者
+func mergeDeckCards(im *cardImporter, sourceID, targetID uint, result *models.DeckMergeResult) error {
+	var cards []models.Card
+	if err := im.tx.Preload("Tags").Where("deck_id = ?", sourceID).Order(cardPositionOrder).Find(&cards).Error; err != nil {
+		return err
+	}
+
+	notes := make(map[uint]bool)
+	for i := range cards {
+		card := &cards[i]
+		if card.NoteID != nil {
+			if notes[*card.NoteID] {
+				continue
+			}
+			notes[*card.NoteID] = true
+			if err := mergeNote(im, *card.NoteID, targetID, result); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := database.NormalizeQuestion(card.Question)
+		if existingID, ok := im.questions[key]; ok && im.mode != models.DuplicateKeep {
+			result.DuplicateCardIDs = append(result.DuplicateCardIDs, card.ID)
+			if im.mode == models.DuplicateSkip {
+				result.Skipped++
+				continue
+			}
+
+			if err := im.tx.Where("card_id = ?", card.ID).Order("position ASC").Find(&card.Options).Error; err != nil {
+				return err
+			}
+			for j := range card.Options {
+				card.Options[j].ID = 0
+			}
+			if err := im.updateCard(existingID, card); err != nil {
+				return err
+			}
+			result.Updated++
+			continue
+		}
+
+		err := saveCardWithRevision(im.tx, card, tagIDsOf(card.Tags), func(card *models.Card) {
+			card.DeckID = targetID
+		})
+		if err != nil {
+			return err
+		}
+		if _, ok := im.questions[key]; !ok {
+			im.questions[key] = card.ID
+		}
+		result.MovedCards++
+	}
+
+	return nil
+}
+
+// mergeNote 将笔记及其生成的卡片移动到目标卡包，与目标卡包的笔记内容重复时按导入选项跳过、更新已有笔记或保留两者
+func mergeNote(im *cardImporter, noteID, targetID uint, result *models.DeckMergeResult) error {
+	var note models.Note
+	if err := im.tx.Preload("Tags").First(&note, noteID).Error; err != nil {
+		return err
+	}
+
+	key := database.NormalizeQuestion(note.Content)
+	if existingID, ok := im.notes[key]; ok && im.mode != models.DuplicateKeep {
+		var cardIDs []uint
+		if err := im.tx.Model(&models.Card{}).Where("note_id = ?", note.ID).Pluck("id", &cardIDs).Error; err != nil {
+			return err
+		}
+		result.DuplicateCardIDs = append(result.DuplicateCardIDs, cardIDs...)
+
+		updated := false
+		if im.mode == models.DuplicateUpdate {
+			var err error
+			if updated, err = im.updateNote(existingID, &note); err != nil {
+				return err
+			}
+		}
+		if updated {
+			result.Updated++
+		} else {
+			result.Skipped++
+		}
+		return nil
+	}
+
+	note.DeckID = targetID
+	if err := saveNoteWithTags(im.tx, &note, tagIDsOf(note.Tags)); err != nil {
+		return err
+	}
+	if err := syncNoteCards(im.tx, &note); err != nil {
+		return err
+	}
+	if _, ok := im.notes[key]; !ok {
+		im.notes[key] = note.ID
+	}
+
+	var count int64
+	if err := im.tx.Model(&models.Card{}).Where("note_id = ?", note.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	result.MovedNotes++
+	result.MovedCards += int(count)
+	return nil
+}
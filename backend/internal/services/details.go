@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidColor 颜色格式无效
+var ErrInvalidColor = errors.New("颜色必须是 #RRGGBB 格式的十六进制颜色")
+
+// colorPattern 十六进制颜色，如 #4A90E2
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// validColor 检查颜色是否为空或有效的十六进制颜色
+func validColor(color string) bool {
+	return color == "" || colorPattern.MatchString(color)
+}
+
+// applyDetails 将描述、颜色、图标和元数据的修改应用到卡包或标签，未提供的字段保持不变，元数据整体替换
+func applyDetails(details *models.Details, update models.DetailsUpdate) error {
+	if update.Color != nil {
+		if !validColor(*update.Color) {
+			return ErrInvalidColor
+		}
+		details.Color = *update.Color
+	}
+	if update.Description != nil {
+		details.Description = *update.Description
+	}
+	if update.Icon != nil {
+		details.Icon = *update.Icon
+	}
+	if update.Metadata != nil {
+		details.Metadata = update.Metadata
+		if len(update.Metadata) == 0 {
+			details.Metadata = nil
+		}
+	}
+	return nil
+}
+
+// importedDetails 整理导入文件中的描述等信息，忽略无效的颜色
+func importedDetails(details models.Details) models.Details {
+	if !validColor(details.Color) {
+		details.Color = ""
+	}
+	if len(details.Metadata) == 0 {
+		details.Metadata = nil
+	}
+	return details
+}
+
+// importTagDetails 为导入的标签设置描述等信息，标签已有的信息不被覆盖
+func importTagDetails(tx *gorm.DB, tag models.Tag, details models.Details) error {
+	details = importedDetails(details)
+	merged := tag.Details
+	if merged.Description == "" {
+		merged.Description = details.Description
+	}
+	if merged.Color == "" {
+		merged.Color = details.Color
+	}
+	if merged.Icon == "" {
+		merged.Icon = details.Icon
+	}
+	if len(merged.Metadata) == 0 {
+		merged.Metadata = details.Metadata
+	}
+
+	return tx.Model(&tag).Select("Description", "Color", "Icon", "Metadata").Updates(&models.Tag{Details: merged}).Error
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateCard 卡包中已存在相同问题的卡片
+var ErrDuplicateCard = errors.New("卡包中已存在相同问题的卡片")
+
+// findDuplicateCards 查找卡包中与问题重复的卡片ID，由数据库函数normalize_question规范化已有卡片的问题
+func findDuplicateCards(tx *gorm.DB, deckID uint, question string) ([]uint, error) {
+	ids := []uint{}
+	err := tx.Model(&models.Card{}).
+		Where("deck_id = ? AND normalize_question(question) = ?", deckID, database.NormalizeQuestion(question)).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetDuplicates 获取卡包中问题重复的卡片，按问题分组
+func (s *DeckService) GetDuplicates(deckID uint) ([]models.DuplicateGroup, error) {
+	if err := s.db.First(&models.Deck{}, deckID).Error; err != nil {
+		return nil, err
+	}
+
+	var cards []models.Card
+	if err := s.db.Where("deck_id = ?", deckID).Preload("Deck").Preload("Tags").Order("id ASC").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+
+	byQuestion := make(map[string][]models.CardResponse)
+	var keys []string
+	for _, card := range cards {
+		key := database.NormalizeQuestion(card.Question)
+		if _, ok := byQuestion[key]; !ok {
+			keys = append(keys, key)
+		}
+		byQuestion[key] = append(byQuestion[key], NewCardResponse(card))
+	}
+	sort.Strings(keys)
+
+	groups := []models.DuplicateGroup{}
+	for _, key := range keys {
+		if len(byQuestion[key]) > 1 {
+			groups = append(groups, models.DuplicateGroup{Question: key, Cards: byQuestion[key]})
+		}
+	}
+	return groups, nil
+}
+
+// cardImporter 向卡包导入卡片和笔记，按导入选项处理与已有卡片问题重复的情况
+type cardImporter struct {
+	tx        *gorm.DB
+	mode      string
+	questions map[string]uint // 规范化问题到第一张相同问题的卡片ID
+	notes     map[string]uint // 规范化内容到第一条相同内容的笔记ID
+	result    *models.ImportResult
+}
+
+// newCardImporter 创建卡片导入器，载入卡包中已有卡片的问题和笔记内容
+func newCardImporter(tx *gorm.DB, deck *models.Deck, mode string) (*cardImporter, error) {
+	if mode == "" {
+		mode = models.DuplicateKeep
+	}
+	im := &cardImporter{
+		tx:        tx,
+		mode:      mode,
+		questions: make(map[string]uint),
+		notes:     make(map[string]uint),
+		result:    &models.ImportResult{Deck: deck},
+	}
+
+	var cards []models.Card
+	if err := tx.Select("id", "question").Where("deck_id = ? AND note_id IS NULL", deck.ID).Order("id ASC").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	for _, card := range cards {
+		key := database.NormalizeQuestion(card.Question)
+		if _, ok := im.questions[key]; !ok {
+			im.questions[key] = card.ID
+		}
+	}
+
+	var notes []models.Note
+	if err := tx.Select("id", "content").Where("deck_id = ?", deck.ID).Order("id ASC").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		key := database.NormalizeQuestion(note.Content)
+		if _, ok := im.notes[key]; !ok {
+			im.notes[key] = note.ID
+		}
+	}
+
+	return im, nil
+}
+
+// importCard 导入卡片，重复时按导入选项跳过、更新已有卡片或保留两者
+func (im *cardImporter) importCard(card *models.Card) error {
+	key := database.NormalizeQuestion(card.Question)
+	if existingID, ok := im.questions[key]; ok {
+		switch im.mode {
+		case models.DuplicateSkip:
+			im.result.Skipped++
+			return nil
+		case models.DuplicateUpdate:
+			if err := im.updateCard(existingID, card); err != nil {
+				return err
+			}
+			im.result.Updated++
+			return nil
+		}
+	}
+
+	// 按文件中的顺序排在卡包的最后
+	position, err := nextCardPosition(im.tx, card.DeckID)
+	if err != nil {
+		return err
+	}
+	card.Position = position
+	if err := im.tx.Create(card).Error; err != nil {
+		return err
+	}
+	if _, ok := im.questions[key]; !ok {
+		im.questions[key] = card.ID
+	}
+	im.result.Created++
+	return nil
+}
+
+// updateCard 用导入的卡片更新已有卡片的答案、格式、标签和选项，并记录修订
+func (im *cardImporter) updateCard(id uint, imported *models.Card) error {
+	var card models.Card
+	if err := im.tx.Preload("Tags").First(&card, id).Error; err != nil {
+		return err
+	}
+
+	options := imported.Options
+	if options == nil {
+		options = []models.CardOption{}
+	}
+
+	return saveCardWithOptions(im.tx, &card, tagIDsOf(imported.Tags), options, func(card *models.Card) {
+		card.Type = models.CardTypeBasic
+		if imported.Type != "" {
+			card.Type = imported.Type
+		}
+		card.Answer = imported.Answer
+		card.Format = normalizeFormat(imported.Format)
+		if imported.Flag != models.FlagNone {
+			card.Flag = imported.Flag
+		}
+	})
+}
+
+// importNote 导入笔记，与已有笔记内容重复时按导入选项跳过、更新已有笔记或保留两者
+func (im *cardImporter) importNote(note *models.Note) error {
+	key := database.NormalizeQuestion(note.Content)
+	if existingID, ok := im.notes[key]; ok && im.mode != models.DuplicateKeep {
+		updated := false
+		if im.mode == models.DuplicateUpdate {
+			var err error
+			if updated, err = im.updateNote(existingID, note); err != nil {
+				return err
+			}
+		}
+		if updated {
+			im.result.Updated++
+		} else {
+			im.result.Skipped++
+		}
+		return nil
+	}
+
+	if err := createNoteWithCards(im.tx, note); err != nil {
+		return err
+	}
+	if _, ok := im.notes[key]; !ok {
+		im.notes[key] = note.ID
+	}
+	im.result.Created++
+	return nil
+}
+
+// updateNote 用导入的笔记更新已有笔记的内容、字段、遮挡区域和标签并同步卡片，未变化的编号保留原有复习进度；
+// 两者的笔记类型不同时不更新，返回false
+func (im *cardImporter) updateNote(id uint, imported *models.Note) (bool, error) {
+	var note models.Note
+	if err := im.tx.First(&note, id).Error; err != nil {
+		return false, err
+	}
+	if note.Type != imported.Type || !sameNoteType(note.NoteTypeID, imported.NoteTypeID) {
+		return false, nil
+	}
+
+	note.Content = imported.Content
+	note.Extra = imported.Extra
+	note.Fields = imported.Fields
+	note.NoteType = imported.NoteType
+	note.MediaID = imported.MediaID
+	note.Masks = imported.Masks
+	if err := saveNoteWithTags(im.tx, &note, tagIDsOf(imported.Tags)); err != nil {
+		return false, err
+	}
+	return true, syncNoteCards(im.tx, &note)
+}
+
+// sameNoteType 判断两条笔记是否使用相同的笔记类型，非模板笔记都没有笔记类型
+func sameNoteType(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flashcard/internal/metrics"
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
 	"fmt"
@@ -33,8 +35,27 @@ func (s *ImportExportService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// ExportDeck 导出卡包为JSON格式
+// ExportDeck 导出卡包为指定格式的文件
 func (s *ImportExportService) ExportDeck(deckID uint, format string) (string, error) {
+	return s.ExportDeckWithContent(deckID, format, models.ExportContentSource)
+}
+
+// ExportDeckWithContent 导出卡包并指定内容类型，rendered时TXT/CSV输出渲染后的HTML，JSON始终导出源文本
+func (s *ImportExportService) ExportDeckWithContent(deckID uint, format string, content string) (string, error) {
+	rendered := content == models.ExportContentRendered && strings.ToLower(format) != "json"
+	filename, err := s.exportDeck(deckID, format, rendered)
+
+	// 仅使用已支持的格式作为标签值，避免任意输入导致标签数量膨胀
+	formatLabel := strings.ToLower(format)
+	if formatLabel != "json" && formatLabel != "csv" && formatLabel != "txt" {
+		formatLabel = "unsupported"
+	}
+	metrics.ExportsTotal.WithLabelValues(formatLabel, metrics.Status(err)).Inc()
+	return filename, err
+}
+
+// exportDeck 导出卡包为JSON、CSV或TXT格式
+func (s *ImportExportService) exportDeck(deckID uint, format string, rendered bool) (string, error) {
 	// 获取卡包信息
 	var deck models.Deck
 	if err := s.db.First(&deck, deckID).Error; err != nil {
@@ -47,9 +68,11 @@ func (s *ImportExportService) ExportDeck(deckID uint, format string) (string, er
 		return "", err
 	}
 
-	// 获取卡包下的所有卡片
+	// 获取卡包下的所有卡片，按卡片顺序导出
 	var cards []models.Card
-	if err := s.db.Where("deck_id = ?", deckID).Preload("Tag").Find(&cards).Error; err != nil {
+	if err := s.db.Where("deck_id = ?", deckID).Preload("Tags").Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order(cardPositionOrder).Find(&cards).Error; err != nil {
 		return "", err
 	}
 
@@ -57,31 +80,60 @@ func (s *ImportExportService) ExportDeck(deckID uint, format string) (string, er
 	var tagExports []models.TagExport
 	for _, tag := range tags {
 		tagExports = append(tagExports, models.TagExport{
-			Name:        tag.Name,
-			Description: "", // 目前模型中没有描述字段
-			CreatedAt:   tag.CreatedAt,
+			Name:      tagPathOf(tag),
+			CreatedAt: tag.CreatedAt,
+			Details:   tag.Details,
 		})
 	}
 
 	var cardExports []models.CardExport
+	exportedNotes := make(map[uint]bool)
 	for _, card := range cards {
+		// 图片遮挡卡片依赖媒体文件，只能通过完整备份迁移
+		if card.Type == models.CardTypeOcclusion {
+			continue
+		}
+
+		// 同一笔记生成的多张卡片只导出一次；模板卡片已渲染为独立的问答，按普通卡片逐张导出
+		if card.NoteID != nil && card.Type != models.CardTypeTemplate {
+			if exportedNotes[*card.NoteID] {
+				continue
+			}
+			exportedNotes[*card.NoteID] = true
+		}
+
 		cardExport := models.CardExport{
 			Question:  card.Question,
 			Answer:    card.Answer,
+			Flag:      card.Flag,
 			CreatedAt: card.CreatedAt,
 		}
-		if card.Tag != nil {
-			cardExport.TagName = card.Tag.Name
+		if card.Format == models.ContentFormatMarkdown {
+			cardExport.Format = card.Format
+		}
+		if rendered {
+			cardExport.Question = renderContent(card.Question, card.Format)
+			cardExport.Answer = renderContent(card.Answer, card.Format)
+		}
+		if card.Type != models.CardTypeBasic && card.Type != models.CardTypeTemplate {
+			cardExport.Type = card.Type
+		}
+		for _, option := range card.Options {
+			cardExport.Options = append(cardExport.Options, models.OptionExport{
+				Content: option.Content,
+				Correct: option.Correct,
+			})
 		}
+		cardExport.TagNames = tagNamesOf(card.Tags)
 		cardExports = append(cardExports, cardExport)
 	}
 
 	exportData := models.DeckExport{
-		Name:        deck.Name,
-		Description: "", // 目前模型中没有描述字段
-		Cards:       cardExports,
-		Tags:        tagExports,
-		CreatedAt:   deck.CreatedAt,
+		Name:      deck.Name,
+		Cards:     cardExports,
+		Tags:      tagExports,
+		CreatedAt: deck.CreatedAt,
+		Details:   deck.Details,
 	}
 
 	// 确保导出目录存在
@@ -138,7 +190,7 @@ func (s *ImportExportService) exportToCSV(data interface{}, deckName string) (st
 	defer writer.Flush()
 
 	// 写入表头
-	if err := writer.Write([]string{"ID", "Question", "Answer", "Tag"}); err != nil {
+	if err := writer.Write([]string{"ID", "Question", "Answer", "Tag", "Type"}); err != nil {
 		return "", err
 	}
 
@@ -154,7 +206,8 @@ func (s *ImportExportService) exportToCSV(data interface{}, deckName string) (st
 			strconv.Itoa(i + 1), // 使用序号作为ID
 			card.Question,
 			card.Answer,
-			card.TagName,
+			strings.Join(card.TagNames, csvTagSeparator),
+			card.Type,
 		}); err != nil {
 			return "", err
 		}
@@ -170,6 +223,15 @@ func (s *ImportExportService) ImportDeck(filePath string) (*models.Deck, error)
 
 // ImportDeckWithName 从文件导入卡包并指定卡包名称
 func (s *ImportExportService) ImportDeckWithName(filePath string, deckName string) (*models.Deck, error) {
+	result, err := s.ImportDeckWithOptions(filePath, models.ImportOptions{DeckName: deckName})
+	if err != nil {
+		return nil, err
+	}
+	return result.Deck, nil
+}
+
+// ImportDeckWithOptions 按导入选项从文件导入卡包，可以导入到已有卡包并处理重复的卡片
+func (s *ImportExportService) ImportDeckWithOptions(filePath string, opts models.ImportOptions) (*models.ImportResult, error) {
 	// 检查文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("文件不存在: %s", filePath)
@@ -177,25 +239,53 @@ func (s *ImportExportService) ImportDeckWithName(filePath string, deckName strin
 
 	// 根据文件扩展名确定导入格式
 	ext := strings.ToLower(filepath.Ext(filePath))
+	var result *models.ImportResult
+	var err error
 	switch ext {
 	case ".json":
-		return s.importFromJSONWithName(filePath, deckName)
+		result, err = s.importFromJSONWithOptions(filePath, opts)
 	case ".csv":
-		return s.importFromCSVWithName(filePath, deckName)
+		result, err = s.importFromCSVWithOptions(filePath, opts)
 	case ".txt":
-		return s.importFromTXTWithName(filePath, deckName)
+		result, err = s.importFromTXTWithOptions(filePath, opts)
 	default:
 		return nil, fmt.Errorf("不支持的导入格式: %s", ext)
 	}
+
+	metrics.ImportsTotal.WithLabelValues(strings.TrimPrefix(ext, "."), metrics.Status(err)).Inc()
+	return result, err
+}
+
+// importTargetDeck 获取导入的目标卡包：指定了已有卡包时使用该卡包，否则按名称和描述等信息新建卡包
+func importTargetDeck(tx *gorm.DB, opts models.ImportOptions, deckName string, details models.Details) (*models.Deck, error) {
+	var deck models.Deck
+	if opts.DeckID != nil {
+		if err := tx.First(&deck, *opts.DeckID).Error; err != nil {
+			return nil, err
+		}
+		return &deck, nil
+	}
+
+	deck.Name = deckName
+	deck.Details = importedDetails(details)
+	if err := tx.Create(&deck).Error; err != nil {
+		return nil, err
+	}
+	return &deck, nil
 }
 
 // importFromJSON 从JSON文件导入
 func (s *ImportExportService) importFromJSON(filePath string) (*models.Deck, error) {
-	return s.importFromJSONWithName(filePath, "")
+	result, err := s.importFromJSONWithOptions(filePath, models.ImportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Deck, nil
 }
 
-// importFromJSONWithName 从JSON文件导入并指定卡包名称
-func (s *ImportExportService) importFromJSONWithName(filePath string, deckName string) (*models.Deck, error) {
+// importFromJSONWithOptions 按导入选项从JSON文件导入
+func (s *ImportExportService) importFromJSONWithOptions(filePath string, opts models.ImportOptions) (*models.ImportResult, error) {
+	deckName := opts.DeckName
 	// 读取文件
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -210,24 +300,25 @@ func (s *ImportExportService) importFromJSONWithName(filePath string, deckName s
 		// 如果解析失败，尝试旧格式
 		file.Seek(0, 0)
 		var importData struct {
-			Deck  models.Deck   `json:"deck"`
-			Tags  []models.Tag  `json:"tags"`
-			Cards []models.Card `json:"cards"`
+			Deck  models.Deck  `json:"deck"`
+			Tags  []models.Tag `json:"tags"`
+			Cards []struct {
+				models.Card
+				TagID *uint `json:"tag_id"`
+			} `json:"cards"`
 		}
 		if err := json.NewDecoder(file).Decode(&importData); err != nil {
 			return nil, fmt.Errorf("无法解析导入文件格式: %v", err)
 		}
 		// 转换为 DeckExport 格式
 		deckExport = models.DeckExport{
-			Name:        importData.Deck.Name,
-			Description: "",
-			CreatedAt:   importData.Deck.CreatedAt,
+			Name:      importData.Deck.Name,
+			CreatedAt: importData.Deck.CreatedAt,
 		}
 		for _, tag := range importData.Tags {
 			deckExport.Tags = append(deckExport.Tags, models.TagExport{
-				Name:        tag.Name,
-				Description: "",
-				CreatedAt:   tag.CreatedAt,
+				Name:      tag.Name,
+				CreatedAt: tag.CreatedAt,
 			})
 		}
 		for _, card := range importData.Cards {
@@ -262,44 +353,91 @@ func (s *ImportExportService) importFromJSONWithName(filePath string, deckName s
 	if deckName != "" {
 		finalDeckName = deckName
 	}
-	deck := models.Deck{
-		Name:     finalDeckName,
-		Archived: false,
+	deck, err := importTargetDeck(tx, opts, finalDeckName, deckExport.Details)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-	if err := tx.Create(&deck).Error; err != nil {
+	importer, err := newCardImporter(tx, deck, opts.OnDuplicate)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	// 创建标签映射（标签名到ID）
-	tagMap := make(map[string]uint)
+	// 创建标签映射（标签名到标签）
+	tagMap := make(map[string]models.Tag)
 	for _, tagExport := range deckExport.Tags {
-		newTag := models.Tag{
-			DeckID: &deck.ID,
-			Name:   tagExport.Name,
-		}
-		if err := tx.Create(&newTag).Error; err != nil {
+		tags, err := importTags(tx, deck.ID, tagMap, []string{tagExport.Name})
+		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
-		tagMap[tagExport.Name] = newTag.ID
+		for _, tag := range tags {
+			if err := importTagDetails(tx, tag, tagExport.Details); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
 	}
 
 	// 创建卡片
 	cardCount := 0
 	for _, cardExport := range deckExport.Cards {
+		// 兼容旧版本导出的单个标签
+		tagNames := cardExport.TagNames
+		if len(tagNames) == 0 && cardExport.TagName != "" {
+			tagNames = []string{cardExport.TagName}
+		}
+		tags, err := importTags(tx, deck.ID, tagMap, tagNames)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		// 填空笔记按填空编号生成多张卡片
+		if cardExport.Type == models.CardTypeCloze {
+			note := models.Note{
+				DeckID:  deck.ID,
+				Tags:    tags,
+				Type:    models.CardTypeCloze,
+				Content: cardExport.Question,
+				Extra:   cardExport.Answer,
+			}
+			if err := importer.importNote(&note); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			cardCount++
+			continue
+		}
+
 		newCard := models.Card{
 			DeckID:   deck.ID,
 			Question: cardExport.Question,
 			Answer:   cardExport.Answer,
+			Format:   normalizeFormat(cardExport.Format),
+			Tags:     tags,
+		}
+		if validFlag(cardExport.Flag) {
+			newCard.Flag = cardExport.Flag
 		}
-		// 如果卡片有关联标签，则使用新标签ID
-		if cardExport.TagName != "" {
-			if newTagID, exists := tagMap[cardExport.TagName]; exists {
-				newCard.TagID = &newTagID
+
+		// 选择题卡片同时创建选项
+		if cardExport.Type == models.CardTypeChoice {
+			options := make([]models.CardOption, len(cardExport.Options))
+			for i, option := range cardExport.Options {
+				options[i] = models.CardOption{Content: option.Content, Correct: option.Correct}
+			}
+			validOptions, err := validateOptions(options)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
 			}
+			newCard.Type = models.CardTypeChoice
+			newCard.Options = validOptions
 		}
-		if err := tx.Create(&newCard).Error; err != nil {
+
+		if err := importer.importCard(&newCard); err != nil {
 			tx.Rollback()
 			return nil, err
 		}
@@ -311,16 +449,21 @@ func (s *ImportExportService) importFromJSONWithName(filePath string, deckName s
 		return nil, err
 	}
 
-	return &deck, nil
+	return importer.result, nil
 }
 
 // importFromCSV 从CSV文件导入
 func (s *ImportExportService) importFromCSV(filePath string) (*models.Deck, error) {
-	return s.importFromCSVWithName(filePath, "")
+	result, err := s.importFromCSVWithOptions(filePath, models.ImportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Deck, nil
 }
 
-// importFromCSVWithName 从CSV文件导入并指定卡包名称
-func (s *ImportExportService) importFromCSVWithName(filePath string, deckName string) (*models.Deck, error) {
+// importFromCSVWithOptions 按导入选项从CSV文件导入
+func (s *ImportExportService) importFromCSVWithOptions(filePath string, opts models.ImportOptions) (*models.ImportResult, error) {
+	deckName := opts.DeckName
 	// 读取文件
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -337,8 +480,14 @@ func (s *ImportExportService) importFromCSVWithName(filePath string, deckName st
 		return nil, err
 	}
 
-	// 验证表头
-	if len(header) < 3 || header[0] != "ID" || header[1] != "Question" || header[2] != "Answer" {
+	// 按笔记类型导入时表头列映射到笔记类型的字段，否则验证表头
+	var columns *noteTypeColumns
+	if opts.NoteTypeID != nil {
+		columns, err = mapNoteTypeColumns(s.db, *opts.NoteTypeID, header, opts.FieldMap)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(header) < 3 || header[0] != "ID" || header[1] != "Question" || header[2] != "Answer" {
 		return nil, fmt.Errorf("CSV格式错误，表头应为: ID,Question,Answer,Tag")
 	}
 
@@ -358,16 +507,19 @@ func (s *ImportExportService) importFromCSVWithName(filePath string, deckName st
 		finalDeckName = strings.TrimSuffix(finalDeckName, "_"+getCurrentTimestamp()) // 移除时间戳（如果有）
 	}
 
-	deck := models.Deck{
-		Name: finalDeckName,
+	deck, err := importTargetDeck(tx, opts, finalDeckName, models.Details{})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-	if err := tx.Create(&deck).Error; err != nil {
+	importer, err := newCardImporter(tx, deck, opts.OnDuplicate)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	// 创建标签映射（标签名到ID）
-	tagMap := make(map[string]uint)
+	// 创建标签映射（标签名到标签）
+	tagMap := make(map[string]models.Tag)
 
 	// 读取数据行
 	for {
@@ -380,6 +532,24 @@ func (s *ImportExportService) importFromCSVWithName(filePath string, deckName st
 			return nil, err
 		}
 
+		// 每行生成一条模板笔记
+		if columns != nil {
+			note, err := columns.note(tx, deck.ID, tagMap, record)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if note == nil {
+				importer.result.Skipped++
+				continue
+			}
+			if err := importer.importNote(note); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			continue
+		}
+
 		// 跳过空行
 		if len(record) < 3 {
 			continue
@@ -392,32 +562,34 @@ func (s *ImportExportService) importFromCSVWithName(filePath string, deckName st
 			Answer:   record[2],
 		}
 
-		// 如果有标签列，处理标签
+		// 如果有标签列，处理标签，多个标签用分号分隔
 		if len(record) > 3 && record[3] != "" {
-			tagName := record[3]
-			var tagID uint
-			var exists bool
-
-			// 检查标签是否已存在
-			if tagID, exists = tagMap[tagName]; !exists {
-				// 创建新标签
-				tag := models.Tag{
-					DeckID: &deck.ID,
-					Name:   tagName,
-				}
-				if err := tx.Create(&tag).Error; err != nil {
-					tx.Rollback()
-					return nil, err
-				}
-				tagID = tag.ID
-				tagMap[tagName] = tagID
+			tags, err := importTags(tx, deck.ID, tagMap, strings.Split(record[3], csvTagSeparator))
+			if err != nil {
+				tx.Rollback()
+				return nil, err
 			}
+			card.Tags = tags
+		}
 
-			card.TagID = &tagID
+		// 类型列为cloze时创建填空笔记
+		if len(record) > 4 && record[4] == models.CardTypeCloze {
+			note := models.Note{
+				DeckID:  deck.ID,
+				Tags:    card.Tags,
+				Type:    models.CardTypeCloze,
+				Content: card.Question,
+				Extra:   card.Answer,
+			}
+			if err := importer.importNote(&note); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			continue
 		}
 
 		// 创建卡片
-		if err := tx.Create(&card).Error; err != nil {
+		if err := importer.importCard(&card); err != nil {
 			tx.Rollback()
 			return nil, err
 		}
@@ -428,7 +600,90 @@ func (s *ImportExportService) importFromCSVWithName(filePath string, deckName st
 		return nil, err
 	}
 
-	return &deck, nil
+	return importer.result, nil
+}
+
+// noteTypeColumns CSV表头与笔记类型字段的对应关系
+type noteTypeColumns struct {
+	noteType  *models.NoteType
+	fields    map[int]string // 列序号到字段名
+	tagColumn int            // 标签列序号，-1表示没有标签列
+}
+
+// mapNoteTypeColumns 将CSV表头映射到笔记类型的字段：优先使用字段映射，其次按同名字段匹配（不区分大小写），Tags或Tag列作为标签
+func mapNoteTypeColumns(db *gorm.DB, noteTypeID uint, header []string, fieldMap map[string]string) (*noteTypeColumns, error) {
+	noteType, err := findNoteType(db, noteTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]string, len(noteType.Fields))
+	for _, name := range noteType.Fields {
+		byName[strings.ToLower(name)] = name
+	}
+	mapped := make(map[string]string, len(fieldMap))
+	for column, field := range fieldMap {
+		name, ok := byName[strings.ToLower(strings.TrimSpace(field))]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNoteField, field)
+		}
+		mapped[strings.ToLower(strings.TrimSpace(column))] = name
+	}
+
+	columns := &noteTypeColumns{noteType: noteType, fields: make(map[int]string), tagColumn: -1}
+	for i, column := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if name, ok := mapped[key]; ok {
+			columns.fields[i] = name
+		} else if name, ok := byName[key]; ok {
+			columns.fields[i] = name
+		} else if key == "tags" || key == "tag" {
+			columns.tagColumn = i
+		}
+	}
+	if len(columns.fields) == 0 {
+		return nil, ErrNoteTypeColumns
+	}
+
+	return columns, nil
+}
+
+// note 将CSV的一行转换为模板笔记，字段全部为空或没有生成任何卡片时返回nil
+func (c *noteTypeColumns) note(tx *gorm.DB, deckID uint, tagMap map[string]models.Tag, record []string) (*models.Note, error) {
+	fields := make(models.NoteFields, len(c.noteType.Fields))
+	for _, name := range c.noteType.Fields {
+		fields[name] = ""
+	}
+	for i, name := range c.fields {
+		if i < len(record) {
+			fields[name] = record[i]
+		}
+	}
+
+	note := &models.Note{
+		DeckID:     deckID,
+		Type:       models.CardTypeTemplate,
+		NoteTypeID: &c.noteType.ID,
+		NoteType:   c.noteType,
+		Fields:     fields,
+		Content:    templateNoteContent(c.noteType, fields),
+	}
+	if _, err := templateNoteOrds(note); err != nil {
+		if errors.Is(err, ErrEmptyTemplateNote) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if c.tagColumn >= 0 && c.tagColumn < len(record) && record[c.tagColumn] != "" {
+		tags, err := importTags(tx, deckID, tagMap, strings.Split(record[c.tagColumn], csvTagSeparator))
+		if err != nil {
+			return nil, err
+		}
+		note.Tags = tags
+	}
+
+	return note, nil
 }
 
 // exportToTXT 导出为TXT格式
@@ -447,13 +702,18 @@ func (s *ImportExportService) exportToTXT(data interface{}, deckName string) (st
 		return "", fmt.Errorf("数据格式错误")
 	}
 
-	// 按标签分组卡片
+	// 按标签组合分组卡片，保持卡片首次出现的顺序
 	tagCards := make(map[string][]models.CardExport)
+	var tagGroups [][]string
 	noTagCards := []models.CardExport{}
 
 	for _, card := range exportData.Cards {
-		if card.TagName != "" {
-			tagCards[card.TagName] = append(tagCards[card.TagName], card)
+		if len(card.TagNames) > 0 {
+			key := strings.Join(card.TagNames, "\x00")
+			if _, exists := tagCards[key]; !exists {
+				tagGroups = append(tagGroups, card.TagNames)
+			}
+			tagCards[key] = append(tagCards[key], card)
 		} else {
 			noTagCards = append(noTagCards, card)
 		}
@@ -468,15 +728,19 @@ func (s *ImportExportService) exportToTXT(data interface{}, deckName string) (st
 
 	cardIndex := 0
 
-	// 写入有标签的卡片
-	for tagName, cards := range tagCards {
-		_, err := file.WriteString(fmt.Sprintf("# %s\n", tagName))
-		if err != nil {
-			return "", err
+	// 写入有标签的卡片，每个标签一行
+	for _, tagNames := range tagGroups {
+		for _, tagName := range tagNames {
+			_, err := file.WriteString(fmt.Sprintf("# %s\n", tagName))
+			if err != nil {
+				return "", err
+			}
 		}
 
+		cards := tagCards[strings.Join(tagNames, "\x00")]
+
 		for _, card := range cards {
-			_, err := file.WriteString(fmt.Sprintf("%s\n---\n%s", card.Question, card.Answer))
+			_, err := file.WriteString(formatTXTCard(card))
 			if err != nil {
 				return "", err
 			}
@@ -495,7 +759,7 @@ func (s *ImportExportService) exportToTXT(data interface{}, deckName string) (st
 	// 写入无标签的卡片
 	if len(noTagCards) > 0 {
 		for _, card := range noTagCards {
-			_, err := file.WriteString(fmt.Sprintf("%s\n---\n%s", card.Question, card.Answer))
+			_, err := file.WriteString(formatTXTCard(card))
 			if err != nil {
 				return "", err
 			}
@@ -514,6 +778,41 @@ func (s *ImportExportService) exportToTXT(data interface{}, deckName string) (st
 	return filename, nil
 }
 
+// csvTagSeparator CSV标签列中多个标签的分隔符
+const csvTagSeparator = ";"
+
+// importTags 按路径获取导入卡包中的标签，不存在时逐级创建（如 Grammar::Verbs），忽略空名称和重复名称
+func importTags(tx *gorm.DB, deckID uint, tagMap map[string]models.Tag, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		path, ok := splitTagPath(name)
+		if !ok {
+			continue
+		}
+		name = strings.Join(path, models.TagPathSeparator)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag, err := ensureTagPath(tx, &deckID, path, tagMap)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, nil
+}
+
+// formatTXTCard 将卡片格式化为TXT文本，没有附加内容的填空笔记省略---部分
+func formatTXTCard(card models.CardExport) string {
+	if card.Type == models.CardTypeCloze && strings.TrimSpace(card.Answer) == "" {
+		return card.Question
+	}
+	return fmt.Sprintf("%s\n---\n%s", card.Question, card.Answer)
+}
+
 // getCurrentTimestamp 获取当前时间戳（年月日格式）
 func getCurrentTimestamp() string {
 	return time.Now().Format("20060102")
@@ -521,11 +820,16 @@ func getCurrentTimestamp() string {
 
 // importFromTXT 从TXT文件导入
 func (s *ImportExportService) importFromTXT(filePath string) (*models.Deck, error) {
-	return s.importFromTXTWithName(filePath, "")
+	result, err := s.importFromTXTWithOptions(filePath, models.ImportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Deck, nil
 }
 
-// importFromTXTWithName 从TXT文件导入并指定卡包名称
-func (s *ImportExportService) importFromTXTWithName(filePath string, deckName string) (*models.Deck, error) {
+// importFromTXTWithOptions 按导入选项从TXT文件导入
+func (s *ImportExportService) importFromTXTWithOptions(filePath string, opts models.ImportOptions) (*models.ImportResult, error) {
+	deckName := opts.DeckName
 	// 读取文件
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -555,10 +859,13 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 		finalDeckName = strings.TrimSuffix(finalDeckName, "_"+getCurrentTimestamp()) // 移除时间戳（如果有）
 	}
 
-	deck := models.Deck{
-		Name: finalDeckName,
+	deck, err := importTargetDeck(tx, opts, finalDeckName, models.Details{})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-	if err := tx.Create(&deck).Error; err != nil {
+	importer, err := newCardImporter(tx, deck, opts.OnDuplicate)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -566,9 +873,10 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 	// 解析TXT内容
 	contentStr := string(content)
 
-	// 创建标签映射（标签名到ID）
-	tagMap := make(map[string]uint)
-	var currentTagID *uint
+	// 创建标签映射（标签名到标签）
+	tagMap := make(map[string]models.Tag)
+	var currentTags []models.Tag
+	readingTags := false // 连续的标签行共同作用于之后的卡片
 
 	// 按===分割卡片
 	cardParts := strings.Split(contentStr, "===")
@@ -591,31 +899,23 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 
 			// 检查是否是标签行
 			if strings.HasPrefix(line, "#") {
-				tagName := strings.TrimSpace(line[1:])
-				if tagName != "" {
-					// 检查标签是否已存在
-					var exists bool
-					var tagID uint
-
-					if tagID, exists = tagMap[tagName]; !exists {
-						// 创建新标签
-						tag := models.Tag{
-							DeckID: &deck.ID,
-							Name:   tagName,
-						}
-						if err := tx.Create(&tag).Error; err != nil {
-							tx.Rollback()
-							return nil, err
-						}
-						tagID = tag.ID
-						tagMap[tagName] = tagID
+				tags, err := importTags(tx, deck.ID, tagMap, []string{line[1:]})
+				if err != nil {
+					tx.Rollback()
+					return nil, err
+				}
+				if len(tags) > 0 {
+					if !readingTags {
+						currentTags = nil
+						readingTags = true
 					}
-					currentTagID = &tagID
+					currentTags = append(currentTags, tags...)
 				}
 				// 跳过标签行，不加入到cleanedLines中
 				continue
 			}
 
+			readingTags = false
 			cleanedLines = append(cleanedLines, line)
 		}
 
@@ -624,6 +924,25 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 
 		// 分割问题和答案
 		parts := strings.Split(cleanedContent, "---")
+
+		// 包含填空标记的内容作为填空笔记导入，---之后为可选的背面附加内容
+		if hasCloze(parts[0]) && len(parts) <= 2 {
+			note := models.Note{
+				DeckID:  deck.ID,
+				Tags:    currentTags,
+				Type:    models.CardTypeCloze,
+				Content: strings.TrimSpace(parts[0]),
+			}
+			if len(parts) == 2 {
+				note.Extra = strings.TrimSpace(parts[1])
+			}
+			if err := importer.importNote(&note); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			continue
+		}
+
 		if len(parts) != 2 {
 			continue // 跳过格式不正确的卡片
 		}
@@ -641,11 +960,11 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 			DeckID:   deck.ID,
 			Question: questionText,
 			Answer:   answerText,
-			TagID:    currentTagID,
+			Tags:     currentTags,
 		}
 
 		// 创建卡片
-		if err := tx.Create(&card).Error; err != nil {
+		if err := importer.importCard(&card); err != nil {
 			tx.Rollback()
 			return nil, err
 		}
@@ -656,5 +975,5 @@ func (s *ImportExportService) importFromTXTWithName(filePath string, deckName st
 		return nil, err
 	}
 
-	return &deck, nil
+	return importer.result, nil
 }
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"bytes"
+	"flashcard/internal/models"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// mathPlaceholder 渲染Markdown前替换数学公式使用的占位符
+const mathPlaceholder = "FLASHMATH%dX"
+
+var (
+	// markdownRenderer 支持表格、删除线等GFM扩展的Markdown渲染器，原始HTML会被忽略
+	markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// htmlPolicy 渲染结果的HTML白名单，保留代码块的语言类名用于前端高亮
+	htmlPolicy = newHTMLPolicy()
+
+	// mathPattern 匹配代码（原样保留）或LaTeX数学公式：$$...$$、\[...\]、\(...\)、$...$
+	mathPattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`|\\$\\$.+?\\$\\$|\\\\\\[.+?\\\\\\]|\\\\\\(.+?\\\\\\)|\\$[^\\s$](?:[^$\n]*[^\\s$])?\\$")
+)
+
+// newHTMLPolicy 创建HTML清洗策略
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	return policy
+}
+
+// renderContent 按内容格式将文本渲染为清洗后的HTML，纯文本仅转义并保留换行
+func renderContent(text, format string) string {
+	if format != models.ContentFormatMarkdown {
+		return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>\n")
+	}
+
+	// 数学公式先替换为占位符，避免其中的 _ * \ 等字符被当作Markdown语法
+	var maths []string
+	protected := mathPattern.ReplaceAllStringFunc(text, func(m string) string {
+		if strings.HasPrefix(m, "`") {
+			return m
+		}
+		maths = append(maths, m)
+		return fmt.Sprintf(mathPlaceholder, len(maths)-1)
+	})
+
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(protected), &buf); err != nil {
+		return html.EscapeString(text)
+	}
+
+	rendered := htmlPolicy.Sanitize(buf.String())
+	for i, m := range maths {
+		rendered = strings.Replace(rendered, fmt.Sprintf(mathPlaceholder, i), html.EscapeString(m), 1)
+	}
+
+	return rendered
+}
+
+// NewCardResponse 构建卡片响应，包含标签名称和渲染后的HTML
+func NewCardResponse(card models.Card) models.CardResponse {
+	response := models.CardResponse{
+		Card:         card,
+		TagNames:     tagNamesOf(card.Tags),
+		QuestionHTML: renderContent(card.Question, card.Format),
+		AnswerHTML:   renderContent(card.Answer, card.Format),
+	}
+	response.TagID, response.TagName = firstTag(card.Tags)
+	return response
+}
+
+// normalizeFormat 规范化内容格式，除Markdown外均视为纯文本
+func normalizeFormat(format string) string {
+	if format == models.ContentFormatMarkdown {
+		return models.ContentFormatMarkdown
+	}
+	return models.ContentFormatPlain
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flashcard/internal/config"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxMediaSize 单个媒体文件大小上限
+const MaxMediaSize = 20 * 1024 * 1024
+
+var (
+	// ErrUnsupportedMedia 不支持的媒体类型
+	ErrUnsupportedMedia = errors.New("只支持图片和音频文件")
+	// ErrMediaTooLarge 媒体文件过大
+	ErrMediaTooLarge = fmt.Errorf("媒体文件大小不能超过%dMB", MaxMediaSize/1024/1024)
+	// ErrMediaHashMismatch 媒体文件内容与哈希不一致
+	ErrMediaHashMismatch = errors.New("媒体文件内容与哈希不一致")
+)
+
+// 媒体文件以内容的SHA-256哈希命名，存放在以哈希前两位命名的子目录中
+var (
+	mediaHashPattern  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	mediaShardPattern = regexp.MustCompile(`^[0-9a-f]{2}$`)
+)
+
+// MediaService 媒体服务
+type MediaService struct {
+	db *gorm.DB
+}
+
+// NewMediaService 创建媒体服务实例
+func NewMediaService() *MediaService {
+	return &MediaService{
+		db: database.GetDB(),
+	}
+}
+
+// mediaDir 获取媒体文件存储目录
+func mediaDir() string {
+	if config.AppConfig != nil && config.AppConfig.MediaDir != "" {
+		return config.AppConfig.MediaDir
+	}
+	return "./media"
+}
+
+// MediaPath 获取媒体文件的存储路径，按哈希前两位分目录
+func MediaPath(hash string) string {
+	return filepath.Join(mediaDir(), hash[:2], hash)
+}
+
+// SaveMedia 保存上传的媒体文件，内容相同的文件返回已有记录
+func (s *MediaService) SaveMedia(filename string, r io.Reader) (*models.Media, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxMediaSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxMediaSize {
+		return nil, ErrMediaTooLarge
+	}
+
+	mimeType, err := detectMediaType(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var existing models.Media
+	if err := s.db.Where("hash = ?", hash).First(&existing).Error; err == nil {
+		// 记录存在但文件丢失时重新写入
+		if err := writeMediaFile(hash, data); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := writeMediaFile(hash, data); err != nil {
+		return nil, err
+	}
+
+	media := &models.Media{
+		Hash:     hash,
+		Filename: filepath.Base(filename),
+		MimeType: mimeType,
+		Size:     int64(len(data)),
+	}
+	if err := s.db.Create(media).Error; err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// GetMediaByID 根据ID获取媒体
+func (s *MediaService) GetMediaByID(id uint) (*models.Media, error) {
+	var media models.Media
+	if err := s.db.First(&media, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// SetCardMedia 设置卡片引用的媒体，替换原有的全部引用
+func (s *MediaService) SetCardMedia(cardID uint, mediaIDs []uint) (*models.Card, error) {
+	var card models.Card
+	if err := s.db.First(&card, cardID).Error; err != nil {
+		return nil, err
+	}
+
+	media := []models.Media{}
+	if len(mediaIDs) > 0 {
+		if err := s.db.Where("id IN ?", mediaIDs).Find(&media).Error; err != nil {
+			return nil, err
+		}
+		if len(media) != len(uniqueIDs(mediaIDs)) {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+
+	if err := s.db.Model(&card).Association("Media").Replace(media); err != nil {
+		return nil, err
+	}
+	card.Media = media
+
+	return &card, nil
+}
+
+// CollectGarbage 删除创建时间早于宽限期且没有被任何卡片（包括回收站中的卡片）引用的媒体，并清理没有对应记录的文件。
+// 只清理符合<哈希前两位>/<哈希>布局的文件，媒体目录中的其他文件不受影响
+func (s *MediaService) CollectGarbage(gracePeriod time.Duration) (*models.MediaGCResult, error) {
+	result := &models.MediaGCResult{}
+	cutoff := time.Now().Add(-gracePeriod)
+
+	var unused []models.Media
+	err := s.db.Where("created_at < ?", cutoff).
+		Where("id NOT IN (SELECT card_media.media_id FROM card_media JOIN cards ON cards.id = card_media.card_id)").
+		Where("id NOT IN (SELECT media_id FROM notes WHERE media_id IS NOT NULL)").
+		Find(&unused).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, media := range unused {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM card_media WHERE media_id = ?", media.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&media).Error
+		}); err != nil {
+			return nil, err
+		}
+		result.DeletedMedia++
+	}
+
+	// 删除数据库中没有对应记录的文件，宽限期内的文件可能正在上传，暂不删除
+	var hashes []string
+	if err := s.db.Model(&models.Media{}).Pluck("hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		known[hash] = true
+	}
+
+	root := mediaDir()
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel != "." && !mediaShardPattern.MatchString(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMediaFile(rel) || known[info.Name()] || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		result.DeletedFiles++
+		result.FreedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// isMediaFile 判断相对媒体目录的路径是否符合<哈希前两位>/<哈希>的存储布局
+func isMediaFile(rel string) bool {
+	dir, name := filepath.Split(rel)
+	return mediaHashPattern.MatchString(name) && filepath.Clean(dir) == name[:2]
+}
+
+// deleteUnreferencedMedia 在事务中删除指定媒体中已没有卡片或笔记（包括回收站中的）引用的记录，返回删除的媒体的哈希
+func deleteUnreferencedMedia(tx *gorm.DB, ids []uint) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var unused []models.Media
+	err := tx.Where("id IN ?", ids).
+		Where("id NOT IN (SELECT media_id FROM card_media)").
+		Where("id NOT IN (SELECT media_id FROM notes WHERE media_id IS NOT NULL)").
+		Find(&unused).Error
+	if err != nil || len(unused) == 0 {
+		return nil, err
+	}
+
+	hashes := make([]string, len(unused))
+	for i, media := range unused {
+		hashes[i] = media.Hash
+	}
+	if err := tx.Delete(&unused).Error; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// removeMediaFiles 在删除媒体记录的事务提交后删除对应的文件，期间重新上传了相同内容的文件保留
+func removeMediaFiles(db *gorm.DB, hashes []string) {
+	for _, hash := range hashes {
+		var count int64
+		if err := db.Model(&models.Media{}).Where("hash = ?", hash).Count(&count).Error; err != nil || count > 0 {
+			continue
+		}
+		if err := os.Remove(MediaPath(hash)); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除媒体文件失败: %v", err)
+		}
+	}
+}
+
+// detectMediaType 根据文件内容和扩展名识别媒体类型，只允许图片和音频（SVG可能包含脚本，不允许）
+func detectMediaType(filename string, data []byte) (string, error) {
+	allowed := func(mimeType string) bool {
+		if strings.HasPrefix(mimeType, "image/svg") {
+			return false
+		}
+		return strings.HasPrefix(mimeType, "image/") || strings.HasPrefix(mimeType, "audio/")
+	}
+
+	mimeType := http.DetectContentType(data)
+	if allowed(mimeType) {
+		return mimeType, nil
+	}
+
+	// 部分音频格式无法通过内容识别，回退到扩展名
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))); allowed(byExt) {
+		return byExt, nil
+	}
+
+	return "", ErrUnsupportedMedia
+}
+
+// writeMediaFile 写入媒体文件，文件已存在时跳过
+func writeMediaFile(hash string, data []byte) error {
+	path := MediaPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名，避免留下不完整的文件
+	tempFile, err := os.CreateTemp(filepath.Dir(path), hash+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := io.Copy(tempFile, bytes.NewReader(data)); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
+
+// RestoreMediaFile 从备份中恢复媒体文件，内容不能超过媒体文件大小上限，并校验内容与哈希一致
+func RestoreMediaFile(hash string, r io.Reader) error {
+	if !mediaHashPattern.MatchString(hash) {
+		return ErrMediaHashMismatch
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxMediaSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMediaSize {
+		return ErrMediaTooLarge
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return ErrMediaHashMismatch
+	}
+
+	return writeMediaFile(hash, data)
+}
+
+// uniqueIDs 去除重复的ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var result []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnsupportedNoteType 不支持的笔记类型
+var ErrUnsupportedNoteType = errors.New("不支持的笔记类型")
+
+// NoteService 笔记服务
+type NoteService struct {
+	db *gorm.DB
+}
+
+// NewNoteService 创建笔记服务实例
+func NewNoteService() *NoteService {
+	return &NoteService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateNote 创建笔记并生成对应的卡片
+func (s *NoteService) CreateNote(deckID uint, tagIDs []uint, noteType, content, extra string) (*models.Note, error) {
+	if noteType != models.CardTypeCloze {
+		return nil, ErrUnsupportedNoteType
+	}
+
+	note := &models.Note{
+		DeckID:  deckID,
+		Type:    noteType,
+		Content: content,
+		Extra:   extra,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		tags, err := findTags(tx, tagIDs)
+		if err != nil {
+			return err
+		}
+		note.Tags = tags
+
+		return createNoteWithCards(tx, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(note.ID)
+}
+
+// CreateOcclusionNote 创建图片遮挡笔记，每个遮挡区域生成一张卡片
+func (s *NoteService) CreateOcclusionNote(deckID uint, tagIDs []uint, mediaID uint, masks models.OcclusionMasks, content, extra string) (*models.Note, error) {
+	if err := validateMasks(masks); err != nil {
+		return nil, err
+	}
+
+	note := &models.Note{
+		DeckID:  deckID,
+		Type:    models.CardTypeOcclusion,
+		Content: content,
+		Extra:   extra,
+		MediaID: &mediaID,
+		Masks:   assignMaskIDs(nil, masks),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkOcclusionImage(tx, mediaID); err != nil {
+			return err
+		}
+
+		tags, err := findTags(tx, tagIDs)
+		if err != nil {
+			return err
+		}
+		note.Tags = tags
+
+		return createNoteWithCards(tx, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(note.ID)
+}
+
+// CreateTemplateNote 创建自定义笔记类型的笔记，按笔记类型的每个卡片模板生成一张卡片
+func (s *NoteService) CreateTemplateNote(deckID uint, tagIDs []uint, noteTypeID uint, fields map[string]string) (*models.Note, error) {
+	note := &models.Note{
+		DeckID:     deckID,
+		Type:       models.CardTypeTemplate,
+		NoteTypeID: &noteTypeID,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := setTemplateNoteFields(tx, note, fields); err != nil {
+			return err
+		}
+
+		tags, err := findTags(tx, tagIDs)
+		if err != nil {
+			return err
+		}
+		note.Tags = tags
+
+		return createNoteWithCards(tx, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(note.ID)
+}
+
+// GetNoteByID 根据ID获取笔记及其卡片
+func (s *NoteService) GetNoteByID(id uint) (*models.Note, error) {
+	var note models.Note
+	if err := s.db.Preload("Media").Preload("NoteType").Preload("Tags").Preload("Cards", func(db *gorm.DB) *gorm.DB {
+		return db.Order("ord ASC")
+	}).First(&note, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// UpdateNote 更新笔记内容并同步卡片，未变化的填空编号保留原有复习进度
+func (s *NoteService) UpdateNote(id uint, deckID uint, tagIDs []uint, content, extra string) (*models.Note, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Note
+		if err := tx.First(&note, id).Error; err != nil {
+			return err
+		}
+
+		note.DeckID = deckID
+		note.Content = content
+		note.Extra = extra
+
+		if err := saveNoteWithTags(tx, &note, tagIDs); err != nil {
+			return err
+		}
+
+		return syncNoteCards(tx, &note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(id)
+}
+
+// UpdateOcclusionNote 更新图片遮挡笔记，几何形状未变化的区域保留原有复习进度
+func (s *NoteService) UpdateOcclusionNote(id uint, deckID uint, tagIDs []uint, mediaID uint, masks models.OcclusionMasks, content, extra string) (*models.Note, error) {
+	if err := validateMasks(masks); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Note
+		if err := tx.First(&note, id).Error; err != nil {
+			return err
+		}
+
+		if note.Type != models.CardTypeOcclusion {
+			return ErrUnsupportedNoteType
+		}
+
+		if err := checkOcclusionImage(tx, mediaID); err != nil {
+			return err
+		}
+
+		note.DeckID = deckID
+		note.Content = content
+		note.Extra = extra
+		note.MediaID = &mediaID
+		note.Masks = assignMaskIDs(note.Masks, masks)
+
+		if err := saveNoteWithTags(tx, &note, tagIDs); err != nil {
+			return err
+		}
+
+		return syncNoteCards(tx, &note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(id)
+}
+
+// UpdateTemplateNote 更新模板笔记的字段内容并同步卡片，模板序号不变的卡片保留原有复习进度
+func (s *NoteService) UpdateTemplateNote(id uint, deckID uint, tagIDs []uint, fields map[string]string) (*models.Note, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Note
+		if err := tx.First(&note, id).Error; err != nil {
+			return err
+		}
+
+		if note.Type != models.CardTypeTemplate || note.NoteTypeID == nil {
+			return ErrUnsupportedNoteType
+		}
+
+		if err := setTemplateNoteFields(tx, &note, fields); err != nil {
+			return err
+		}
+		note.DeckID = deckID
+
+		if err := saveNoteWithTags(tx, &note, tagIDs); err != nil {
+			return err
+		}
+
+		return syncNoteCards(tx, &note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetNoteByID(id)
+}
+
+// DeleteNote 删除笔记及其生成的所有卡片
+func (s *NoteService) DeleteNote(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Note
+		if err := tx.First(&note, id).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := softDeleteWith(tx, now, &models.Card{}, "note_id = ?", id); err != nil {
+			return err
+		}
+
+		return softDeleteWith(tx, now, &models.Note{}, "id = ?", id)
+	})
+}
+
+// createNoteWithCards 在事务中创建笔记并生成卡片
+func createNoteWithCards(tx *gorm.DB, note *models.Note) error {
+	if _, err := noteOrds(note); err != nil {
+		return err
+	}
+
+	if err := tx.Omit("NoteType").Create(note).Error; err != nil {
+		return err
+	}
+
+	return syncNoteCards(tx, note)
+}
+
+// saveNoteWithTags 保存笔记并替换标签
+func saveNoteWithTags(tx *gorm.DB, note *models.Note, tagIDs []uint) error {
+	tags, err := findTags(tx, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Omit("Tags", "NoteType").Save(note).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(note).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	note.Tags = tags
+
+	return nil
+}
+
+// setTemplateNoteFields 载入模板笔记的笔记类型并设置字段内容，笔记内容取第一个字段
+func setTemplateNoteFields(tx *gorm.DB, note *models.Note, fields map[string]string) error {
+	noteType, err := findNoteType(tx, *note.NoteTypeID)
+	if err != nil {
+		return err
+	}
+
+	normalized, err := normalizeNoteFields(noteType, fields)
+	if err != nil {
+		return err
+	}
+
+	note.NoteType = noteType
+	note.Fields = normalized
+	note.Content = templateNoteContent(noteType, normalized)
+	return nil
+}
+
+// noteOrds 获取笔记需要生成卡片的编号：填空笔记为填空编号，图片遮挡笔记为区域编号，模板笔记为模板序号
+func noteOrds(note *models.Note) ([]int, error) {
+	if note.Type == models.CardTypeTemplate {
+		return templateNoteOrds(note)
+	}
+
+	if note.Type == models.CardTypeOcclusion {
+		if len(note.Masks) == 0 {
+			return nil, ErrInvalidMasks
+		}
+		ords := make([]int, len(note.Masks))
+		for i, mask := range note.Masks {
+			ords[i] = mask.ID
+		}
+		return ords, nil
+	}
+
+	ords := parseClozeOrds(note.Content)
+	if len(ords) == 0 {
+		return nil, ErrNoCloze
+	}
+	return ords, nil
+}
+
+// checkOcclusionImage 检查图片遮挡笔记引用的媒体是否为图片
+func checkOcclusionImage(tx *gorm.DB, mediaID uint) error {
+	var media models.Media
+	if err := tx.First(&media, mediaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOcclusionImage
+		}
+		return err
+	}
+	if !strings.HasPrefix(media.MimeType, "image/") {
+		return ErrOcclusionImage
+	}
+	return nil
+}
+
+// syncNoteCards 根据笔记内容同步卡片：新增编号创建卡片，已有编号更新内容，消失的编号删除卡片；
+// 回收站中的卡片也算作已有编号，只更新内容而不重新创建，避免恢复后出现编号相同的卡片
+func syncNoteCards(tx *gorm.DB, note *models.Note) error {
+	if note.Type == models.CardTypeTemplate && note.NoteType == nil && note.NoteTypeID != nil {
+		noteType, err := findNoteType(tx, *note.NoteTypeID)
+		if err != nil {
+			return err
+		}
+		note.NoteType = noteType
+	}
+
+	ords, err := noteOrds(note)
+	if err != nil {
+		return err
+	}
+
+	var media []models.Media
+	if note.MediaID != nil {
+		if err := tx.Find(&media, *note.MediaID).Error; err != nil {
+			return err
+		}
+	}
+
+	var existing []models.Card
+	if err := tx.Unscoped().Where("note_id = ?", note.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	cardsByOrd := make(map[int]models.Card, len(existing))
+	for _, card := range existing {
+		cardsByOrd[card.Ord] = card
+	}
+
+	for _, ord := range ords {
+		card, exists := cardsByOrd[ord]
+		if !exists {
+			// 回收站中的笔记新生成的卡片与笔记一起删除，恢复笔记时一并恢复
+			card = models.Card{
+				NoteID:    &note.ID,
+				Type:      note.Type,
+				Ord:       ord,
+				DeletedAt: note.DeletedAt,
+			}
+		}
+		delete(cardsByOrd, ord)
+
+		// 新卡片和移动到其他卡包的卡片排在卡包的最后
+		if !exists || card.DeckID != note.DeckID {
+			if card.Position, err = nextCardPosition(tx, note.DeckID); err != nil {
+				return err
+			}
+		}
+		card.DeckID = note.DeckID
+		card.Question = note.Content
+		card.Answer = note.Extra
+		card.Masks = note.Masks
+
+		// 模板卡片保存渲染后的正面和背面
+		if note.Type == models.CardTypeTemplate {
+			card.Question, card.Answer, _ = renderCardTemplate(note.NoteType.Templates[ord-1], note.Fields)
+			card.Format = note.NoteType.Format
+		}
+
+		if err := tx.Unscoped().Save(&card).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&card).Association("Tags").Replace(note.Tags); err != nil {
+			return err
+		}
+
+		// 图片遮挡卡片引用笔记的图片，避免图片被当作未引用媒体清理
+		if len(media) > 0 {
+			if err := tx.Model(&card).Association("Media").Replace(media); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 删除内容中已不存在的编号对应的卡片
+	for _, card := range cardsByOrd {
+		if card.DeletedAt.Valid {
+			continue
+		}
+		if err := tx.Where("card_id = ?", card.ID).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&card).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
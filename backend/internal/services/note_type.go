@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 笔记类型相关错误
+var (
+	ErrNoteTypeNotFound     = errors.New("笔记类型不存在")
+	ErrNoteTypeNameConflict = errors.New("已存在同名的笔记类型")
+	ErrNoteTypeInUse        = errors.New("笔记类型仍被笔记使用（包括回收站中的笔记），无法删除")
+	ErrNoteFieldInUse       = errors.New("删除的字段在笔记中仍有内容，请通过field_renames指定新的字段名或先清空该字段")
+	ErrUnknownNoteField     = errors.New("笔记类型中不存在该字段")
+	ErrEmptyTemplateNote    = errors.New("笔记的字段内容为空，没有生成任何卡片")
+	ErrNoteTypeColumns      = errors.New("CSV表头中没有与笔记类型字段对应的列")
+)
+
+// NoteTypeService 笔记类型服务
+type NoteTypeService struct {
+	db *gorm.DB
+}
+
+// NewNoteTypeService 创建笔记类型服务实例
+func NewNoteTypeService() *NoteTypeService {
+	return &NoteTypeService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateNoteType 创建笔记类型
+func (s *NoteTypeService) CreateNoteType(name string, fields models.NoteFieldNames, templates models.CardTemplates, format string) (*models.NoteType, error) {
+	noteType := &models.NoteType{
+		Name:      name,
+		Fields:    fields,
+		Templates: templates,
+		Format:    normalizeFormat(format),
+	}
+	if err := validateNoteType(noteType); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNoteTypeName(tx, noteType.Name, 0); err != nil {
+			return err
+		}
+		return tx.Create(noteType).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	return noteType, nil
+}
+
+// GetNoteTypes 获取所有笔记类型
+func (s *NoteTypeService) GetNoteTypes() ([]models.NoteType, error) {
+	noteTypes := []models.NoteType{}
+	if err := s.db.Order("id ASC").Find(&noteTypes).Error; err != nil {
+		return nil, err
+	}
+
+	return noteTypes, nil
+}
+
+// GetNoteTypeByID 根据ID获取笔记类型
+func (s *NoteTypeService) GetNoteTypeByID(id uint) (*models.NoteType, error) {
+	var noteType models.NoteType
+	if err := s.db.First(&noteType, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &noteType, nil
+}
+
+// UpdateNoteType 更新笔记类型并重新生成该类型所有笔记（包括回收站中的笔记）的卡片，参数为空时保持原值。
+// renames为旧字段名到新字段名的映射，重命名的字段保留笔记内容；删除的字段在笔记中仍有内容时返回ErrNoteFieldInUse
+func (s *NoteTypeService) UpdateNoteType(id uint, name string, fields models.NoteFieldNames, templates models.CardTemplates, format string, renames map[string]string) (*models.NoteType, error) {
+	var noteType models.NoteType
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&noteType, id).Error; err != nil {
+			return err
+		}
+		oldFields := noteType.Fields
+
+		if name != "" {
+			noteType.Name = name
+		}
+		if fields != nil {
+			noteType.Fields = fields
+		}
+		if templates != nil {
+			noteType.Templates = templates
+		}
+		if format != "" {
+			noteType.Format = normalizeFormat(format)
+		}
+		if err := validateNoteType(&noteType); err != nil {
+			return err
+		}
+		sources, err := noteFieldSources(oldFields, noteType.Fields, renames)
+		if err != nil {
+			return err
+		}
+		if err := checkNoteTypeName(tx, noteType.Name, noteType.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Save(&noteType).Error; err != nil {
+			return err
+		}
+
+		// 模板或字段变化后重新生成卡片，模板序号不变的卡片保留复习进度。
+		// 回收站中的笔记一并更新，避免恢复后内容与笔记类型不一致
+		var notes []models.Note
+		if err := tx.Unscoped().Preload("Tags").Where("note_type_id = ?", noteType.ID).Find(&notes).Error; err != nil {
+			return err
+		}
+		for i := range notes {
+			note := &notes[i]
+			fields, err := renameNoteFields(note.Fields, noteType.Fields, sources)
+			if err != nil {
+				return err
+			}
+			note.Fields = fields
+			note.Content = templateNoteContent(&noteType, fields)
+			note.NoteType = &noteType
+			if err := tx.Unscoped().Omit("Tags", "NoteType").Save(note).Error; err != nil {
+				return err
+			}
+			if err := syncNoteCards(tx, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &noteType, nil
+}
+
+// DeleteNoteType 删除笔记类型，仍有笔记使用时不能删除
+func (s *NoteTypeService) DeleteNoteType(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.NoteType{}, id).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.Note{}).Where("note_type_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrNoteTypeInUse
+		}
+
+		return tx.Delete(&models.NoteType{}, id).Error
+	})
+}
+
+// noteFieldSources 根据字段重命名映射计算每个新字段的内容来自哪个旧字段，没有重命名的同名字段保留原内容
+func noteFieldSources(oldFields, newFields models.NoteFieldNames, renames map[string]string) (map[string]string, error) {
+	oldNames := make(map[string]bool, len(oldFields))
+	for _, name := range oldFields {
+		oldNames[name] = true
+	}
+	newNames := make(map[string]bool, len(newFields))
+	for _, name := range newFields {
+		newNames[name] = true
+	}
+
+	sources := make(map[string]string, len(newFields))
+	for from, to := range renames {
+		if !oldNames[from] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNoteField, from)
+		}
+		if !newNames[to] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNoteField, to)
+		}
+		if _, exists := sources[to]; exists {
+			return nil, invalidNoteType("多个字段重命名为 %s", to)
+		}
+		sources[to] = from
+	}
+	for _, name := range newFields {
+		if _, renamed := sources[name]; !renamed && oldNames[name] {
+			if _, movedAway := renames[name]; !movedAway {
+				sources[name] = name
+			}
+		}
+	}
+	return sources, nil
+}
+
+// renameNoteFields 按新字段的来源整理笔记的字段内容，没有对应新字段的旧字段仍有内容时返回ErrNoteFieldInUse
+func renameNoteFields(fields models.NoteFields, newFields models.NoteFieldNames, sources map[string]string) (models.NoteFields, error) {
+	used := make(map[string]bool, len(sources))
+	renamed := make(models.NoteFields, len(newFields))
+	for _, name := range newFields {
+		if source, ok := sources[name]; ok {
+			renamed[name] = fields[source]
+			used[source] = true
+		} else {
+			renamed[name] = ""
+		}
+	}
+	for name, value := range fields {
+		if !used[name] && strings.TrimSpace(value) != "" {
+			return nil, fmt.Errorf("%w: %s", ErrNoteFieldInUse, name)
+		}
+	}
+	return renamed, nil
+}
+
+// checkNoteTypeName 检查笔记类型名称是否与其他未删除的笔记类型重复
+func checkNoteTypeName(tx *gorm.DB, name string, excludeID uint) error {
+	var count int64
+	if err := tx.Model(&models.NoteType{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrNoteTypeNameConflict
+	}
+	return nil
+}
+
+// findNoteType 获取笔记使用的笔记类型，不存在时返回ErrNoteTypeNotFound
+func findNoteType(tx *gorm.DB, id uint) (*models.NoteType, error) {
+	var noteType models.NoteType
+	if err := tx.First(&noteType, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteTypeNotFound
+		}
+		return nil, err
+	}
+	return &noteType, nil
+}
+
+// normalizeNoteFields 按笔记类型的字段名称整理字段内容：字段名不区分大小写，未提供的字段为空
+func normalizeNoteFields(noteType *models.NoteType, fields map[string]string) (models.NoteFields, error) {
+	byName := make(map[string]string, len(noteType.Fields))
+	for _, name := range noteType.Fields {
+		byName[strings.ToLower(name)] = name
+	}
+
+	normalized := make(models.NoteFields, len(noteType.Fields))
+	for _, name := range noteType.Fields {
+		normalized[name] = ""
+	}
+	for key, value := range fields {
+		name, ok := byName[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNoteField, key)
+		}
+		normalized[name] = value
+	}
+	return normalized, nil
+}
+
+// templateNoteOrds 获取模板笔记需要生成卡片的模板序号（从1开始），正面为空的模板不生成卡片
+func templateNoteOrds(note *models.Note) ([]int, error) {
+	if note.NoteType == nil {
+		return nil, ErrNoteTypeNotFound
+	}
+
+	var ords []int
+	for i, tmpl := range note.NoteType.Templates {
+		if _, _, ok := renderCardTemplate(tmpl, note.Fields); ok {
+			ords = append(ords, i+1)
+		}
+	}
+	if len(ords) == 0 {
+		return nil, ErrEmptyTemplateNote
+	}
+	return ords, nil
+}
+
+// templateNoteContent 模板笔记的内容为第一个字段的内容，用于排序和查重
+func templateNoteContent(noteType *models.NoteType, fields models.NoteFields) string {
+	if len(noteType.Fields) == 0 {
+		return ""
+	}
+	return fields[noteType.Fields[0]]
+}
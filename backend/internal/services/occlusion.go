@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidMasks 遮挡区域不合法
+	ErrInvalidMasks = errors.New("至少需要一个遮挡区域，矩形需要正的宽高，多边形至少需要三个顶点")
+	// ErrOcclusionImage 遮挡笔记需要图片
+	ErrOcclusionImage = errors.New("图片遮挡笔记需要引用一张已上传的图片")
+)
+
+// validateMasks 校验遮挡区域
+func validateMasks(masks models.OcclusionMasks) error {
+	if len(masks) == 0 {
+		return ErrInvalidMasks
+	}
+
+	for _, mask := range masks {
+		switch mask.Shape {
+		case models.MaskShapeRect:
+			if mask.Width <= 0 || mask.Height <= 0 {
+				return ErrInvalidMasks
+			}
+		case models.MaskShapePolygon:
+			if len(mask.Points) < 3 {
+				return ErrInvalidMasks
+			}
+		default:
+			return ErrInvalidMasks
+		}
+	}
+
+	return nil
+}
+
+// maskKey 遮挡区域的几何特征，用于判断编辑前后区域是否变化（不包含标签）
+func maskKey(mask models.OcclusionMask) string {
+	var b strings.Builder
+	b.WriteString(mask.Shape)
+	if mask.Shape == models.MaskShapeRect {
+		fmt.Fprintf(&b, ":%g,%g,%g,%g", mask.X, mask.Y, mask.Width, mask.Height)
+		return b.String()
+	}
+	for _, point := range mask.Points {
+		fmt.Fprintf(&b, ":%g,%g", point[0], point[1])
+	}
+	return b.String()
+}
+
+// assignMaskIDs 为遮挡区域分配编号：几何形状未变化的区域沿用原编号，新区域使用递增的新编号
+func assignMaskIDs(previous, masks models.OcclusionMasks) models.OcclusionMasks {
+	idsByKey := make(map[string][]int, len(previous))
+	nextID := 1
+	for _, mask := range previous {
+		key := maskKey(mask)
+		idsByKey[key] = append(idsByKey[key], mask.ID)
+		if mask.ID >= nextID {
+			nextID = mask.ID + 1
+		}
+	}
+
+	result := make(models.OcclusionMasks, len(masks))
+	for i, mask := range masks {
+		key := maskKey(mask)
+		if ids := idsByKey[key]; len(ids) > 0 {
+			mask.ID = ids[0]
+			idsByKey[key] = ids[1:]
+		} else {
+			mask.ID = nextID
+			nextID++
+		}
+		result[i] = mask
+	}
+
+	return result
+}
+
+// splitMasks 拆分出当前卡片需要遮挡的区域和其他区域
+func splitMasks(masks models.OcclusionMasks, ord int) (*models.OcclusionMask, []models.OcclusionMask) {
+	var hide *models.OcclusionMask
+	var show []models.OcclusionMask
+	for i := range masks {
+		if masks[i].ID == ord && hide == nil {
+			hide = &masks[i]
+			continue
+		}
+		show = append(show, masks[i])
+	}
+	return hide, show
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSearchQuery 搜索语句格式错误
+var ErrInvalidSearchQuery = errors.New("搜索语句格式错误")
+
+// flagNames 搜索语句中可以使用的标记颜色名称
+var flagNames = map[string]int{
+	"none":      models.FlagNone,
+	"red":       models.FlagRed,
+	"orange":    models.FlagOrange,
+	"green":     models.FlagGreen,
+	"blue":      models.FlagBlue,
+	"pink":      models.FlagPink,
+	"turquoise": models.FlagTurquoise,
+	"purple":    models.FlagPurple,
+}
+
+// cardTypes 搜索语句中可以使用的卡片类型
+var cardTypes = []string{models.CardTypeBasic, models.CardTypeCloze, models.CardTypeChoice, models.CardTypeOcclusion, models.CardTypeTemplate}
+
+// propColumns prop: 条件可以比较的复习属性
+var propColumns = map[string]string{
+	"ivl":    "reviews.interval",
+	"reps":   "reviews.repetitions",
+	"lapses": "reviews.lapses",
+	"ease":   "reviews.e_factor",
+	"due":    "julianday(date(reviews.next_review, 'localtime')) - julianday(date('now', 'localtime'))", // 距离到期的天数，负数为已过期
+}
+
+// propPattern 匹配 prop: 条件的属性、比较运算符和数值，如 ivl>=30
+var propPattern = regexp.MustCompile(`^([a-z]+)(<=|>=|!=|=|<|>)(-?\d+(?:\.\d+)?)$`)
+
+// queryTokenKind 搜索语句的词法单元类型
+type queryTokenKind int
+
+const (
+	tokenTerm       queryTokenKind = iota // 搜索条件或文本
+	tokenNot                              // 条件前的 -
+	tokenOr                               // OR
+	tokenLeftParen                        // (
+	tokenRightParen                       // )
+)
+
+// queryToken 搜索语句的词法单元
+type queryToken struct {
+	kind   queryTokenKind
+	key    string // 搜索条件名称（如 deck、tag），纯文本为空
+	value  string // 去掉引号后的内容
+	quoted bool   // 内容是否带有引号
+	pos    int    // 在搜索语句中的位置（从1开始的字符序号），用于错误提示
+}
+
+// searchCondition 搜索语句转换得到的SQL条件
+type searchCondition struct {
+	sql  string
+	args []interface{}
+}
+
+// cardQuery 解析后的卡片搜索语句
+type cardQuery struct {
+	condition searchCondition
+	terms     []string // 需要在摘要中高亮的文本
+}
+
+// parseCardQuery 解析卡片搜索语句，语句为空时返回nil
+//
+// 语法：空格分隔的条件同时满足，OR 连接的条件满足其一，- 排除满足条件的卡片，括号用于分组，
+// 包含空格的内容用双引号括起，* 匹配任意字符。支持的条件：
+//
+//	文本             问题或答案包含该文本
+//	front: back:     问题或答案整体匹配，如 front:foo* 为以foo开头
+//	deck:            卡包名称，包括子卡包
+//	tag:             标签名称或路径，包括子标签；tag:none 为没有标签的卡片
+//	is:              new 新卡片、due 到期（包括新卡片）、review 复习过、suspended 暂停、flagged 有标记
+//	flag:            标记颜色的编号（0-7）或名称
+//	type:            卡片类型
+//	prop:            复习属性 ivl、reps、lapses、ease、due 与数值比较，如 prop:ivl>30
+//	added: edited: rated:  最近N天内创建、修改、复习过的卡片，1为今天
+func parseCardQuery(query string) (*cardQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &queryParser{tokens: tokens, now: time.Now()}
+	condition, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, queryError(p.tokens[p.pos].pos, "多余的右括号")
+	}
+
+	return &cardQuery{condition: condition, terms: p.terms}, nil
+}
+
+// applyCardQuery 按搜索语句过滤卡片，语句格式错误时将错误记录到查询中
+func applyCardQuery(query *gorm.DB, q string) *gorm.DB {
+	parsed, err := parseCardQuery(q)
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+	if parsed == nil {
+		return query
+	}
+	return query.Where(parsed.condition.sql, parsed.condition.args...)
+}
+
+// queryError 创建带有出错位置的搜索语句错误
+func queryError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w（第%d个字符）: %s", ErrInvalidSearchQuery, pos, fmt.Sprintf(format, args...))
+}
+
+// tokenizeQuery 将搜索语句拆分为词法单元
+func tokenizeQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLeftParen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRightParen, pos: i + 1})
+			i++
+		case r == '-':
+			if i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == ')' {
+				return nil, queryError(i+1, "- 后缺少搜索条件")
+			}
+			tokens = append(tokens, queryToken{kind: tokenNot, pos: i + 1})
+			i++
+		default:
+			token, next, err := readQueryTerm(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = next
+		}
+	}
+	return tokens, nil
+}
+
+// readQueryTerm 从start开始读取一个搜索条件，直到引号外的空白或括号，返回条件和下一个字符的位置
+func readQueryTerm(runes []rune, start int) (queryToken, int, error) {
+	token := queryToken{kind: tokenTerm, pos: start + 1}
+	var value strings.Builder
+	quoted := false
+
+	i := start
+	for ; i < len(runes); i++ {
+		r := runes[i]
+		if quoted {
+			switch {
+			case r == '"':
+				quoted = false
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				value.WriteRune(runes[i])
+			default:
+				value.WriteRune(r)
+			}
+			continue
+		}
+
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+		switch {
+		case r == '"':
+			quoted = true
+			token.quoted = true
+		case r == ':' && token.key == "" && !token.quoted && value.Len() > 0 && isQueryKey(value.String()):
+			token.key = strings.ToLower(value.String())
+			value.Reset()
+		default:
+			value.WriteRune(r)
+		}
+	}
+	if quoted {
+		return token, i, queryError(start+1, "引号没有闭合")
+	}
+
+	token.value = value.String()
+	if token.key != "" && token.value == "" {
+		return token, i, queryError(start+1, "%s: 后缺少内容", token.key)
+	}
+	if token.key == "" && !token.quoted && strings.EqualFold(token.value, "or") {
+		token.kind = tokenOr
+	}
+	return token, i, nil
+}
+
+// isQueryKey 判断冒号前的文本是否为搜索条件名称（只包含英文字母）
+func isQueryKey(text string) bool {
+	for _, r := range text {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryParser 搜索语句解析器，OR 的优先级低于空格表示的 AND
+type queryParser struct {
+	tokens  []queryToken
+	pos     int
+	depth   int       // 当前所在括号的层数
+	negated bool      // 当前是否在 - 排除的条件中，排除的文本不高亮
+	terms   []string  // 需要高亮的文本
+	now     time.Time // 解析时的时间，用于到期和最近N天的条件
+}
+
+// parseOr 解析 OR 连接的条件
+func (p *queryParser) parseOr() (searchCondition, error) {
+	conditions := []searchCondition{}
+	for {
+		condition, err := p.parseAnd()
+		if err != nil {
+			return searchCondition{}, err
+		}
+		conditions = append(conditions, condition)
+
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOr {
+			return joinConditions(" OR ", conditions), nil
+		}
+		p.pos++
+	}
+}
+
+// parseAnd 解析空格分隔、需要同时满足的条件
+func (p *queryParser) parseAnd() (searchCondition, error) {
+	conditions := []searchCondition{}
+	for p.pos < len(p.tokens) {
+		kind := p.tokens[p.pos].kind
+		if kind == tokenOr || kind == tokenRightParen {
+			break
+		}
+		condition, err := p.parseUnary()
+		if err != nil {
+			return searchCondition{}, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) == 0 {
+		switch {
+		case p.pos >= len(p.tokens):
+			// 只有以 OR 结尾时才会在末尾缺少条件
+			return searchCondition{}, queryError(p.tokens[p.pos-1].pos, "OR 后缺少搜索条件")
+		case p.tokens[p.pos].kind == tokenOr:
+			return searchCondition{}, queryError(p.tokens[p.pos].pos, "OR 前缺少搜索条件")
+		case p.depth == 0:
+			return searchCondition{}, queryError(p.tokens[p.pos].pos, "多余的右括号")
+		default:
+			return searchCondition{}, queryError(p.tokens[p.pos].pos, "括号内缺少搜索条件")
+		}
+	}
+	return joinConditions(" AND ", conditions), nil
+}
+
+// parseUnary 解析单个条件、排除的条件或括号内的条件
+func (p *queryParser) parseUnary() (searchCondition, error) {
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case tokenNot:
+		if p.pos >= len(p.tokens) {
+			return searchCondition{}, queryError(token.pos, "- 后缺少搜索条件")
+		}
+		p.negated = !p.negated
+		condition, err := p.parseUnary()
+		p.negated = !p.negated
+		if err != nil {
+			return searchCondition{}, err
+		}
+		return searchCondition{sql: "NOT (" + condition.sql + ")", args: condition.args}, nil
+
+	case tokenLeftParen:
+		p.depth++
+		condition, err := p.parseOr()
+		p.depth--
+		if err != nil {
+			return searchCondition{}, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenRightParen {
+			return searchCondition{}, queryError(token.pos, "括号没有闭合")
+		}
+		p.pos++
+		return condition, nil
+
+	default:
+		return p.termCondition(token)
+	}
+}
+
+// joinConditions 用AND或OR连接多个条件
+func joinConditions(operator string, conditions []searchCondition) searchCondition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+
+	parts := make([]string, len(conditions))
+	var args []interface{}
+	for i, condition := range conditions {
+		parts[i] = "(" + condition.sql + ")"
+		args = append(args, condition.args...)
+	}
+	return searchCondition{sql: strings.Join(parts, operator), args: args}
+}
+
+// termCondition 将单个搜索条件转换为SQL条件
+func (p *queryParser) termCondition(token queryToken) (searchCondition, error) {
+	value := token.value
+	switch token.key {
+	case "":
+		p.highlight(value)
+		return textCondition(value), nil
+
+	case "front", "back":
+		p.highlight(value)
+		column := "cards.question"
+		if token.key == "back" {
+			column = "cards.answer"
+		}
+		return searchCondition{sql: column + ` LIKE ? ESCAPE '\'`, args: []interface{}{globToLike(value)}}, nil
+
+	case "deck":
+		return searchCondition{
+			sql: "cards.deck_id IN (WITH RECURSIVE subtree(id) AS (" +
+				`SELECT id FROM decks WHERE name LIKE ? ESCAPE '\' AND deleted_at IS NULL ` +
+				"UNION SELECT decks.id FROM decks JOIN subtree ON decks.parent_id = subtree.id WHERE decks.deleted_at IS NULL) " +
+				"SELECT id FROM subtree)",
+			args: []interface{}{globToLike(value)},
+		}, nil
+
+	case "tag":
+		if strings.EqualFold(value, "none") {
+			return searchCondition{sql: "cards.id NOT IN (SELECT card_id FROM card_tags)"}, nil
+		}
+		pattern := globToLike(value)
+		return searchCondition{
+			sql: "cards.id IN (SELECT card_tags.card_id FROM card_tags WHERE card_tags.tag_id IN (WITH RECURSIVE subtree(id) AS (" +
+				`SELECT id FROM tags WHERE (name LIKE ? ESCAPE '\' OR path LIKE ? ESCAPE '\') AND deleted_at IS NULL ` +
+				"UNION SELECT tags.id FROM tags JOIN subtree ON tags.parent_id = subtree.id WHERE tags.deleted_at IS NULL) " +
+				"SELECT id FROM subtree))",
+			args: []interface{}{pattern, pattern},
+		}, nil
+
+	case "is":
+		return p.stateCondition(token)
+
+	case "flag":
+		flag, ok := flagNames[strings.ToLower(value)]
+		if !ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || !validFlag(n) {
+				return searchCondition{}, queryError(token.pos, "flag: 的值必须是%d-%d或颜色名称（如red、blue）", models.FlagNone, models.FlagMax)
+			}
+			flag = n
+		}
+		return searchCondition{sql: "cards.flag = ?", args: []interface{}{flag}}, nil
+
+	case "type":
+		cardType := strings.ToLower(value)
+		for _, t := range cardTypes {
+			if t == cardType {
+				return searchCondition{sql: "cards.type = ?", args: []interface{}{cardType}}, nil
+			}
+		}
+		return searchCondition{}, queryError(token.pos, "type: 的值必须是 %s 之一", strings.Join(cardTypes, "、"))
+
+	case "prop":
+		return propCondition(token)
+
+	case "added", "edited", "rated":
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			return searchCondition{}, queryError(token.pos, "%s: 的值必须是正整数天数", token.key)
+		}
+		year, month, day := p.now.Date()
+		since := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location()).AddDate(0, 0, 1-days)
+		switch token.key {
+		case "added":
+			return searchCondition{sql: "cards.created_at >= ?", args: []interface{}{since}}, nil
+		case "edited":
+			return searchCondition{sql: "cards.updated_at >= ?", args: []interface{}{since}}, nil
+		default:
+			return searchCondition{sql: "cards.id IN (SELECT card_id FROM review_logs WHERE reviewed_at >= ?)", args: []interface{}{since}}, nil
+		}
+
+	default:
+		return searchCondition{}, queryError(token.pos, "未知的搜索条件 %s:，搜索包含冒号的文本请加引号", token.key)
+	}
+}
+
+// stateCondition 转换 is: 条件
+func (p *queryParser) stateCondition(token queryToken) (searchCondition, error) {
+	switch strings.ToLower(token.value) {
+	case "new":
+		return searchCondition{sql: "NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.card_id = cards.id)"}, nil
+	case "due":
+		return searchCondition{
+			sql:  "NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.card_id = cards.id AND reviews.next_review > ?)",
+			args: []interface{}{p.now},
+		}, nil
+	case "review":
+		return searchCondition{sql: "EXISTS (SELECT 1 FROM reviews WHERE reviews.card_id = cards.id)"}, nil
+	case "suspended":
+		return searchCondition{sql: "cards.suspended = ?", args: []interface{}{true}}, nil
+	case "flagged":
+		return searchCondition{sql: "cards.flag <> ?", args: []interface{}{models.FlagNone}}, nil
+	default:
+		return searchCondition{}, queryError(token.pos, "is: 的值必须是 new、due、review、suspended、flagged 之一")
+	}
+}
+
+// propCondition 转换 prop: 条件，没有复习记录的卡片不满足任何属性条件
+func propCondition(token queryToken) (searchCondition, error) {
+	match := propPattern.FindStringSubmatch(strings.ToLower(token.value))
+	if match == nil {
+		return searchCondition{}, queryError(token.pos, "prop: 的格式应为属性、比较运算符和数值，如 prop:ivl>30")
+	}
+
+	column, ok := propColumns[match[1]]
+	if !ok {
+		return searchCondition{}, queryError(token.pos, "prop: 的属性必须是 ivl、reps、lapses、ease、due 之一")
+	}
+	value, _ := strconv.ParseFloat(match[3], 64)
+	if match[1] != "ease" && strings.Contains(match[3], ".") {
+		return searchCondition{}, queryError(token.pos, "prop:%s 的值必须是整数", match[1])
+	}
+
+	operator := match[2]
+	if operator == "!=" {
+		operator = "<>"
+	}
+	return searchCondition{
+		sql:  "cards.id IN (SELECT reviews.card_id FROM reviews WHERE " + column + " " + operator + " ?)",
+		args: []interface{}{value},
+	}, nil
+}
+
+// textCondition 问题或答案包含文本的条件，启用全文搜索、不含通配符且不少于三个字符时使用cards_fts索引
+func textCondition(text string) searchCondition {
+	if !strings.Contains(text, "*") {
+		if phrase := ftsPhrase(text); phrase != "" {
+			return searchCondition{sql: "cards.id IN (SELECT rowid FROM cards_fts WHERE cards_fts MATCH ?)", args: []interface{}{phrase}}
+		}
+	}
+
+	pattern := "%" + globToLike(text) + "%"
+	return searchCondition{
+		sql:  `cards.question LIKE ? ESCAPE '\' OR cards.answer LIKE ? ESCAPE '\'`,
+		args: []interface{}{pattern, pattern},
+	}
+}
+
+// highlight 记录需要高亮的文本，通配符分隔的各部分分别高亮
+func (p *queryParser) highlight(text string) {
+	if p.negated {
+		return
+	}
+	for _, part := range strings.Split(text, "*") {
+		if strings.TrimSpace(part) != "" {
+			p.terms = append(p.terms, part)
+		}
+	}
+}
+
+// globToLike 将带 * 通配符的文本转换为LIKE模式，转义LIKE的特殊字符
+func globToLike(glob string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%")
+	return replacer.Replace(glob)
+}
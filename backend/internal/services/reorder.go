@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// cardPositionOrder 卡片在卡包中的顺序，位置相同时先创建的在前
+const cardPositionOrder = "cards.position ASC, cards.id ASC"
+
+// 调整卡片顺序相关错误
+var (
+	ErrReorderNoCards      = errors.New("请指定移动的卡片ID或范围")
+	ErrReorderInvalidRange = errors.New("移动的范围无效")
+	ErrReorderCardNotFound = errors.New("卡片不在该卡包中")
+)
+
+// ReorderCards 将卡包中的一张或多张卡片（或第start到第end张卡片）移动到指定位置，
+// 移动的卡片连续排列，卡包中所有卡片重新从1开始编号
+func (s *CardService) ReorderCards(deckID uint, req models.CardReorderRequest) (*models.CardReorderResponse, error) {
+	if len(req.CardIDs) == 0 && req.Start == 0 && req.End == 0 {
+		return nil, ErrReorderNoCards
+	}
+
+	var response *models.CardReorderResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.Deck{}, deckID).Error; err != nil {
+			return err
+		}
+
+		var cards []models.Card
+		if err := tx.Select("id", "position").Where("deck_id = ?", deckID).Order(cardPositionOrder).Find(&cards).Error; err != nil {
+			return err
+		}
+
+		moved, rest, err := splitReorderedCards(cards, req)
+		if err != nil {
+			return err
+		}
+
+		index := req.Position - 1
+		if index > len(rest) {
+			index = len(rest)
+		}
+		ordered := make([]models.Card, 0, len(cards))
+		ordered = append(ordered, rest[:index]...)
+		ordered = append(ordered, moved...)
+		ordered = append(ordered, rest[index:]...)
+
+		// 只更新位置有变化的卡片，调整顺序不算修改卡片内容
+		for i, card := range ordered {
+			if card.Position == i+1 {
+				continue
+			}
+			if err := tx.Model(&models.Card{}).Where("id = ?", card.ID).UpdateColumn("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+
+		response = &models.CardReorderResponse{
+			CardIDs:  make([]uint, len(moved)),
+			Position: index + 1,
+			Total:    len(ordered),
+		}
+		for i, card := range moved {
+			response.CardIDs[i] = card.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// splitReorderedCards 将卡包中按顺序排列的卡片分为移动的卡片和其余卡片
+func splitReorderedCards(cards []models.Card, req models.CardReorderRequest) (moved, rest []models.Card, err error) {
+	if len(req.CardIDs) == 0 {
+		if req.Start < 1 || req.End < req.Start || req.End > len(cards) {
+			return nil, nil, ErrReorderInvalidRange
+		}
+		moved = append(moved, cards[req.Start-1:req.End]...)
+		rest = append(rest, cards[:req.Start-1]...)
+		rest = append(rest, cards[req.End:]...)
+		return moved, rest, nil
+	}
+
+	byID := make(map[uint]models.Card, len(cards))
+	for _, card := range cards {
+		byID[card.ID] = card
+	}
+
+	selected := make(map[uint]bool, len(req.CardIDs))
+	for _, id := range uniqueIDs(req.CardIDs) {
+		card, ok := byID[id]
+		if !ok {
+			return nil, nil, ErrReorderCardNotFound
+		}
+		moved = append(moved, card)
+		selected[id] = true
+	}
+	for _, card := range cards {
+		if !selected[card.ID] {
+			rest = append(rest, card)
+		}
+	}
+	return moved, rest, nil
+}
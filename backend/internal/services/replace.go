@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// 查找替换相关错误
+var (
+	ErrReplaceNoScope        = errors.New("请指定卡包、标签或非空的筛选条件")
+	ErrInvalidReplaceField   = errors.New("替换字段必须是 question、answer 或 both")
+	ErrInvalidReplacePattern = errors.New("无效的正则表达式")
+)
+
+// ReplaceCards 在指定范围的卡片中查找并替换问题或答案的内容，所有修改在一个事务中完成，
+// 每张修改的卡片记录修订；dry_run时只返回替换结果预览。筛选条件不能为空，避免误替换全部卡片
+func (s *CardService) ReplaceCards(req models.CardReplaceRequest) (*models.CardReplaceResponse, error) {
+	if req.DeckID == nil && req.TagID == nil && req.Filter == nil {
+		return nil, ErrReplaceNoScope
+	}
+	if req.Filter != nil {
+		if emptyCardFilter(*req.Filter) {
+			return nil, ErrReplaceNoScope
+		}
+		if err := validateCardFilter(*req.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, err := replaceFields(req.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := replacePattern(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CardReplaceResponse{
+		DryRun:         req.DryRun,
+		SkippedCardIDs: []uint{},
+		Cards:          []models.CardReplaceResult{},
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var cards []models.Card
+		if err := replaceScope(tx.Model(&models.Card{}), req).
+			Select("cards.id", "cards.question", "cards.answer", "cards.note_id").
+			Order("cards.id ASC").
+			Find(&cards).Error; err != nil {
+			return err
+		}
+		response.Total = len(cards)
+
+		for _, card := range cards {
+			result := replaceCard(card, fields, pattern, req.Replace, req.Regex)
+			if len(result.Changes) == 0 {
+				continue
+			}
+			if card.NoteID != nil {
+				response.SkippedCardIDs = append(response.SkippedCardIDs, card.ID)
+				continue
+			}
+
+			response.Changed++
+			for _, change := range result.Changes {
+				response.Replacements += change.Count
+			}
+			response.Cards = append(response.Cards, result)
+
+			if req.DryRun {
+				continue
+			}
+			if err := saveReplacedCard(tx, card.ID, result.Changes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// replaceScope 按查找替换的范围筛选卡片：卡包（不包括子卡包）、标签（包括子标签）和筛选条件需要同时满足
+func replaceScope(query *gorm.DB, req models.CardReplaceRequest) *gorm.DB {
+	if req.DeckID != nil {
+		query = query.Where("cards.deck_id = ?", *req.DeckID)
+	}
+	if req.TagID != nil {
+		query = cardsWithTag(query, *req.TagID)
+	}
+	if req.Filter != nil {
+		query = applyCardFilter(query, *req.Filter)
+	}
+	return query
+}
+
+// replaceFields 解析需要替换的字段，默认问题和答案都替换
+func replaceFields(field string) ([]string, error) {
+	switch field {
+	case "", models.ReplaceFieldBoth:
+		return []string{models.ReplaceFieldQuestion, models.ReplaceFieldAnswer}, nil
+	case models.ReplaceFieldQuestion, models.ReplaceFieldAnswer:
+		return []string{field}, nil
+	default:
+		return nil, ErrInvalidReplaceField
+	}
+}
+
+// replacePattern 编译查找的内容，普通文本按字面匹配
+func replacePattern(req models.CardReplaceRequest) (*regexp.Regexp, error) {
+	expr := req.Find
+	if !req.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if req.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReplacePattern, err)
+	}
+	return pattern, nil
+}
+
+// replaceCard 计算卡片各字段替换后的内容，只返回有变化的字段
+func replaceCard(card models.Card, fields []string, pattern *regexp.Regexp, replace string, regex bool) models.CardReplaceResult {
+	result := models.CardReplaceResult{CardID: card.ID, Changes: []models.CardReplaceChange{}}
+	for _, field := range fields {
+		before := card.Question
+		if field == models.ReplaceFieldAnswer {
+			before = card.Answer
+		}
+
+		var after string
+		if regex {
+			after = pattern.ReplaceAllString(before, replace)
+		} else {
+			after = pattern.ReplaceAllLiteralString(before, replace)
+		}
+		if after == before {
+			continue
+		}
+
+		result.Changes = append(result.Changes, models.CardReplaceChange{
+			Field:  field,
+			Before: before,
+			After:  after,
+			Count:  len(pattern.FindAllStringIndex(before, -1)),
+		})
+	}
+	return result
+}
+
+// saveReplacedCard 保存替换后的卡片内容并记录修订
+func saveReplacedCard(tx *gorm.DB, cardID uint, changes []models.CardReplaceChange) error {
+	var card models.Card
+	if err := tx.Preload("Tags").First(&card, cardID).Error; err != nil {
+		return err
+	}
+
+	return saveCardWithRevision(tx, &card, tagIDsOf(card.Tags), func(card *models.Card) {
+		for _, change := range changes {
+			if change.Field == models.ReplaceFieldAnswer {
+				card.Answer = change.After
+			} else {
+				card.Question = change.After
+			}
+		}
+	})
+}
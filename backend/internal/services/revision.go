@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/config"
+	"flashcard/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound 修订记录不存在或不属于该卡片
+var ErrRevisionNotFound = errors.New("修订记录不存在")
+
+// defaultRevisionLimit 未加载配置时每张卡片保留的修订数量
+const defaultRevisionLimit = 50
+
+// revisionLimit 获取每张卡片保留的修订数量，0表示不限制
+func revisionLimit() int {
+	if config.AppConfig != nil {
+		return config.AppConfig.CardRevisionLimit
+	}
+	return defaultRevisionLimit
+}
+
+// GetCardRevisions 获取卡片的修订记录（最新的在前）
+func (s *CardService) GetCardRevisions(cardID uint) ([]models.CardRevision, error) {
+	if err := s.db.Select("id").First(&models.Card{}, cardID).Error; err != nil {
+		return nil, err
+	}
+
+	revisions := []models.CardRevision{}
+	if err := s.db.Where("card_id = ?", cardID).Order("id DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// DiffCardRevisions 比较卡片的两个修订
+func (s *CardService) DiffCardRevisions(cardID, fromID, toID uint) (*models.RevisionDiff, error) {
+	from, err := s.getRevision(cardID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getRevision(cardID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.RevisionDiff{
+		CardID: cardID,
+		FromID: fromID,
+		ToID:   toID,
+		Fields: []models.FieldDiff{},
+	}
+	if from.DeckID != to.DeckID {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldDeckID, From: from.DeckID, To: to.DeckID})
+	}
+	if !equalIDs(from.TagIDs, to.TagIDs) {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldTagIDs, From: from.TagIDs, To: to.TagIDs})
+	}
+	if from.Question != to.Question {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldQuestion, From: from.Question, To: to.Question, Lines: diffLines(from.Question, to.Question)})
+	}
+	if from.Answer != to.Answer {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldAnswer, From: from.Answer, To: to.Answer, Lines: diffLines(from.Answer, to.Answer)})
+	}
+	if from.Format != to.Format {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldFormat, From: from.Format, To: to.Format})
+	}
+	if from.Options != nil && to.Options != nil && !equalOptions(from.Options, to.Options) {
+		diff.Fields = append(diff.Fields, models.FieldDiff{Field: models.FieldOptions, From: from.Options, To: to.Options})
+	}
+
+	return diff, nil
+}
+
+// RevertCard 将卡片恢复到指定修订的内容，恢复操作本身也会记录为新的修订
+func (s *CardService) RevertCard(cardID, revisionID uint) (*models.Card, error) {
+	revision, err := s.getRevision(cardID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var card models.Card
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Tags").First(&card, cardID).Error; err != nil {
+			return err
+		}
+
+		if card.NoteID != nil {
+			return ErrCardManagedByNote
+		}
+
+		// 恢复时忽略之后已删除的标签
+		var tagIDs []uint
+		if err := tx.Model(&models.Tag{}).Where("id IN ?", revision.TagIDs).Pluck("id", &tagIDs).Error; err != nil {
+			return err
+		}
+
+		// 旧版本的修订没有记录选项，恢复时保留当前选项
+		var options []models.CardOption
+		if revision.Options != nil {
+			options = make([]models.CardOption, len(revision.Options))
+			for i, option := range revision.Options {
+				options[i] = models.CardOption{Position: i, Content: option.Content, Correct: option.Correct}
+			}
+		}
+
+		return saveCardWithOptions(tx, &card, tagIDs, options, func(card *models.Card) {
+			card.DeckID = revision.DeckID
+			card.Question = revision.Question
+			card.Answer = revision.Answer
+			card.Format = revision.Format
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &card, nil
+}
+
+// getRevision 获取属于指定卡片的修订
+func (s *CardService) getRevision(cardID, revisionID uint) (*models.CardRevision, error) {
+	var revision models.CardRevision
+	if err := s.db.Where("card_id = ?", cardID).First(&revision, revisionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// saveCardWithRevision 在事务中修改并保存卡片、替换标签，内容有变化时记录修订
+func saveCardWithRevision(tx *gorm.DB, card *models.Card, tagIDs []uint, apply func(card *models.Card)) error {
+	return saveCardWithOptions(tx, card, tagIDs, nil, apply)
+}
+
+// saveCardWithOptions 与 saveCardWithRevision 相同，options 不为nil时同时替换卡片的全部选项（空切片删除所有选项）
+func saveCardWithOptions(tx *gorm.DB, card *models.Card, tagIDs []uint, options []models.CardOption, apply func(card *models.Card)) error {
+	if err := tx.Where("card_id = ?", card.ID).Order("position ASC").Find(&card.Options).Error; err != nil {
+		return err
+	}
+
+	before := *card
+	apply(card)
+
+	// 移动到其他卡包的卡片排在目标卡包的最后
+	if card.DeckID != before.DeckID {
+		position, err := nextCardPosition(tx, card.DeckID)
+		if err != nil {
+			return err
+		}
+		card.Position = position
+	}
+
+	if err := tx.Omit("Tags", "Options").Save(card).Error; err != nil {
+		return err
+	}
+
+	if err := setCardTags(tx, card, tagIDs); err != nil {
+		return err
+	}
+
+	if options != nil {
+		if err := tx.Where("card_id = ?", card.ID).Delete(&models.CardOption{}).Error; err != nil {
+			return err
+		}
+		for i := range options {
+			options[i].ID = 0
+			options[i].CardID = card.ID
+		}
+		if len(options) > 0 {
+			if err := tx.Create(&options).Error; err != nil {
+				return err
+			}
+		}
+		card.Options = options
+	}
+
+	return recordRevision(tx, &before, card)
+}
+
+// recordRevision 记录卡片修订，第一次编辑时先保存编辑前的原始内容，并按保留数量清理旧修订
+func recordRevision(tx *gorm.DB, before, after *models.Card) error {
+	changed := changedFields(before, after)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.CardRevision{}).Where("card_id = ?", after.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		original := newRevision(before, nil)
+		original.CreatedAt = before.UpdatedAt
+		if err := tx.Create(&original).Error; err != nil {
+			return err
+		}
+	}
+
+	revision := newRevision(after, changed)
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	if limit := revisionLimit(); limit > 0 {
+		return tx.Exec(`DELETE FROM card_revisions WHERE card_id = ? AND id NOT IN (
+			SELECT id FROM card_revisions WHERE card_id = ? ORDER BY id DESC LIMIT ?)`, after.ID, after.ID, limit).Error
+	}
+
+	return nil
+}
+
+// newRevision 根据卡片内容创建修订快照
+func newRevision(card *models.Card, changed []string) models.CardRevision {
+	return models.CardRevision{
+		CardID:        card.ID,
+		DeckID:        card.DeckID,
+		TagIDs:        tagIDsOf(card.Tags),
+		Question:      card.Question,
+		Answer:        card.Answer,
+		Format:        normalizeFormat(card.Format),
+		Options:       revisionOptions(card.Options),
+		ChangedFields: changed,
+	}
+}
+
+// revisionOptions 将卡片选项转换为修订中保存的选项，没有选项时返回空切片
+func revisionOptions(options []models.CardOption) []models.OptionExport {
+	result := make([]models.OptionExport, len(options))
+	for i, option := range options {
+		result[i] = models.OptionExport{Content: option.Content, Correct: option.Correct}
+	}
+	return result
+}
+
+// changedFields 比较卡片修改前后变化的字段
+func changedFields(before, after *models.Card) []string {
+	var changed []string
+	if before.DeckID != after.DeckID {
+		changed = append(changed, models.FieldDeckID)
+	}
+	if !equalIDs(tagIDsOf(before.Tags), tagIDsOf(after.Tags)) {
+		changed = append(changed, models.FieldTagIDs)
+	}
+	if before.Question != after.Question {
+		changed = append(changed, models.FieldQuestion)
+	}
+	if before.Answer != after.Answer {
+		changed = append(changed, models.FieldAnswer)
+	}
+	if normalizeFormat(before.Format) != normalizeFormat(after.Format) {
+		changed = append(changed, models.FieldFormat)
+	}
+	if !equalOptions(revisionOptions(before.Options), revisionOptions(after.Options)) {
+		changed = append(changed, models.FieldOptions)
+	}
+	return changed
+}
+
+// equalOptions 比较两组选项的内容、正确性和顺序
+func equalOptions(a, b []models.OptionExport) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalIDs 比较两个升序的ID列表
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffLines 基于最长公共子序列计算两段文本的逐行差异
+func diffLines(from, to string) []models.DiffLine {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []models.DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, models.DiffLine{Op: "equal", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, models.DiffLine{Op: "delete", Text: a[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, models.DiffLine{Op: "delete", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, models.DiffLine{Op: "insert", Text: b[j]})
+	}
+
+	return lines
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrSavedSearchNameConflict 保存的搜索名称重复
+var ErrSavedSearchNameConflict = errors.New("已存在同名的保存搜索")
+
+// SavedSearchService 保存搜索服务
+type SavedSearchService struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchService 创建保存搜索服务实例
+func NewSavedSearchService() *SavedSearchService {
+	return &SavedSearchService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateSavedSearch 保存搜索，未指定顺序时排在最后
+func (s *SavedSearchService) CreateSavedSearch(name string, filter models.SavedFilter, sortOrder *int) (*models.SavedSearch, error) {
+	if err := validateSavedFilter(filter); err != nil {
+		return nil, err
+	}
+
+	search := &models.SavedSearch{
+		Name:   name,
+		Filter: filter,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSavedSearchName(tx, name, 0); err != nil {
+			return err
+		}
+
+		if sortOrder != nil {
+			search.SortOrder = *sortOrder
+		} else {
+			var last int
+			if err := tx.Model(&models.SavedSearch{}).Select("COALESCE(MAX(sort_order), -1)").Scan(&last).Error; err != nil {
+				return err
+			}
+			search.SortOrder = last + 1
+		}
+
+		return tx.Create(search).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return search, nil
+}
+
+// GetSavedSearches 获取所有保存的搜索，按顺序排列
+func (s *SavedSearchService) GetSavedSearches() ([]models.SavedSearch, error) {
+	searches := []models.SavedSearch{}
+	if err := s.db.Order("sort_order ASC, id ASC").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+// GetSavedSearchByID 根据ID获取保存的搜索
+func (s *SavedSearchService) GetSavedSearchByID(id uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := s.db.First(&search, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// UpdateSavedSearch 更新保存的搜索，参数为空时保持原值；指定筛选条件时整体替换
+func (s *SavedSearchService) UpdateSavedSearch(id uint, name string, filter *models.SavedFilter, sortOrder *int) (*models.SavedSearch, error) {
+	if filter != nil {
+		if err := validateSavedFilter(*filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var search models.SavedSearch
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&search, id).Error; err != nil {
+			return err
+		}
+
+		if name != "" {
+			if err := checkSavedSearchName(tx, name, id); err != nil {
+				return err
+			}
+			search.Name = name
+		}
+		if filter != nil {
+			search.Filter = *filter
+		}
+		if sortOrder != nil {
+			search.SortOrder = *sortOrder
+		}
+
+		return tx.Save(&search).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// DeleteSavedSearch 删除保存的搜索
+func (s *SavedSearchService) DeleteSavedSearch(id uint) error {
+	result := s.db.Delete(&models.SavedSearch{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SearchCards 执行保存的搜索，返回排序和分页后的卡片列表；未指定排序方式时使用保存的排序方式和方向
+func (s *SavedSearchService) SearchCards(id uint, page models.CardPageRequest) (*models.CardListResponse, error) {
+	search, err := s.GetSavedSearchByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Sort == "" {
+		page.Sort = search.Filter.Sort
+		if page.Order == "" {
+			page.Order = search.Filter.Order
+		}
+	}
+
+	cardService := &CardService{db: s.db}
+	return cardService.SearchCards(models.CardSearchRequest{
+		CardFilter:      search.Filter.CardFilter,
+		CardPageRequest: page,
+	})
+}
+
+// validateSavedFilter 检查保存的搜索语句、排序方式和排序方向
+func validateSavedFilter(filter models.SavedFilter) error {
+	if err := validateCardFilter(filter.CardFilter); err != nil {
+		return err
+	}
+	_, err := resolveCardSort(models.CardPageRequest{Sort: filter.Sort, Order: filter.Order}, true)
+	return err
+}
+
+// checkSavedSearchName 检查保存搜索的名称是否与其他保存的搜索重复
+func checkSavedSearchName(tx *gorm.DB, name string, excludeID uint) error {
+	var count int64
+	if err := tx.Model(&models.SavedSearch{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSavedSearchNameConflict
+	}
+	return nil
+}
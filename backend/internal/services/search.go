@@ -0,0 +1,177 @@
+package services
+
+import (
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// snippetRadius 搜索摘要中关键词前后保留的字符数
+const snippetRadius = 30
+
+// ftsMatchQuery 将搜索关键词转换为FTS5查询：按空白拆分的每个词作为一个短语按子串匹配，各词之间为AND关系；
+// 全文搜索不可用时所有词、可用时不足三个字符的词无法通过索引匹配，作为rest返回
+func ftsMatchQuery(keyword string) (match string, rest []string) {
+	var phrases []string
+	for _, term := range strings.Fields(keyword) {
+		if phrase := ftsPhrase(term); phrase != "" {
+			phrases = append(phrases, phrase)
+		} else {
+			rest = append(rest, term)
+		}
+	}
+	return strings.Join(phrases, " "), rest
+}
+
+// ftsPhrase 将文本转换为按子串匹配的FTS5短语，全文搜索不可用或文本不足三个字符时返回空字符串
+func ftsPhrase(text string) string {
+	if !database.FTSEnabled() || utf8.RuneCountInString(text) < database.FTSMinTermLength {
+		return ""
+	}
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// applyKeywordFilter 按关键词过滤卡片的问题和答案，启用全文搜索时通过cards_fts索引匹配，
+// 不能使用索引的词对问题和答案使用LIKE查询
+func applyKeywordFilter(query *gorm.DB, keyword string) *gorm.DB {
+	match, rest := ftsMatchQuery(keyword)
+	if match != "" {
+		query = query.Where("cards.id IN (SELECT rowid FROM cards_fts WHERE cards_fts MATCH ?)", match)
+	}
+	return applyLikeTerms(query, rest)
+}
+
+// applyLikeTerms 要求问题或答案包含每个词
+func applyLikeTerms(query *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		query = query.Where("question LIKE ? OR answer LIKE ?", "%"+term+"%", "%"+term+"%")
+	}
+	return query
+}
+
+// rankByKeyword 按关键词全文搜索卡片，并连接相关度fts.rank用于排序，问题中的匹配权重高于答案，
+// 不能使用索引的词使用LIKE过滤；没有可以通过索引匹配的词时返回原查询和false
+func rankByKeyword(query *gorm.DB, keyword string) (*gorm.DB, bool) {
+	match, rest := ftsMatchQuery(keyword)
+	if match == "" {
+		return query, false
+	}
+	query = query.Joins("JOIN (SELECT rowid, bm25(cards_fts, 2.0, 1.0) AS rank FROM cards_fts WHERE cards_fts MATCH ?) AS fts ON fts.rowid = cards.id", match)
+	return applyLikeTerms(query, rest), true
+}
+
+// cardSnippet 生成卡片的搜索摘要：截取问题（问题中没有匹配时为答案）中第一个匹配附近的文本，
+// 转义HTML后用<mark>标记所有匹配的关键词；没有匹配时返回空字符串
+func cardSnippet(card models.Card, terms []string) string {
+	if len(terms) == 0 {
+		return ""
+	}
+
+	for _, text := range []string{card.Question, card.Answer} {
+		if snippet := highlightSnippet(text, terms); snippet != "" {
+			return snippet
+		}
+	}
+	return ""
+}
+
+// highlightSnippet 在文本中查找关键词（忽略大小写和全角半角），返回带高亮的摘要
+func highlightSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	folded := foldRunes(runes)
+
+	// 标记每个字符是否属于匹配的关键词
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		pattern := foldRunes([]rune(term))
+		for i := 0; i+len(pattern) <= len(folded); i++ {
+			if !equalRunes(folded[i:i+len(pattern)], pattern) {
+				continue
+			}
+			for j := i; j < i+len(pattern); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := first - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := first + snippetRadius*2
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i + 1
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		// 换行等连续空白统一为一个空格
+		segment := html.EscapeString(collapseSpaces(string(runes[i:j])))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// collapseSpaces 将连续的空白字符合并为一个空格
+func collapseSpaces(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteRune(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// foldRunes 将字符转换为半角小写形式用于匹配，长度与原字符序列相同
+func foldRunes(runes []rune) []rune {
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = unicode.ToLower(database.FoldWidth(r))
+	}
+	return folded
+}
+
+// equalRunes 判断两个字符序列是否相同
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
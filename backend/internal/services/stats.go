@@ -0,0 +1,62 @@
+package services
+
+import (
+	"gorm.io/gorm"
+)
+
+// cardCounts 分组后的卡片数量统计
+type cardCounts struct {
+	GroupID uint
+	Total   int
+	Due     int
+}
+
+// countCardsBy 按指定列分组，一次查询统计卡片总数和待复习卡片数
+func countCardsBy(db *gorm.DB, column string, scope cardScope) (map[uint]cardCounts, error) {
+	var rows []cardCounts
+
+	query := db.
+		Table("cards").
+		Select(column + " AS group_id, " +
+			"COUNT(cards.id) AS total, " +
+			"SUM(CASE WHEN reviews.next_review <= date('now') OR reviews.id IS NULL THEN 1 ELSE 0 END) AS due").
+		Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
+		Where("cards.deleted_at IS NULL").
+		Where(column + " IS NOT NULL").
+		Group(column)
+
+	if err := scope(query).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]cardCounts, len(rows))
+	for _, row := range rows {
+		counts[row.GroupID] = row
+	}
+
+	return counts, nil
+}
+
+// countTagsByDeck 按卡包分组统计标签数量
+func countTagsByDeck(db *gorm.DB) (map[uint]int, error) {
+	var rows []struct {
+		DeckID uint
+		Count  int
+	}
+
+	if err := db.
+		Table("tags").
+		Select("deck_id, COUNT(*) AS count").
+		Where("deleted_at IS NULL AND deck_id IS NOT NULL").
+		Group("deck_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.DeckID] = row.Count
+	}
+
+	return counts, nil
+}
@@ -1,10 +1,14 @@
 package services
 
 import (
+	"flashcard/internal/metrics"
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
 	"fmt"
 	"math"
+	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,30 +26,166 @@ func NewStudyService() *StudyService {
 	}
 }
 
-// StartDeckStudy 开始学习卡包
+// StartDeckStudy 开始学习卡包，同时抽取所有子卡包的卡片；
+// 新卡片按顺序学习的卡包抽取排在最前面的新卡片，并按卡片顺序出现在学习队列中
 func (s *StudyService) StartDeckStudy(deckID uint, limit int) (*models.StudySession, error) {
+	var decks []models.Deck
+	if err := s.db.Find(&decks).Error; err != nil {
+		return nil, err
+	}
+
+	var root *models.Deck
+	for i := range decks {
+		if decks[i].ID == deckID {
+			root = &decks[i]
+		}
+	}
+	if root == nil {
+		return s.createStudySession(nil), nil
+	}
+
+	cardIDs, err := s.deckStudyCardIDs(*root, limit, deckChildren(decks))
+	if err != nil {
+		return nil, err
+	}
+
+	sequential := make(map[uint]bool)
+	for _, deck := range decks {
+		if deck.NewCardOrder == models.NewCardOrderSequential {
+			sequential[deck.ID] = true
+		}
+	}
+	if len(sequential) > 0 {
+		if cardIDs, err = s.firstNewCardIDs(cardIDs, sequential); err != nil {
+			return nil, err
+		}
+	}
+
 	var cards []models.Card
-	err := s.db.Where("deck_id = ?", deckID).
+	err = s.db.Where("id IN ?", cardIDs).
 		Preload("Deck").
-		Preload("Tag").
+		Preload("Tags").
 		Preload("Review").
+		Preload("Options").
+		Preload("Media").
 		Order("RANDOM()").
-		Limit(limit).
 		Find(&cards).Error
 	if err != nil {
 		return nil, err
 	}
 
+	orderNewCards(cards, sequential)
 	return s.createStudySession(cards), nil
 }
 
+// firstNewCardIDs 将随机抽取的卡片中按顺序学习的卡包的新卡片，替换为该卡包中排在最前面的相同数量的新卡片
+func (s *StudyService) firstNewCardIDs(cardIDs []uint, sequential map[uint]bool) ([]uint, error) {
+	var picked []models.Card
+	err := s.db.Select("id", "deck_id").
+		Where("id IN ? AND NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.card_id = cards.id)", cardIDs).
+		Find(&picked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int)
+	replaced := make(map[uint]bool)
+	for _, card := range picked {
+		if sequential[card.DeckID] {
+			counts[card.DeckID]++
+			replaced[card.ID] = true
+		}
+	}
+
+	ids := make([]uint, 0, len(cardIDs))
+	for _, id := range cardIDs {
+		if !replaced[id] {
+			ids = append(ids, id)
+		}
+	}
+	for deckID, count := range counts {
+		var first []uint
+		err := s.db.Model(&models.Card{}).
+			Where("deck_id = ? AND suspended = ?", deckID, false).
+			Where("NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.card_id = cards.id)").
+			Order(cardPositionOrder).
+			Limit(count).
+			Pluck("id", &first).Error
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, first...)
+	}
+	return ids, nil
+}
+
+// orderNewCards 将按顺序学习的卡包的新卡片按卡片顺序排列，其他卡片在学习队列中的位置不变
+func orderNewCards(cards []models.Card, sequential map[uint]bool) {
+	slots := make(map[uint][]int)
+	for i, card := range cards {
+		if card.Review == nil && sequential[card.DeckID] {
+			slots[card.DeckID] = append(slots[card.DeckID], i)
+		}
+	}
+
+	for _, indexes := range slots {
+		ordered := make([]models.Card, len(indexes))
+		for j, i := range indexes {
+			ordered[j] = cards[i]
+		}
+		sort.Slice(ordered, func(a, b int) bool {
+			if ordered[a].Position != ordered[b].Position {
+				return ordered[a].Position < ordered[b].Position
+			}
+			return ordered[a].ID < ordered[b].ID
+		})
+		for j, i := range indexes {
+			cards[i] = ordered[j]
+		}
+	}
+}
+
+// deckStudyCardIDs 随机抽取卡包及其子卡包的卡片ID，
+// 每一级卡包抽取的数量都不超过该卡包自身的学习上限
+func (s *StudyService) deckStudyCardIDs(deck models.Deck, limit int, children map[uint][]models.Deck) ([]uint, error) {
+	if deck.StudyLimit > 0 && deck.StudyLimit < limit {
+		limit = deck.StudyLimit
+	}
+
+	var ids []uint
+	err := s.db.Model(&models.Card{}).
+		Where("deck_id = ? AND suspended = ?", deck.ID, false).
+		Order("RANDOM()").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children[deck.ID] {
+		childIDs, err := s.deckStudyCardIDs(child, limit, children)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, childIDs...)
+	}
+
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
 // StartTagStudy 开始学习标签
 func (s *StudyService) StartTagStudy(tagID uint, limit int) (*models.StudySession, error) {
 	var cards []models.Card
-	err := s.db.Where("tag_id = ?", tagID).
+	err := cardsWithTag(s.db, tagID).Where("suspended = ?", false).
 		Preload("Deck").
-		Preload("Tag").
+		Preload("Tags").
 		Preload("Review").
+		Preload("Options").
+		Preload("Media").
 		Order("RANDOM()").
 		Limit(limit).
 		Find(&cards).Error
@@ -59,9 +199,12 @@ func (s *StudyService) StartTagStudy(tagID uint, limit int) (*models.StudySessio
 // StartRandomStudy 开始随机学习
 func (s *StudyService) StartRandomStudy(limit int) (*models.StudySession, error) {
 	var cards []models.Card
-	err := s.db.Preload("Deck").
-		Preload("Tag").
+	err := s.db.Where("suspended = ?", false).
+		Preload("Deck").
+		Preload("Tags").
 		Preload("Review").
+		Preload("Options").
+		Preload("Media").
 		Order("RANDOM()").
 		Limit(limit).
 		Find(&cards).Error
@@ -72,6 +215,42 @@ func (s *StudyService) StartRandomStudy(limit int) (*models.StudySession, error)
 	return s.createStudySession(cards), nil
 }
 
+// StartFilteredStudy 按筛选条件（卡包、标签、关键词、标记等）开始学习，可以只包含到期的卡片
+func (s *StudyService) StartFilteredStudy(req models.FilteredStudyRequest, limit int) (*models.StudySession, error) {
+	if err := validateCardFilter(req.CardFilter); err != nil {
+		return nil, err
+	}
+
+	query := applyCardFilter(s.db.Model(&models.Card{}), req.CardFilter).Where("cards.suspended = ?", false)
+	if req.DueOnly {
+		query = query.Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
+			Where("reviews.next_review <= ? OR reviews.id IS NULL", time.Now())
+	}
+
+	var cards []models.Card
+	err := query.Preload("Deck").
+		Preload("Tags").
+		Preload("Review").
+		Preload("Options").
+		Preload("Media").
+		Order("RANDOM()").
+		Limit(limit).
+		Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createStudySession(cards), nil
+}
+
+// SetCardFlag 学习时设置卡片的标记颜色
+func (s *StudyService) SetCardFlag(cardID uint, flag int) error {
+	if !validFlag(flag) {
+		return ErrInvalidFlag
+	}
+	return setCardFlag(s.db, cardID, flag)
+}
+
 // GetDueCards 获取到期复习的卡片
 func (s *StudyService) GetDueCards(limit int) (*models.StudySession, error) {
 	var cards []models.Card
@@ -80,9 +259,12 @@ func (s *StudyService) GetDueCards(limit int) (*models.StudySession, error) {
 	// 获取到期的卡片（包括新卡片）
 	err := s.db.Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
 		Where("reviews.next_review <= ? OR reviews.id IS NULL", now).
+		Where("cards.suspended = ?", false).
 		Preload("Deck").
-		Preload("Tag").
+		Preload("Tags").
 		Preload("Review").
+		Preload("Options").
+		Preload("Media").
 		Order("reviews.next_review ASC").
 		Limit(limit).
 		Find(&cards).Error
@@ -100,14 +282,42 @@ func (s *StudyService) createStudySession(cards []models.Card) *models.StudySess
 	for i, card := range cards {
 		queue[i] = models.StudyQueue{
 			CardID:   card.ID,
+			Type:     card.Type,
 			Question: card.Question,
 			Answer:   card.Answer,
+			Format:   normalizeFormat(card.Format),
+			Media:    card.Media,
 			DeckName: card.Deck.Name,
+			Flag:     card.Flag,
+		}
+
+		// 填空卡片正面隐藏当前编号的填空，背面显示完整内容
+		if card.Type == models.CardTypeCloze {
+			queue[i].Question = renderClozeFront(card.Question, card.Ord)
+			queue[i].Answer = renderClozeBack(card.Question, card.Answer)
 		}
 
-		if card.Tag != nil {
-			queue[i].TagName = card.Tag.Name
+		// 图片遮挡卡片返回需要遮挡的区域和其他区域，区域标签作为答案
+		if card.Type == models.CardTypeOcclusion {
+			queue[i].HideMask, queue[i].ShowMasks = splitMasks(card.Masks, card.Ord)
+			if queue[i].HideMask != nil && queue[i].HideMask.Label != "" {
+				queue[i].Answer = strings.TrimSpace(queue[i].HideMask.Label + "\n\n" + card.Answer)
+			}
+		}
+
+		queue[i].QuestionHTML = renderContent(queue[i].Question, queue[i].Format)
+		queue[i].AnswerHTML = renderContent(queue[i].Answer, queue[i].Format)
+
+		// 选择题选项打乱顺序，不返回正确答案
+		if card.Type == models.CardTypeChoice {
+			queue[i].Options, queue[i].Multiple = shuffleOptions(card.Options)
 		}
+
+		for _, tag := range card.Tags {
+			queue[i].TagIDs = append(queue[i].TagIDs, tag.ID)
+		}
+		queue[i].TagNames = tagNamesOf(card.Tags)
+		queue[i].TagID, queue[i].TagName = firstTag(card.Tags)
 	}
 
 	return &models.StudySession{
@@ -121,6 +331,20 @@ func (s *StudyService) createStudySession(cards []models.Card) *models.StudySess
 
 // SubmitReview 提交复习结果
 func (s *StudyService) SubmitReview(cardID uint, result models.ReviewResult) (*models.ReviewResponse, error) {
+	// 选择题只能提交选项，不能自评复习结果
+	var choiceCount int64
+	if err := s.db.Model(&models.Card{}).Where("id = ? AND type = ?", cardID, models.CardTypeChoice).Count(&choiceCount).Error; err != nil {
+		return nil, err
+	}
+	if choiceCount > 0 {
+		return nil, ErrChoicesRequired
+	}
+
+	return s.applyReview(cardID, result)
+}
+
+// applyReview 按复习结果更新卡片的复习记录
+func (s *StudyService) applyReview(cardID uint, result models.ReviewResult) (*models.ReviewResponse, error) {
 	// 获取或创建复习记录
 	var review models.Review
 	err := s.db.Where("card_id = ?", cardID).First(&review).Error
@@ -140,21 +364,45 @@ func (s *StudyService) SubmitReview(cardID uint, result models.ReviewResult) (*m
 	}
 
 	// 使用SM-2算法更新复习参数
+	lastInterval := review.Interval
 	s.updateReviewBySM2(&review, result)
 
-	// 保存或更新复习记录
-	if review.ID == 0 {
-		err = s.db.Create(&review).Error
-	} else {
-		err = s.db.Save(&review).Error
+	// 复习阶段的卡片被遗忘，记为一次遗忘
+	if result == models.Again && lastInterval >= 1 {
+		review.Lapses++
 	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 保存或更新复习记录
+		if review.ID == 0 {
+			if err := tx.Create(&review).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Save(&review).Error; err != nil {
+				return err
+			}
+		}
+
+		// 记录复习日志
+		return tx.Create(&models.ReviewLog{
+			CardID:       cardID,
+			Result:       result,
+			LastInterval: lastInterval,
+			Interval:     review.Interval,
+			EFactor:      review.EFactor,
+			ReviewedAt:   time.Now(),
+		}).Error
+	})
 	if err != nil {
 		return nil, err
 	}
+	metrics.ReviewsSubmitted.WithLabelValues(result.String()).Inc()
 
 	// 构建响应
 	response := &models.ReviewResponse{
 		Success:    true,
+		Result:     result,
 		NextReview: review.NextReview,
 		Interval:   review.Interval,
 		Message:    s.getReviewMessage(result, review.Interval),
@@ -163,6 +411,34 @@ func (s *StudyService) SubmitReview(cardID uint, result models.ReviewResult) (*m
 	return response, nil
 }
 
+// SubmitChoiceReview 提交选择题答案，由服务端评分后按对应的复习结果更新
+func (s *StudyService) SubmitChoiceReview(cardID uint, choices []uint) (*models.ReviewResponse, error) {
+	var card models.Card
+	if err := s.db.Preload("Options").First(&card, cardID).Error; err != nil {
+		return nil, err
+	}
+
+	if card.Type != models.CardTypeChoice {
+		return nil, ErrNotChoiceCard
+	}
+
+	result, correctIDs, err := gradeChoices(card.Options, choices)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.applyReview(cardID, result)
+	if err != nil {
+		return nil, err
+	}
+
+	correct := result == models.Good
+	response.Correct = &correct
+	response.CorrectOptions = correctIDs
+
+	return response, nil
+}
+
 // updateReviewBySM2 使用SM-2算法更新复习参数
 func (s *StudyService) updateReviewBySM2(review *models.Review, result models.ReviewResult) {
 	switch result {
@@ -1,12 +1,24 @@
 package services
 
 import (
+	"errors"
 	"flashcard/internal/models"
 	"flashcard/pkg/database"
+	"sort"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// 标签层级相关错误
+var (
+	ErrInvalidTagName   = errors.New("标签名称不能为空且不能包含::")
+	ErrInvalidTagParent = errors.New("上级标签不存在或不属于同一卡包")
+	ErrTagCycle         = errors.New("不能将标签移动到自身或其子标签下")
+	ErrTagPathConflict  = errors.New("同一位置已存在同名标签")
+)
+
 // TagService 标签服务
 type TagService struct {
 	db *gorm.DB
@@ -24,14 +36,17 @@ func (s *TagService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// CreateTag 创建标签
-func (s *TagService) CreateTag(deckID *uint, name string) (*models.Tag, error) {
+// CreateTag 创建标签，parentID为空时创建顶层标签
+func (s *TagService) CreateTag(deckID *uint, parentID *uint, name string) (*models.Tag, error) {
 	tag := &models.Tag{
-		DeckID: deckID,
-		Name:   name,
+		DeckID:   deckID,
+		ParentID: parentID,
+		Name:     name,
 	}
 
-	if err := s.db.Create(tag).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return saveTag(tx, tag)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -60,31 +75,85 @@ func (s *TagService) GetTagsByDeckID(deckID uint) ([]models.Tag, error) {
 
 // UpdateTag 更新标签
 func (s *TagService) UpdateTag(id uint, name string) (*models.Tag, error) {
-	var tag models.Tag
-	if err := s.db.First(&tag, id).Error; err != nil {
-		return nil, err
-	}
-
-	tag.Name = name
+	return s.updateTag(id, func(tx *gorm.DB, tag *models.Tag) error {
+		tag.Name = name
+		return nil
+	})
+}
 
-	if err := s.db.Save(&tag).Error; err != nil {
-		return nil, err
-	}
+// UpdateTagWithDeck 更新标签：提供名称时同时更新卡包ID，更换卡包时子标签一起移动，上级标签不在新卡包时改为顶层标签；
+// 描述、颜色、图标和元数据只更新提供的字段
+func (s *TagService) UpdateTagWithDeck(id uint, req models.UpdateTagRequest) (*models.Tag, error) {
+	return s.updateTag(id, func(tx *gorm.DB, tag *models.Tag) error {
+		if req.Name != nil {
+			if !equalDeckID(tag.DeckID, req.DeckID) {
+				tag.ParentID = nil
+			}
+			tag.Name = *req.Name
+			tag.DeckID = req.DeckID
+		}
+		return applyDetails(&tag.Details, req.DetailsUpdate)
+	})
+}
 
-	return &tag, nil
+// MoveTag 将标签及其子标签移动到新的上级标签下，parentID为空时移动到顶层
+func (s *TagService) MoveTag(id uint, parentID *uint) (*models.Tag, error) {
+	return s.updateTag(id, func(tx *gorm.DB, tag *models.Tag) error {
+		if parentID != nil {
+			ids, err := tagSubtreeIDs(tx, tag.ID)
+			if err != nil {
+				return err
+			}
+			for _, subtreeID := range ids {
+				if subtreeID == *parentID {
+					return ErrTagCycle
+				}
+			}
+		}
+		tag.ParentID = parentID
+		return nil
+	})
 }
 
-// UpdateTagWithDeck 更新标签（包含卡包ID）
-func (s *TagService) UpdateTagWithDeck(id uint, name string, deckID *uint) (*models.Tag, error) {
-	var tag models.Tag
-	if err := s.db.First(&tag, id).Error; err != nil {
-		return nil, err
+// RenameTagPath 按完整路径重命名标签，路径中不存在的上级标签会自动创建，子标签随之移动
+func (s *TagService) RenameTagPath(id uint, path string) (*models.Tag, error) {
+	names, ok := splitTagPath(path)
+	if !ok {
+		return nil, ErrInvalidTagName
 	}
 
-	tag.Name = name
-	tag.DeckID = deckID
+	return s.updateTag(id, func(tx *gorm.DB, tag *models.Tag) error {
+		newPath := strings.Join(names, models.TagPathSeparator)
+		if strings.HasPrefix(newPath, tag.Path+models.TagPathSeparator) {
+			return ErrTagCycle
+		}
 
-	if err := s.db.Save(&tag).Error; err != nil {
+		tag.ParentID = nil
+		if len(names) > 1 {
+			parent, err := ensureTagPath(tx, tag.DeckID, names[:len(names)-1], nil)
+			if err != nil {
+				return err
+			}
+			tag.ParentID = &parent.ID
+		}
+		tag.Name = names[len(names)-1]
+		return nil
+	})
+}
+
+// updateTag 在事务中修改标签并更新它和子标签的路径
+func (s *TagService) updateTag(id uint, apply func(tx *gorm.DB, tag *models.Tag) error) (*models.Tag, error) {
+	var tag models.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, id).Error; err != nil {
+			return err
+		}
+		if err := apply(tx, &tag); err != nil {
+			return err
+		}
+		return saveTag(tx, &tag)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -101,29 +170,25 @@ func (s *TagService) DeleteTag(id uint, deleteCards bool) error {
 		}
 	}()
 
-	// 如果需要同时删除卡片
-	if deleteCards {
-		// 先删除关联的复习记录
-		if err := tx.Exec("DELETE FROM reviews WHERE card_id IN (SELECT id FROM cards WHERE tag_id = ?)", id).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
+	now := time.Now()
 
-		// 删除卡片
-		if err := tx.Where("tag_id = ?", id).Delete(&models.Card{}).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
-	} else {
-		// 仅将卡片的标签置为空
-		if err := tx.Model(&models.Card{}).Where("tag_id = ?", id).Update("tag_id", nil).Error; err != nil {
+	// 子标签随标签一起删除
+	ids, err := tagSubtreeIDs(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 如果需要同时删除卡片，卡片和复习记录随标签一起移入回收站
+	if deleteCards {
+		if err := softDeleteWith(tx, now, &models.Card{}, "id IN (SELECT card_id FROM card_tags WHERE tag_id IN ?)", ids); err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	// 删除标签
-	if err := tx.Delete(&models.Tag{}, id).Error; err != nil {
+	// 删除标签，卡片与标签的关联保留，恢复标签后卡片重新带上该标签
+	if err := softDeleteWith(tx, now, &models.Tag{}, "id IN ?", ids); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -137,17 +202,16 @@ func (s *TagService) GetTagStats(tagID uint) (*models.TagStats, error) {
 	var count int64
 
 	// 获取总卡片数
-	if err := s.db.Model(&models.Card{}).Where("tag_id = ?", tagID).Count(&count).Error; err != nil {
+	if err := cardsWithTag(s.db.Model(&models.Card{}), tagID).Count(&count).Error; err != nil {
 		return nil, err
 	}
 	stats.TotalCards = int(count)
 
 	// 获取待复习卡片数
-	query := s.db.
-		Table("cards").
+	query := cardsWithTag(s.db.Table("cards"), tagID).
 		Select("COUNT(cards.id)").
 		Joins("LEFT JOIN reviews ON cards.id = reviews.card_id").
-		Where("cards.tag_id = ?", tagID).
+		Where("cards.deleted_at IS NULL").
 		Where("(reviews.next_review <= date('now') OR reviews.id IS NULL)")
 
 	if err := query.Scan(&count).Error; err != nil {
@@ -168,20 +232,15 @@ func (s *TagService) GetTagsByDeckIDWithStats(deckID uint) ([]models.TagWithStat
 		return nil, err
 	}
 
-	var result []models.TagWithStats
-	for _, tag := range tags {
-		stats, err := s.GetTagStats(tag.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		result = append(result, models.TagWithStats{
-			Tag:   tag,
-			Stats: *stats,
-		})
+	counts, err := countCardsBy(s.db, "tag_cards.tag_id", func(query *gorm.DB) *gorm.DB {
+		return query.Joins("JOIN "+tagCardsTable+" ON tag_cards.card_id = cards.id").
+			Where("tag_cards.tag_id IN (?)", s.db.Model(&models.Tag{}).Select("id").Where("deck_id = ?", deckID))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return withTagStats(tags, counts), nil
 }
 
 // GetAllTags 获取所有标签
@@ -200,18 +259,261 @@ func (s *TagService) GetAllTagsWithStats() ([]models.TagWithStats, error) {
 		return nil, err
 	}
 
+	counts, err := countCardsBy(s.db, "tag_cards.tag_id", func(query *gorm.DB) *gorm.DB {
+		return query.Joins("JOIN " + tagCardsTable + " ON tag_cards.card_id = cards.id")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return withTagStats(tags, counts), nil
+}
+
+// withTagStats 将分组统计结果组装到标签列表中
+func withTagStats(tags []models.Tag, counts map[uint]cardCounts) []models.TagWithStats {
 	var result []models.TagWithStats
 	for _, tag := range tags {
-		stats, err := s.GetTagStats(tag.ID)
+		tagCounts := counts[tag.ID]
+		result = append(result, models.TagWithStats{
+			Tag: tag,
+			Stats: models.TagStats{
+				TotalCards: tagCounts.Total,
+				DueCards:   tagCounts.Due,
+			},
+		})
+	}
+
+	return result
+}
+
+// ErrInvalidTags 指定的标签不存在
+var ErrInvalidTags = errors.New("标签不存在")
+
+// findTags 根据ID查找标签，有不存在的标签时返回 ErrInvalidTags
+func findTags(tx *gorm.DB, tagIDs []uint) ([]models.Tag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	tags := []models.Tag{}
+	if len(tagIDs) == 0 {
+		return tags, nil
+	}
+
+	if err := tx.Where("id IN ?", tagIDs).Order("id ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(tagIDs) {
+		return nil, ErrInvalidTags
+	}
+
+	return tags, nil
+}
+
+// setCardTags 替换卡片的全部标签
+func setCardTags(tx *gorm.DB, card *models.Card, tagIDs []uint) error {
+	tags, err := findTags(tx, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(card).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	card.Tags = tags
+
+	return nil
+}
+
+// tagIDsOf 获取标签ID列表（升序）
+func tagIDsOf(tags []models.Tag) []uint {
+	ids := make([]uint, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// tagIDsOrCurrent 获取要设置的标签ID，未指定时保留当前的标签
+func tagIDsOrCurrent(tagIDs *[]uint, current []models.Tag) []uint {
+	if tagIDs == nil {
+		return tagIDsOf(current)
+	}
+	return *tagIDs
+}
+
+// tagNamesOf 获取标签的完整路径列表
+func tagNamesOf(tags []models.Tag) []string {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tagPathOf(tag))
+	}
+	return names
+}
+
+// firstTag 获取第一个标签的ID和完整路径，用于兼容只支持单个标签的旧版本客户端
+func firstTag(tags []models.Tag) (*uint, string) {
+	if len(tags) == 0 {
+		return nil, ""
+	}
+	id := tags[0].ID
+	return &id, tagPathOf(tags[0])
+}
+
+// tagPathOf 获取标签的完整路径，旧数据没有路径时使用标签名
+func tagPathOf(tag models.Tag) string {
+	if tag.Path == "" {
+		return tag.Name
+	}
+	return tag.Path
+}
+
+// sortTagsByPath 按完整路径排列标签，上级标签的路径是子标签路径的前缀，因此排在子标签之前
+func sortTagsByPath(tags []models.Tag) {
+	sort.Slice(tags, func(i, j int) bool { return tagPathOf(tags[i]) < tagPathOf(tags[j]) })
+}
+
+// activeTagSubtree 查询标签及其所有未删除子孙标签的ID，参数为标签ID
+const activeTagSubtree = "WITH RECURSIVE subtree(id) AS (" +
+	"SELECT id FROM tags WHERE id = ? AND deleted_at IS NULL " +
+	"UNION SELECT tags.id FROM tags JOIN subtree ON tags.parent_id = subtree.id WHERE tags.deleted_at IS NULL) " +
+	"SELECT id FROM subtree"
+
+// tagCardsTable 每个标签（包括其子孙标签）下的卡片，同一标签和卡片只出现一次
+const tagCardsTable = "(WITH RECURSIVE closure(ancestor, descendant) AS (" +
+	"SELECT id, id FROM tags WHERE deleted_at IS NULL " +
+	"UNION SELECT closure.ancestor, tags.id FROM tags JOIN closure ON tags.parent_id = closure.descendant " +
+	"WHERE tags.deleted_at IS NULL) " +
+	"SELECT DISTINCT closure.ancestor AS tag_id, card_tags.card_id FROM closure " +
+	"JOIN card_tags ON card_tags.tag_id = closure.descendant) AS tag_cards"
+
+// cardsWithTag 限定拥有指定标签或其子孙标签的卡片，已删除的标签不匹配任何卡片
+func cardsWithTag(query *gorm.DB, tagID uint) *gorm.DB {
+	return query.Where("cards.id IN (SELECT card_tags.card_id FROM card_tags "+
+		"WHERE card_tags.tag_id IN ("+activeTagSubtree+"))", tagID)
+}
+
+// tagSubtreeIDs 获取标签及其所有未删除子孙标签的ID
+func tagSubtreeIDs(tx *gorm.DB, tagID uint) ([]uint, error) {
+	var ids []uint
+	if err := tx.Raw(activeTagSubtree, tagID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// splitTagPath 拆分标签路径，有空的标签名时返回false
+func splitTagPath(path string) ([]string, bool) {
+	names := strings.Split(path, models.TagPathSeparator)
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if names[i] == "" {
+			return nil, false
+		}
+	}
+	return names, true
+}
+
+// saveTag 校验并保存标签，根据上级标签计算路径并更新所有子孙标签的路径和卡包
+func saveTag(tx *gorm.DB, tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" || strings.Contains(tag.Name, models.TagPathSeparator) {
+		return ErrInvalidTagName
+	}
+
+	tag.Path = tag.Name
+	if tag.ParentID != nil {
+		var parent models.Tag
+		err := tx.First(&parent, *tag.ParentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !equalDeckID(parent.DeckID, tag.DeckID)) {
+			return ErrInvalidTagParent
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
+		tag.Path = tagPathOf(parent) + models.TagPathSeparator + tag.Name
+	}
 
-		result = append(result, models.TagWithStats{
-			Tag:   tag,
-			Stats: *stats,
-		})
+	var count int64
+	err := whereDeckID(tx.Model(&models.Tag{}), tag.DeckID).
+		Where("path = ? AND id <> ?", tag.Path, tag.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTagPathConflict
 	}
 
-	return result, nil
+	if err := tx.Save(tag).Error; err != nil {
+		return err
+	}
+
+	return refreshTagPaths(tx, tag)
+}
+
+// refreshTagPaths 根据标签的路径和卡包递归更新子孙标签
+func refreshTagPaths(tx *gorm.DB, tag *models.Tag) error {
+	var children []models.Tag
+	if err := tx.Where("parent_id = ?", tag.ID).Find(&children).Error; err != nil {
+		return err
+	}
+
+	for i := range children {
+		child := &children[i]
+		child.DeckID = tag.DeckID
+		child.Path = tagPathOf(*tag) + models.TagPathSeparator + child.Name
+		err := tx.Model(child).Updates(map[string]interface{}{"deck_id": child.DeckID, "path": child.Path}).Error
+		if err != nil {
+			return err
+		}
+		if err := refreshTagPaths(tx, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureTagPath 按路径逐级查找标签，不存在的标签自动创建，返回最后一级标签；cache不为空时缓存路径对应的标签
+func ensureTagPath(tx *gorm.DB, deckID *uint, names []string, cache map[string]models.Tag) (*models.Tag, error) {
+	var parent *models.Tag
+	for i := range names {
+		path := strings.Join(names[:i+1], models.TagPathSeparator)
+
+		tag, cached := cache[path]
+		if !cached {
+			err := whereDeckID(tx, deckID).Where("path = ?", path).First(&tag).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tag = models.Tag{DeckID: deckID, Name: names[i], Path: path}
+				if parent != nil {
+					tag.ParentID = &parent.ID
+				}
+				err = tx.Create(&tag).Error
+			}
+			if err != nil {
+				return nil, err
+			}
+			if cache != nil {
+				cache[path] = tag
+			}
+		}
+		parent = &tag
+	}
+
+	return parent, nil
+}
+
+// whereDeckID 限定标签所属的卡包，deckID为空表示不属于任何卡包
+func whereDeckID(query *gorm.DB, deckID *uint) *gorm.DB {
+	if deckID == nil {
+		return query.Where("deck_id IS NULL")
+	}
+	return query.Where("deck_id = ?", *deckID)
+}
+
+// equalDeckID 判断两个可空的卡包ID是否相同
+func equalDeckID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/models"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidNoteType 笔记类型的字段或模板不合法
+var ErrInvalidNoteType = errors.New("笔记类型不合法")
+
+// invalidNoteType 生成包含具体原因的笔记类型不合法错误
+func invalidNoteType(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidNoteType}, args...)...)
+}
+
+// templateTagPattern 匹配 {{字段}}、{{#字段}}、{{^字段}} 和 {{/字段}}
+var templateTagPattern = regexp.MustCompile(`\{\{\s*([#^/]?)\s*([^{}]*?)\s*\}\}`)
+
+// validateNoteType 校验笔记类型：字段名非空且不重复，至少一个模板，模板只引用已有字段，每个正面模板至少引用一个字段
+func validateNoteType(noteType *models.NoteType) error {
+	noteType.Name = strings.TrimSpace(noteType.Name)
+	if noteType.Name == "" {
+		return invalidNoteType("名称不能为空")
+	}
+	if len(noteType.Fields) == 0 {
+		return invalidNoteType("至少需要一个字段")
+	}
+	if len(noteType.Templates) == 0 {
+		return invalidNoteType("至少需要一个卡片模板")
+	}
+
+	fields := make(map[string]bool, len(noteType.Fields))
+	seen := make(map[string]bool, len(noteType.Fields))
+	for i, name := range noteType.Fields {
+		name = strings.TrimSpace(name)
+		noteType.Fields[i] = name
+		if name == "" || strings.ContainsAny(name, "{}#^/") {
+			return invalidNoteType("字段名不能为空，且不能包含 { } # ^ /")
+		}
+		if strings.EqualFold(name, models.TemplateFrontSide) {
+			return invalidNoteType("字段名不能使用保留名称 %s", models.TemplateFrontSide)
+		}
+		if seen[strings.ToLower(name)] {
+			return invalidNoteType("字段名重复: %s", name)
+		}
+		seen[strings.ToLower(name)] = true
+		fields[name] = true
+	}
+
+	for i, tmpl := range noteType.Templates {
+		if strings.TrimSpace(tmpl.Name) == "" {
+			noteType.Templates[i].Name = fmt.Sprintf("卡片%d", i+1)
+		}
+		referenced := false
+		for _, match := range templateTagPattern.FindAllStringSubmatch(tmpl.Front, -1) {
+			if !fields[match[2]] {
+				return invalidNoteType("正面模板引用了不存在的字段: %s", match[2])
+			}
+			if match[1] == "" {
+				referenced = true
+			}
+		}
+		if !referenced {
+			return invalidNoteType("正面模板至少需要引用一个字段")
+		}
+		for _, match := range templateTagPattern.FindAllStringSubmatch(tmpl.Back, -1) {
+			if !fields[match[2]] && !(match[1] == "" && match[2] == models.TemplateFrontSide) {
+				return invalidNoteType("背面模板引用了不存在的字段: %s", match[2])
+			}
+		}
+	}
+
+	return nil
+}
+
+// renderTemplate 按字段内容渲染模板，返回渲染结果以及是否有非空字段被替换到结果中
+func renderTemplate(tmpl string, fields map[string]string) (string, bool) {
+	var b strings.Builder
+	filled := false
+
+	for {
+		loc := templateTagPattern.FindStringSubmatchIndex(tmpl)
+		if loc == nil {
+			b.WriteString(tmpl)
+			break
+		}
+		b.WriteString(tmpl[:loc[0]])
+		kind, name := tmpl[loc[2]:loc[3]], tmpl[loc[4]:loc[5]]
+		rest := tmpl[loc[1]:]
+
+		switch kind {
+		case "":
+			if value := fields[name]; value != "" {
+				b.WriteString(value)
+				if name != models.TemplateFrontSide && strings.TrimSpace(value) != "" {
+					filled = true
+				}
+			}
+		case "#", "^":
+			inner, after := splitTemplateSection(rest, name)
+			if (strings.TrimSpace(fields[name]) != "") == (kind == "#") {
+				text, innerFilled := renderTemplate(inner, fields)
+				b.WriteString(text)
+				filled = filled || innerFilled
+			}
+			rest = after
+		}
+		// 多余的 {{/字段}} 直接忽略
+
+		tmpl = rest
+	}
+
+	return b.String(), filled
+}
+
+// splitTemplateSection 在 {{#字段}} 之后的文本中找到对应的 {{/字段}}，返回区块内容和区块之后的文本；
+// 缺少结束标记时区块延续到模板末尾
+func splitTemplateSection(text, name string) (string, string) {
+	depth := 0
+	for _, loc := range templateTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		if text[loc[4]:loc[5]] != name {
+			continue
+		}
+		switch text[loc[2]:loc[3]] {
+		case "#", "^":
+			depth++
+		case "/":
+			if depth == 0 {
+				return text[:loc[0]], text[loc[1]:]
+			}
+			depth--
+		}
+	}
+	return text, ""
+}
+
+// renderCardTemplate 渲染模板卡片的正面和背面，正面没有填入任何非空字段时不生成卡片
+func renderCardTemplate(tmpl models.CardTemplate, fields models.NoteFields) (front, back string, ok bool) {
+	front, ok = renderTemplate(tmpl.Front, fields)
+	if !ok {
+		return "", "", false
+	}
+
+	backFields := make(map[string]string, len(fields)+1)
+	for name, value := range fields {
+		backFields[name] = value
+	}
+	backFields[models.TemplateFrontSide] = front
+	back, _ = renderTemplate(tmpl.Back, backFields)
+
+	return strings.TrimSpace(front), strings.TrimSpace(back), true
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"errors"
+	"flashcard/internal/config"
+	"flashcard/internal/models"
+	"flashcard/pkg/database"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 回收站相关错误
+var (
+	ErrInvalidTrashType   = errors.New("无效的回收站项目类型")
+	ErrNotInTrash         = errors.New("回收站中不存在该项目")
+	ErrTrashParentDeleted = errors.New("所属卡包或标签已删除，请先恢复")
+	ErrTrashNameConflict  = errors.New("已存在同名项目，无法恢复")
+)
+
+// defaultTrashRetentionDays 未加载配置时回收站项目的保留天数
+const defaultTrashRetentionDays = 30
+
+// cardDataTables 按card_id关联卡片的表，彻底删除卡片时一并删除
+var cardDataTables = []string{"reviews", "review_logs", "card_options", "card_media", "card_tags", "card_revisions"}
+
+// deletedDeckSubtree 查询回收站中的卡包及与它一起删除的子孙卡包ID，参数为两次卡包ID
+const deletedDeckSubtree = "WITH RECURSIVE subtree(id) AS (SELECT id FROM decks WHERE id = ? " +
+	"UNION SELECT decks.id FROM decks JOIN subtree ON decks.parent_id = subtree.id " +
+	"WHERE decks.deleted_at = (SELECT deleted_at FROM decks WHERE id = ?)) SELECT id FROM subtree"
+
+// deletedTagSubtree 查询回收站中的标签及与它一起删除的子孙标签ID，参数为两次标签ID
+const deletedTagSubtree = "WITH RECURSIVE subtree(id) AS (SELECT id FROM tags WHERE id = ? " +
+	"UNION SELECT tags.id FROM tags JOIN subtree ON tags.parent_id = subtree.id " +
+	"WHERE tags.deleted_at = (SELECT deleted_at FROM tags WHERE id = ?)) SELECT id FROM subtree"
+
+// deletedWithTag 卡片和它的某个标签一起删除（删除标签时选择了同时删除卡片）
+const deletedWithTag = "EXISTS (SELECT 1 FROM card_tags JOIN tags ON tags.id = card_tags.tag_id " +
+	"WHERE card_tags.card_id = cards.id AND tags.deleted_at = cards.deleted_at)"
+
+// TrashService 回收站服务
+type TrashService struct {
+	db *gorm.DB
+}
+
+// NewTrashService 创建回收站服务实例
+func NewTrashService() *TrashService {
+	return &TrashService{
+		db: database.GetDB(),
+	}
+}
+
+// trashRetentionDays 获取回收站项目的保留天数，0表示不自动清理
+func trashRetentionDays() int {
+	if config.AppConfig != nil {
+		return config.AppConfig.TrashRetentionDays
+	}
+	return defaultTrashRetentionDays
+}
+
+// ListTrash 获取回收站中的项目（最近删除的在前），itemType为空时返回全部类型
+// 随卡包或标签一起删除的子项目不单独列出，恢复或彻底删除上级项目时一并处理
+func (s *TrashService) ListTrash(itemType string) ([]models.TrashItem, error) {
+	items := []models.TrashItem{}
+
+	if itemType == "" || itemType == models.TrashTypeDeck {
+		var decks []models.Deck
+		err := s.db.Unscoped().
+			Where("deleted_at IS NOT NULL").
+			Where("NOT EXISTS (SELECT 1 FROM decks AS parent WHERE parent.id = decks.parent_id AND parent.deleted_at = decks.deleted_at)").
+			Find(&decks).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, deck := range decks {
+			items = append(items, newTrashItem(models.TrashTypeDeck, deck.ID, deck.Name, nil, deck.DeletedAt))
+		}
+	}
+
+	if itemType == "" || itemType == models.TrashTypeTag {
+		var tags []models.Tag
+		err := s.db.Unscoped().
+			Where("deleted_at IS NOT NULL").
+			Where("deck_id IS NULL OR deck_id IN (SELECT id FROM decks WHERE deleted_at IS NULL)").
+			Where("NOT EXISTS (SELECT 1 FROM tags AS parent WHERE parent.id = tags.parent_id AND parent.deleted_at = tags.deleted_at)").
+			Find(&tags).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			items = append(items, newTrashItem(models.TrashTypeTag, tag.ID, tagPathOf(tag), tag.DeckID, tag.DeletedAt))
+		}
+	}
+
+	if itemType == "" || itemType == models.TrashTypeCard {
+		var cards []models.Card
+		err := s.db.Unscoped().
+			Where("deleted_at IS NOT NULL").
+			Where("deck_id IN (SELECT id FROM decks WHERE deleted_at IS NULL)").
+			Where("NOT " + deletedWithTag).
+			Find(&cards).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range cards {
+			deckID := card.DeckID
+			items = append(items, newTrashItem(models.TrashTypeCard, card.ID, card.Question, &deckID, card.DeletedAt))
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Restore 恢复回收站中的项目，同时恢复和它一起删除的子项目
+func (s *TrashService) Restore(itemType string, id uint) error {
+	switch itemType {
+	case models.TrashTypeDeck:
+		return s.restoreDeck(id)
+	case models.TrashTypeTag:
+		return s.restoreTag(id)
+	case models.TrashTypeCard:
+		return s.restoreCard(id)
+	default:
+		return ErrInvalidTrashType
+	}
+}
+
+// Purge 彻底删除回收站中的项目及其子项目、复习记录等关联数据
+func (s *TrashService) Purge(itemType string, id uint) error {
+	var model interface{}
+	switch itemType {
+	case models.TrashTypeDeck:
+		model = &models.Deck{}
+	case models.TrashTypeTag:
+		model = &models.Tag{}
+	case models.TrashTypeCard:
+		model = &models.Card{}
+	default:
+		return ErrInvalidTrashType
+	}
+
+	if err := findDeleted(s.db, model, id); err != nil {
+		return err
+	}
+
+	var freed []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		freed, err = purgeItem(tx, itemType, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	removeMediaFiles(s.db, freed)
+	return nil
+}
+
+// PurgeExpired 彻底删除在指定时间之前删除的所有项目
+func (s *TrashService) PurgeExpired(before time.Time) (*models.TrashPurgeResult, error) {
+	result := &models.TrashPurgeResult{}
+
+	var freed []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 先清理卡包，卡包下的标签和卡片随之删除
+		steps := []struct {
+			itemType string
+			model    interface{}
+			count    *int
+		}{
+			{models.TrashTypeDeck, &models.Deck{}, &result.Decks},
+			{models.TrashTypeTag, &models.Tag{}, &result.Tags},
+			{models.TrashTypeCard, &models.Card{}, &result.Cards},
+		}
+
+		for _, step := range steps {
+			var ids []uint
+			err := tx.Unscoped().Model(step.model).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+				Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				hashes, err := purgeItem(tx, step.itemType, id)
+				if err != nil {
+					return err
+				}
+				freed = append(freed, hashes...)
+			}
+			*step.count = len(ids)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removeMediaFiles(s.db, freed)
+	return result, nil
+}
+
+// StartAutoPurge 定期彻底删除超过保留天数的回收站项目，保留天数为0时不启动
+func (s *TrashService) StartAutoPurge(interval time.Duration) {
+	days := trashRetentionDays()
+	if days <= 0 {
+		return
+	}
+
+	purge := func() {
+		result, err := s.PurgeExpired(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Printf("自动清理回收站失败: %v", err)
+			return
+		}
+		if result.Decks+result.Tags+result.Cards > 0 {
+			log.Printf("自动清理回收站: 卡包%d个，标签%d个，卡片%d张", result.Decks, result.Tags, result.Cards)
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
+
+// restoreDeck 恢复卡包及一起删除的子卡包、标签、笔记和卡片
+func (s *TrashService) restoreDeck(id uint) error {
+	var deck models.Deck
+	if err := findDeleted(s.db, &deck, id); err != nil {
+		return err
+	}
+
+	if deck.ParentID != nil {
+		if err := checkParentActive(s.db, &models.Deck{}, *deck.ParentID); err != nil {
+			return err
+		}
+	}
+
+	var ids []uint
+	if err := s.db.Raw(deletedDeckSubtree, id, id).Scan(&ids).Error; err != nil {
+		return err
+	}
+
+	// 恢复的卡包与同一上级卡包下未删除的卡包同名时不能恢复
+	var count int64
+	err := s.db.Model(&models.Deck{}).
+		Joins("JOIN decks AS restored ON COALESCE(restored.parent_id, 0) = COALESCE(decks.parent_id, 0) AND restored.name = decks.name").
+		Where("restored.id IN ?", ids).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTrashNameConflict
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		deletedAt := tx.Unscoped().Model(&models.Deck{}).Select("deleted_at").Where("id = ?", id)
+		for _, model := range []interface{}{&models.Tag{}, &models.Note{}, &models.Card{}} {
+			err := tx.Unscoped().Model(model).
+				Where("deck_id IN ? AND deleted_at = (?)", ids, deletedAt).
+				Update("deleted_at", nil).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Model(&models.Deck{}).Where("id IN ?", ids).Update("deleted_at", nil).Error
+	})
+}
+
+// restoreTag 恢复标签及一起删除的子标签和卡片
+func (s *TrashService) restoreTag(id uint) error {
+	var tag models.Tag
+	if err := findDeleted(s.db, &tag, id); err != nil {
+		return err
+	}
+
+	if tag.DeckID != nil {
+		if err := checkParentActive(s.db, &models.Deck{}, *tag.DeckID); err != nil {
+			return err
+		}
+	}
+	path := tag.Name
+	if tag.ParentID != nil {
+		if err := checkParentActive(s.db, &models.Tag{}, *tag.ParentID); err != nil {
+			return err
+		}
+		var parent models.Tag
+		if err := s.db.First(&parent, *tag.ParentID).Error; err != nil {
+			return err
+		}
+		path = tagPathOf(parent) + models.TagPathSeparator + tag.Name
+	}
+
+	// 先检查路径冲突，避免恢复时违反标签路径唯一索引
+	var count int64
+	if err := whereDeckID(s.db.Model(&models.Tag{}), tag.DeckID).Where("path = ?", path).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTrashNameConflict
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Raw(deletedTagSubtree, id, id).Scan(&ids).Error; err != nil {
+			return err
+		}
+
+		deletedAt := tx.Unscoped().Model(&models.Tag{}).Select("deleted_at").Where("id = ?", id)
+		err := tx.Unscoped().Model(&models.Card{}).
+			Where("id IN (SELECT card_id FROM card_tags WHERE tag_id IN ?) AND deleted_at = (?)", ids, deletedAt).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.Tag{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		// 删除期间上级标签可能已改名，重新计算路径
+		tag.DeletedAt = gorm.DeletedAt{}
+		return saveTag(tx, &tag)
+	})
+	if errors.Is(err, ErrTagPathConflict) {
+		return ErrTrashNameConflict
+	}
+	return err
+}
+
+// restoreCard 恢复卡片，由已删除笔记生成的卡片会连同笔记及其一起删除的卡片恢复
+func (s *TrashService) restoreCard(id uint) error {
+	var card models.Card
+	if err := findDeleted(s.db, &card, id); err != nil {
+		return err
+	}
+
+	if err := checkParentActive(s.db, &models.Deck{}, card.DeckID); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.Unscoped().Model(&models.Card{}).Where("id = ?", id).Where(deletedWithTag).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTrashParentDeleted
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if card.NoteID != nil {
+			var note models.Note
+			err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&note, *card.NoteID).Error
+			if err == nil {
+				deletedAt := tx.Unscoped().Model(&models.Note{}).Select("deleted_at").Where("id = ?", note.ID)
+				err = tx.Unscoped().Model(&models.Card{}).
+					Where("note_id = ? AND deleted_at = (?)", note.ID, deletedAt).
+					Update("deleted_at", nil).Error
+				if err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&models.Note{}).Where("id = ?", note.ID).Update("deleted_at", nil).Error; err != nil {
+					return err
+				}
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Model(&models.Card{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+}
+
+// purgeItem 在事务中彻底删除项目及其子项目，返回不再被引用而删除的媒体的哈希
+func purgeItem(tx *gorm.DB, itemType string, id uint) ([]string, error) {
+	switch itemType {
+	case models.TrashTypeDeck:
+		var ids []uint
+		if err := tx.Raw(deletedDeckSubtree, id, id).Scan(&ids).Error; err != nil {
+			return nil, err
+		}
+		freed, err := purgeCards(tx, "deck_id IN ?", ids)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Where("deck_id IN ?", ids).Delete(&models.Note{}).Error; err != nil {
+			return nil, err
+		}
+		for _, table := range []string{"card_tags", "note_tags"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE tag_id IN (SELECT id FROM tags WHERE deck_id IN ?)", ids).Error; err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Unscoped().Where("deck_id IN ?", ids).Delete(&models.Tag{}).Error; err != nil {
+			return nil, err
+		}
+		// 之前单独删除的子卡包恢复时改为顶层卡包
+		if err := tx.Unscoped().Model(&models.Deck{}).Where("parent_id IN ? AND id NOT IN ?", ids, ids).Update("parent_id", nil).Error; err != nil {
+			return nil, err
+		}
+		return freed, tx.Unscoped().Delete(&models.Deck{}, ids).Error
+
+	case models.TrashTypeTag:
+		var ids []uint
+		if err := tx.Raw(deletedTagSubtree, id, id).Scan(&ids).Error; err != nil {
+			return nil, err
+		}
+		freed, err := purgeCards(tx, "id IN (SELECT card_id FROM card_tags WHERE tag_id IN ?) AND deleted_at = (SELECT deleted_at FROM tags WHERE id = ?)", ids, id)
+		if err != nil {
+			return nil, err
+		}
+		// 其他卡片和笔记不再引用这些标签
+		for _, table := range []string{"card_tags", "note_tags"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE tag_id IN ?", ids).Error; err != nil {
+				return nil, err
+			}
+		}
+		// 之前单独删除的子标签恢复时改为顶层标签
+		if err := tx.Unscoped().Model(&models.Tag{}).Where("parent_id IN ? AND id NOT IN ?", ids, ids).Update("parent_id", nil).Error; err != nil {
+			return nil, err
+		}
+		return freed, tx.Unscoped().Delete(&models.Tag{}, ids).Error
+
+	case models.TrashTypeCard:
+		return purgeCards(tx, "id = ?", id)
+
+	default:
+		return nil, ErrInvalidTrashType
+	}
+}
+
+// purgeCards 彻底删除符合条件的卡片及其关联数据，清理已删除且没有卡片的笔记，
+// 并删除这些卡片引用的、已没有其他卡片或笔记引用的媒体记录，返回删除的媒体的哈希
+func purgeCards(tx *gorm.DB, query string, args ...interface{}) ([]string, error) {
+	var ids []uint
+	if err := tx.Unscoped().Model(&models.Card{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	var mediaIDs []uint
+	if len(ids) > 0 {
+		if err := tx.Table("card_media").Where("card_id IN ?", ids).Distinct().Pluck("media_id", &mediaIDs).Error; err != nil {
+			return nil, err
+		}
+
+		for _, table := range cardDataTables {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE card_id IN ?", ids).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		if err := tx.Unscoped().Delete(&models.Card{}, ids).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err := tx.Unscoped().
+		Where("deleted_at IS NOT NULL AND id NOT IN (SELECT note_id FROM cards WHERE note_id IS NOT NULL)").
+		Delete(&models.Note{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Exec("DELETE FROM note_tags WHERE note_id NOT IN (SELECT id FROM notes)").Error; err != nil {
+		return nil, err
+	}
+
+	return deleteUnreferencedMedia(tx, mediaIDs)
+}
+
+// findDeleted 查找已软删除的记录，未删除或不存在时返回 ErrNotInTrash
+func findDeleted(db *gorm.DB, model interface{}, id uint) error {
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").First(model, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInTrash
+	}
+	return err
+}
+
+// checkParentActive 检查上级卡包或标签未被删除
+func checkParentActive(db *gorm.DB, model interface{}, id uint) error {
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTrashParentDeleted
+	}
+	return nil
+}
+
+// softDeleteWith 以相同的删除时间软删除记录，恢复时据此找回一起删除的子项目
+func softDeleteWith(tx *gorm.DB, deletedAt time.Time, model interface{}, query string, args ...interface{}) error {
+	return tx.Model(model).Where(query, args...).Update("deleted_at", deletedAt).Error
+}
+
+// newTrashItem 创建回收站项目，开启自动清理时计算清理时间
+func newTrashItem(itemType string, id uint, name string, deckID *uint, deletedAt gorm.DeletedAt) models.TrashItem {
+	item := models.TrashItem{
+		Type:      itemType,
+		ID:        id,
+		Name:      name,
+		DeckID:    deckID,
+		DeletedAt: deletedAt.Time,
+	}
+	if days := trashRetentionDays(); days > 0 {
+		purgeAt := deletedAt.Time.AddDate(0, 0, days)
+		item.PurgeAt = &purgeAt
+	}
+	return item
+}
@@ -37,8 +37,8 @@ func InitDatabase() error {
 		logLevel = logger.Error
 	}
 
-	// 连接数据库
-	db, err := gorm.Open(sqlite.Open(cfg.DBPath), &gorm.Config{
+	// 连接数据库，使用注册了全文搜索分词函数的驱动
+	db, err := gorm.Open(sqlite.Dialector{DriverName: DriverName, DSN: cfg.DBPath}, &gorm.Config{
 		Logger: logger.Default.LogMode(logLevel),
 	})
 	if err != nil {
@@ -53,44 +53,140 @@ func InitDatabase() error {
 	}
 
 	// 创建索引
-	if err := createIndexes(); err != nil {
+	if err := CreateIndexes(DB); err != nil {
 		return fmt.Errorf("创建索引失败: %v", err)
 	}
 
+	// 创建卡片全文索引
+	if err := SetupFullTextSearch(DB); err != nil {
+		return fmt.Errorf("创建全文索引失败: %v", err)
+	}
+
 	log.Println("数据库初始化成功")
 	return nil
 }
 
 // migrate 执行数据库迁移
 func migrate() error {
-	return DB.AutoMigrate(
+	backfillPositions := DB.Migrator().HasTable(&models.Card{}) && !DB.Migrator().HasColumn(&models.Card{}, "position")
+
+	err := DB.AutoMigrate(
 		&models.Deck{},
 		&models.Tag{},
+		&models.NoteType{},
+		&models.Note{},
+		&models.Media{},
 		&models.Card{},
+		&models.CardOption{},
 		&models.Review{},
+		&models.ReviewLog{},
+		&models.CardRevision{},
+		&models.SavedSearch{},
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := migrateTagLinks(); err != nil {
+		return err
+	}
+
+	// 旧版本的卡片没有顺序，按创建的先后编号
+	if backfillPositions {
+		err := DB.Exec("UPDATE cards SET position = (SELECT COUNT(*) FROM cards AS c WHERE c.deck_id = cards.deck_id AND c.id <= cards.id)").Error
+		if err != nil {
+			return fmt.Errorf("初始化卡片顺序失败: %v", err)
+		}
+	}
+
+	// 旧版本的标签都是顶层标签，路径即标签名
+	return DB.Exec("UPDATE tags SET path = name WHERE path = '' OR path IS NULL").Error
+}
+
+// migrateTagLinks 将旧版本卡片和笔记的单个标签（tag_id列）迁移到标签关联表
+func migrateTagLinks() error {
+	links := []struct {
+		model interface{}
+		table string
+		join  string
+		key   string
+	}{
+		{&models.Card{}, "cards", "card_tags", "card_id"},
+		{&models.Note{}, "notes", "note_tags", "note_id"},
+	}
+
+	for _, link := range links {
+		if !DB.Migrator().HasColumn(link.model, "tag_id") {
+			continue
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			sql := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s, tag_id) SELECT id, tag_id FROM %s WHERE tag_id IS NOT NULL",
+				link.join, link.key, link.table)
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+			// 清空旧列，避免重复迁移覆盖之后的标签修改
+			return tx.Exec(fmt.Sprintf("UPDATE %s SET tag_id = NULL WHERE tag_id IS NOT NULL", link.table)).Error
+		})
+		if err != nil {
+			return fmt.Errorf("迁移%s标签失败: %v", link.table, err)
+		}
+	}
+
+	return nil
 }
 
-// createIndexes 创建必要的索引
-func createIndexes() error {
-	// 为tags表创建复合唯一索引（deck_id + name）
-	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_deck_name ON tags(deck_id, name) WHERE deleted_at IS NULL").Error; err != nil {
+// CreateIndexes 创建必要的索引，包括普通迁移不会创建的部分唯一索引
+func CreateIndexes(db *gorm.DB) error {
+	// 为decks表创建名称唯一索引，同一上级卡包下（顶层卡包之间）名称唯一，已删除的卡包不占用名称
+	if err := db.Exec("DROP INDEX IF EXISTS idx_decks_name").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_decks_parent_name ON decks(COALESCE(parent_id, 0), name) WHERE deleted_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// 为note_types表创建名称唯一索引，已删除的笔记类型不占用名称
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_note_types_name ON note_types(name) WHERE deleted_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// 为saved_searches表创建名称唯一索引
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_searches_name ON saved_searches(name)").Error; err != nil {
+		return err
+	}
+
+	// 为tags表创建复合唯一索引（deck_id + path），同一上级标签下的标签名唯一
+	if err := db.Exec("DROP INDEX IF EXISTS idx_tags_deck_name").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_deck_path ON tags(deck_id, path) WHERE deleted_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// 删除旧版本的卡包标签复合索引，标签关系改由card_tags表保存
+	if err := db.Exec("DROP INDEX IF EXISTS idx_cards_deck_tag").Error; err != nil {
+		return err
+	}
+
+	// 为card_tags表创建标签索引，按标签查询卡片
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_card_tags_tag ON card_tags(tag_id)").Error; err != nil {
 		return err
 	}
 
-	// 为cards表创建复合索引用于搜索
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_cards_deck_tag ON cards(deck_id, tag_id) WHERE deleted_at IS NULL").Error; err != nil {
+	// 为cards表创建卡包顺序索引，按卡片顺序列出和学习新卡片
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_cards_deck_position ON cards(deck_id, position)").Error; err != nil {
 		return err
 	}
 
 	// 为reviews表创建next_review索引
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_reviews_next_review ON reviews(next_review)").Error; err != nil {
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_reviews_next_review ON reviews(next_review)").Error; err != nil {
 		return err
 	}
 
-	// 为cards表创建全文搜索索引（可选，SQLite FTS）
-	// 暂时使用普通索引，后续可优化为FTS
-	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_cards_question ON cards(question) WHERE deleted_at IS NULL").Error; err != nil {
+	// 为cards表创建问题索引，用于查重和按问题排序；关键词搜索使用cards_fts全文索引（见SetupFullTextSearch）
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_cards_question ON cards(question) WHERE deleted_at IS NULL").Error; err != nil {
 		return err
 	}
 
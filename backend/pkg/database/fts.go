@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// DriverName 注册了查重规范化函数的SQLite驱动名称
+const DriverName = "sqlite3_flashcard"
+
+// FTSMinTermLength 全文索引按三个字符一组（trigram）分词，短于该长度的词无法通过索引匹配
+const FTSMinTermLength = 3
+
+// ftsEnabled 当前数据库是否启用了FTS5全文搜索
+var ftsEnabled bool
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("normalize_question", NormalizeQuestion, true)
+		},
+	})
+}
+
+// FTSEnabled 判断卡片全文搜索是否可用，不可用时搜索退回LIKE查询
+func FTSEnabled() bool {
+	return ftsEnabled
+}
+
+// SetFTSEnabled 设置搜索是否使用全文索引，关闭后退回LIKE查询，用于对比两种查询方式
+func SetFTSEnabled(enabled bool) {
+	ftsEnabled = enabled
+}
+
+// NormalizeQuestion 规范化问题文本用于查重：全角字母数字和符号转为半角，忽略大小写，合并空白
+func NormalizeQuestion(question string) string {
+	folded := strings.Map(func(r rune) rune {
+		if r == '　' {
+			return ' '
+		}
+		return unicode.ToLower(FoldWidth(r))
+	}, question)
+	return strings.Join(strings.Fields(folded), " ")
+}
+
+// FoldWidth 将全角字母、数字和标点转换为对应的半角字符
+func FoldWidth(r rune) rune {
+	if r >= '！' && r <= '～' {
+		return r - 0xFEE0
+	}
+	return r
+}
+
+// ftsTriggers 同步cards_fts索引的触发器名称
+var ftsTriggers = []string{"cards_fts_insert", "cards_fts_delete", "cards_fts_update"}
+
+// ftsTable 卡片全文索引的定义：无内容表只保存索引，问题和答案的原文仍从cards表读取；
+// trigram分词器按连续的三个字符建立索引，中日韩文本和英文单词内部都可以按子串匹配，忽略大小写
+const ftsTable = "CREATE VIRTUAL TABLE cards_fts USING fts5(question, answer, content='', tokenize='trigram')"
+
+// SetupFullTextSearch 创建卡片全文索引cards_fts及同步触发器，索引新建、定义变化或触发器不存在时重建索引；
+// 未使用 sqlite_fts5 构建标签编译时FTS5不可用，删除触发器以免写入卡片失败，返回nil并退回LIKE查询。
+// 触发器只使用SQLite内置的功能，sqlite3命令行等其他SQLite连接写入cards表时索引同样同步
+func SetupFullTextSearch(db *gorm.DB) error {
+	ftsEnabled = false
+
+	var definition string
+	if err := db.Raw("SELECT COALESCE(MAX(sql), '') FROM sqlite_master WHERE type = 'table' AND name = 'cards_fts'").Scan(&definition).Error; err != nil {
+		return err
+	}
+
+	var synced int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ? AND sql NOT LIKE '%fts_tokens%'", ftsTriggers[0]).Scan(&synced).Error; err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 旧版本的索引（按fts_tokens函数分词）删除后按当前定义重建
+		if definition != ftsTable || synced == 0 {
+			for _, name := range ftsTriggers {
+				if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("DROP TABLE IF EXISTS cards_fts").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(ftsTable).Error; err != nil {
+				return err
+			}
+			// 包括回收站中的卡片，查询时按cards表过滤
+			if err := tx.Exec("INSERT INTO cards_fts(rowid, question, answer) SELECT id, question, answer FROM cards").Error; err != nil {
+				return err
+			}
+		}
+
+		triggers := []string{
+			`CREATE TRIGGER IF NOT EXISTS cards_fts_insert AFTER INSERT ON cards BEGIN
+				INSERT INTO cards_fts(rowid, question, answer) VALUES (new.id, new.question, new.answer);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS cards_fts_delete AFTER DELETE ON cards BEGIN
+				INSERT INTO cards_fts(cards_fts, rowid, question, answer) VALUES ('delete', old.id, old.question, old.answer);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS cards_fts_update AFTER UPDATE OF question, answer ON cards BEGIN
+				INSERT INTO cards_fts(cards_fts, rowid, question, answer) VALUES ('delete', old.id, old.question, old.answer);
+				INSERT INTO cards_fts(rowid, question, answer) VALUES (new.id, new.question, new.answer);
+			END`,
+		}
+		for _, trigger := range triggers {
+			if err := tx.Exec(trigger).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module: fts5") {
+			return err
+		}
+		for _, name := range ftsTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		log.Println("SQLite未启用FTS5（需要使用 -tags sqlite_fts5 构建），卡片搜索使用LIKE查询")
+		return nil
+	}
+
+	ftsEnabled = true
+	return nil
+}
@@ -106,7 +106,7 @@ describe('CardForm.vue', () => {
     await wrapper.setData({
       card: {
         deck_id: 1,
-        tag_id: 1,
+        tag_ids: [1],
         question: '测试问题',
         answer: '测试答案'
       }
@@ -118,7 +118,7 @@ describe('CardForm.vue', () => {
     expect(wrapper.emitted('submit')).toBeTruthy()
     expect(wrapper.emitted('submit')[0][0]).toEqual({
       deck_id: 1,
-      tag_id: 1,
+      tag_ids: [1],
       question: '测试问题',
       answer: '测试答案'
     })
@@ -144,7 +144,7 @@ describe('CardForm.vue', () => {
     const existingCard = {
       id: 1,
       deck_id: 1,
-      tag_id: 1,
+      tag_ids: [1],
       question: '现有问题',
       answer: '现有答案'
     }
@@ -32,9 +32,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
@@ -71,9 +70,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
@@ -106,9 +104,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
@@ -148,9 +145,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
@@ -199,7 +195,7 @@ describe('CardDetailView.vue', () => {
       question: '更新后的问题',
       answer: '更新后的答案',
       deck_id: 1,
-      tag_id: 1
+      tag_ids: [1]
     })
     expect(wrapper.vm.isEditing).toBe(false)
     expect(wrapper.vm.card).toEqual(updatedCard)
@@ -211,9 +207,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
@@ -262,9 +257,8 @@ describe('CardDetailView.vue', () => {
       question: '测试问题',
       answer: '测试答案',
       deck_id: 1,
-      tag_id: 1,
       deck: { id: 1, name: '测试卡包' },
-      tag: { id: 1, name: '测试标签' },
+      tags: [{ id: 1, name: '测试标签' }],
       review: {
         id: 1,
         card_id: 1,
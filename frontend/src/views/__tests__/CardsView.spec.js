@@ -127,7 +127,7 @@ describe('CardsView.vue', () => {
     // 触发表单提交事件
     await wrapper.findComponent({ name: 'CardForm' }).vm.$emit('submit', {
       deck_id: 1,
-      tag_id: 1,
+      tag_ids: [1],
       question: '新问题',
       answer: '新答案'
     })
@@ -44,14 +44,14 @@ describe('TagCardsView.vue', () => {
         question: '问题1',
         answer: '答案1',
         deck_id: 1,
-        tag_id: 1
+        tags: [{ id: 1, name: '测试标签' }]
       },
       {
         id: 2,
         question: '问题2',
         answer: '答案2',
         deck_id: 1,
-        tag_id: 1
+        tags: [{ id: 1, name: '测试标签' }]
       }
     ]
 
@@ -166,14 +166,14 @@ describe('TagCardsView.vue', () => {
         question: '问题1',
         answer: '答案1',
         deck_id: 1,
-        tag_id: 1
+        tags: [{ id: 1, name: '测试标签' }]
       },
       {
         id: 2,
         question: '问题2',
         answer: '答案2',
         deck_id: 1,
-        tag_id: 1
+        tags: [{ id: 1, name: '测试标签' }]
       }
     ]
 
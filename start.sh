@@ -112,7 +112,7 @@ start_backend() {
 
     # 编译并启动
     print_info "正在编译后端服务..."
-    go build -o flashcard cmd/server/main.go
+    go build -tags sqlite_fts5 -o flashcard cmd/server/main.go
 
     if [ ! -f "./flashcard" ]; then
         print_error "后端编译失败"